@@ -0,0 +1,308 @@
+// Command classifier-eval runs the complaint classifier over a labeled JSONL
+// dataset and reports routing quality, so prompt and model changes can be
+// compared before they reach production.
+//
+// Usage:
+//
+//	go run ./cmd/classifier-eval -dataset eval.jsonl [-mode llm|keywords]
+//	    [-categories categories.json] [-model classifier.json]
+//	    [-baseline baseline.json] [-write-baseline]
+//
+// Each dataset line is one labeled complaint:
+//
+//	{"id": "w-001", "title": "...", "description": "...", "category": "Water Supply", "priority": "high", "rejected": false}
+//
+// The command exits 1 when a metric drops below the baseline by more than
+// -tolerance, and 2 on usage or setup errors.
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hakim/backend/internal/ai"
+	"github.com/hakim/backend/internal/config"
+	"github.com/hakim/backend/internal/redact"
+	"github.com/hakim/backend/internal/rules"
+	"github.com/hakim/backend/internal/textmodel"
+	"github.com/hakim/backend/pkg/supabase"
+)
+
+// Example is one labeled complaint in the evaluation dataset
+type Example struct {
+	ID          string   `json:"id"`
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Images      []string `json:"images,omitempty"`
+	Category    string   `json:"category"`
+	Priority    string   `json:"priority"`
+	Rejected    bool     `json:"rejected"`
+}
+
+// Outcome is what the classifier produced for one example
+type Outcome struct {
+	Example          *Example
+	Category         string
+	Priority         string
+	Rejected         bool
+	Fallback         bool
+	Err              error
+	Latency          time.Duration
+	PromptTokens     int
+	CompletionTokens int
+}
+
+// staticCategories serves categories loaded from a JSON file
+type staticCategories []supabase.Category
+
+func (s staticCategories) GetCategories() ([]supabase.Category, error) {
+	return s, nil
+}
+
+func main() {
+	datasetPath := flag.String("dataset", "", "labeled JSONL dataset (required)")
+	mode := flag.String("mode", "llm", "classification path: llm or keywords (offline, including -model)")
+	categoriesPath := flag.String("categories", "", "JSON file with the category list; loaded from Supabase when empty")
+	baselinePath := flag.String("baseline", "", "baseline metrics JSON to compare against")
+	writeBaseline := flag.Bool("write-baseline", false, "write this run's metrics to -baseline instead of comparing")
+	tolerance := flag.Float64("tolerance", 0.02, "allowed drop in any rate metric before failing")
+	concurrency := flag.Int("concurrency", 4, "examples classified in parallel")
+	promptCost := flag.Float64("prompt-cost", 0, "USD per 1M prompt tokens")
+	completionCost := flag.Float64("completion-cost", 0, "USD per 1M completion tokens")
+	reportPath := flag.String("report", "", "write the full report as JSON to this file")
+	modelPath := flag.String("model", "", "local model file used offline and as a second opinion")
+	flag.Parse()
+
+	if *datasetPath == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := config.Load(); err != nil {
+		fatal("Failed to load config: %v", err)
+	}
+
+	examples, err := loadDataset(*datasetPath)
+	if err != nil {
+		fatal("Failed to load dataset: %v", err)
+	}
+
+	// Offline runs use the built-in rules; otherwise rules come from Supabase
+	// along with the categories
+	var categories ai.CategorySource
+	ruleEngine := rules.NewEngine(nil)
+	if *categoriesPath != "" {
+		static, err := loadCategories(*categoriesPath)
+		if err != nil {
+			fatal("Failed to load categories: %v", err)
+		}
+		categories = static
+	} else {
+		client := supabase.New()
+		categories = client
+		ruleEngine = rules.NewEngine(client)
+		if err := ruleEngine.Reload(); err != nil {
+			fatal("Failed to load classification rules: %v", err)
+		}
+	}
+
+	var provider ai.Provider
+	switch *mode {
+	case "llm":
+		provider, err = ai.NewProviderFromConfig(config.AppConfig)
+		if err != nil {
+			fatal("Failed to configure LLM provider: %v", err)
+		}
+		if provider == nil {
+			fatal("No LLM provider configured; set LLM_PROVIDERS or use -mode keywords")
+		}
+	case "keywords":
+	default:
+		fatal("Unknown mode %q", *mode)
+	}
+
+	classifier := ai.NewClassifier(categories, provider, ruleEngine)
+	if *modelPath != "" {
+		model, err := textmodel.Load(*modelPath)
+		if err != nil {
+			fatal("Failed to load local model: %v", err)
+		}
+		classifier.UseLocalModel(model, config.AppConfig.LocalModelMinConfidence)
+	}
+	// Evaluate on the same redacted text the server sends
+	if config.AppConfig.PIIRedaction {
+		redactor, err := redact.New(config.AppConfig.PIIRedactionKinds)
+		if err != nil {
+			fatal("Failed to configure PII redaction: %v", err)
+		}
+		classifier.UseRedactor(redactor)
+	}
+	outcomes := run(classifier, examples, *concurrency)
+
+	report := buildReport(outcomes, *promptCost, *completionCost)
+	report.Mode = *mode
+	report.Dataset = *datasetPath
+	printReport(os.Stdout, report)
+
+	if *reportPath != "" {
+		if err := writeJSON(*reportPath, report); err != nil {
+			fatal("Failed to write report: %v", err)
+		}
+	}
+
+	if *baselinePath == "" {
+		return
+	}
+
+	if *writeBaseline {
+		if err := writeJSON(*baselinePath, report.Metrics); err != nil {
+			fatal("Failed to write baseline: %v", err)
+		}
+		fmt.Printf("\nBaseline written to %s\n", *baselinePath)
+		return
+	}
+
+	var baseline Metrics
+	if err := readJSON(*baselinePath, &baseline); err != nil {
+		fatal("Failed to read baseline: %v", err)
+	}
+
+	regressions := compare(&baseline, &report.Metrics, *tolerance)
+	if len(regressions) > 0 {
+		fmt.Println("\nREGRESSIONS against baseline:")
+		for _, r := range regressions {
+			fmt.Println("  - " + r)
+		}
+		os.Exit(1)
+	}
+	fmt.Println("\nNo regressions against baseline.")
+}
+
+func run(classifier *ai.Classifier, examples []Example, concurrency int) []Outcome {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	outcomes := make([]Outcome, len(examples))
+	indexes := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				outcomes[i] = classify(classifier, &examples[i])
+			}
+		}()
+	}
+
+	for i := range examples {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	return outcomes
+}
+
+func classify(classifier *ai.Classifier, example *Example) Outcome {
+	outcome := Outcome{Example: example}
+
+	start := time.Now()
+	// Examples carry full details, so the classifier is not allowed to ask
+	// clarifying questions instead of answering
+	result, err := classifier.ClassifyWithoutQuestions(example.Title, example.Description, example.Images)
+	outcome.Latency = time.Since(start)
+
+	if err != nil {
+		if _, rejected := ai.RejectionReason(err); rejected {
+			outcome.Rejected = true
+		} else {
+			outcome.Err = err
+		}
+		return outcome
+	}
+
+	outcome.Category = result.CategoryName
+	outcome.Priority = result.Priority
+	outcome.Fallback = result.Fallback
+	outcome.PromptTokens = result.PromptTokens
+	outcome.CompletionTokens = result.CompletionTokens
+	return outcome
+}
+
+func loadDataset(path string) ([]Example, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var examples []Example
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		var example Example
+		if err := json.Unmarshal([]byte(text), &example); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if example.ID == "" {
+			example.ID = fmt.Sprintf("line-%d", line)
+		}
+		if !example.Rejected && example.Category == "" {
+			return nil, fmt.Errorf("line %d: category is required unless rejected is true", line)
+		}
+		examples = append(examples, example)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(examples) == 0 {
+		return nil, fmt.Errorf("dataset is empty")
+	}
+
+	return examples, nil
+}
+
+func loadCategories(path string) (staticCategories, error) {
+	var categories []supabase.Category
+	if err := readJSON(path, &categories); err != nil {
+		return nil, err
+	}
+	return categories, nil
+}
+
+func readJSON(path string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func writeJSON(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+func fatal(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+	os.Exit(2)
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Labels used in the confusion matrix for outcomes that are not a category
+const (
+	labelRejected = "(rejected)"
+	labelError    = "(error)"
+)
+
+// Metrics are the headline numbers compared against a baseline
+type Metrics struct {
+	Total              int     `json:"total"`
+	Accuracy           float64 `json:"accuracy"`
+	PriorityAgreement  float64 `json:"priority_agreement"`
+	RejectionPrecision float64 `json:"rejection_precision"`
+	RejectionRecall    float64 `json:"rejection_recall"`
+	Fallbacks          int     `json:"fallbacks"`
+	Errors             int     `json:"errors"`
+	LatencyMeanMs      int64   `json:"latency_mean_ms"`
+	LatencyP95Ms       int64   `json:"latency_p95_ms"`
+	PromptTokens       int     `json:"prompt_tokens"`
+	CompletionTokens   int     `json:"completion_tokens"`
+	CostUSD            float64 `json:"cost_usd"`
+}
+
+// Report is the full result of an evaluation run
+type Report struct {
+	Mode      string                    `json:"mode"`
+	Dataset   string                    `json:"dataset"`
+	Metrics   Metrics                   `json:"metrics"`
+	Confusion map[string]map[string]int `json:"confusion"`
+	Misses    []Miss                    `json:"misses"`
+}
+
+// Miss is an example the classifier got wrong
+type Miss struct {
+	ID        string `json:"id"`
+	Expected  string `json:"expected"`
+	Predicted string `json:"predicted"`
+	Error     string `json:"error,omitempty"`
+}
+
+func buildReport(outcomes []Outcome, promptCost, completionCost float64) *Report {
+	report := &Report{
+		Confusion: make(map[string]map[string]int),
+	}
+	m := &report.Metrics
+	m.Total = len(outcomes)
+
+	var (
+		valid, correct                   int
+		priorityMatch, priorityScored    int
+		truePositive, predictedRejection int
+		expectedRejection                int
+		latencies                        []time.Duration
+		totalLatency                     time.Duration
+	)
+
+	for i := range outcomes {
+		o := &outcomes[i]
+		expected := strings.TrimSpace(o.Example.Category)
+		if o.Example.Rejected {
+			expected = labelRejected
+			expectedRejection++
+		}
+
+		predicted := o.Category
+		if strings.EqualFold(predicted, expected) {
+			predicted = expected
+		}
+		switch {
+		case o.Err != nil:
+			predicted = labelError
+			m.Errors++
+		case o.Rejected:
+			predicted = labelRejected
+			predictedRejection++
+			if o.Example.Rejected {
+				truePositive++
+			}
+		}
+
+		if report.Confusion[expected] == nil {
+			report.Confusion[expected] = make(map[string]int)
+		}
+		report.Confusion[expected][predicted]++
+
+		if !o.Example.Rejected {
+			valid++
+			if predicted == expected {
+				correct++
+			}
+			// Only examples labeled with a priority and given one count
+			if o.Example.Priority != "" && o.Priority != "" {
+				priorityScored++
+				if o.Priority == o.Example.Priority {
+					priorityMatch++
+				}
+			}
+		}
+
+		if predicted != expected {
+			miss := Miss{ID: o.Example.ID, Expected: expected, Predicted: predicted}
+			if o.Err != nil {
+				miss.Error = o.Err.Error()
+			}
+			report.Misses = append(report.Misses, miss)
+		}
+
+		if o.Fallback {
+			m.Fallbacks++
+		}
+		m.PromptTokens += o.PromptTokens
+		m.CompletionTokens += o.CompletionTokens
+		latencies = append(latencies, o.Latency)
+		totalLatency += o.Latency
+	}
+
+	m.Accuracy = ratio(correct, valid)
+	m.PriorityAgreement = ratio(priorityMatch, priorityScored)
+	m.RejectionPrecision = ratio(truePositive, predictedRejection)
+	m.RejectionRecall = ratio(truePositive, expectedRejection)
+	m.CostUSD = float64(m.PromptTokens)/1e6*promptCost + float64(m.CompletionTokens)/1e6*completionCost
+
+	if len(latencies) > 0 {
+		sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+		m.LatencyMeanMs = (totalLatency / time.Duration(len(latencies))).Milliseconds()
+		m.LatencyP95Ms = latencies[(len(latencies)*95+99)/100-1].Milliseconds()
+	}
+
+	return report
+}
+
+// ratio returns n/d, or 1 when there is nothing to measure
+func ratio(n, d int) float64 {
+	if d == 0 {
+		return 1
+	}
+	return float64(n) / float64(d)
+}
+
+// compare lists every rate metric that fell more than tolerance below the baseline
+func compare(baseline, current *Metrics, tolerance float64) []string {
+	checks := []struct {
+		name     string
+		baseline float64
+		current  float64
+	}{
+		{"accuracy", baseline.Accuracy, current.Accuracy},
+		{"priority_agreement", baseline.PriorityAgreement, current.PriorityAgreement},
+		{"rejection_precision", baseline.RejectionPrecision, current.RejectionPrecision},
+		{"rejection_recall", baseline.RejectionRecall, current.RejectionRecall},
+	}
+
+	var regressions []string
+	for _, c := range checks {
+		if c.current < c.baseline-tolerance {
+			regressions = append(regressions, fmt.Sprintf("%s %.3f < baseline %.3f", c.name, c.current, c.baseline))
+		}
+	}
+	return regressions
+}
+
+func printReport(w io.Writer, report *Report) {
+	m := report.Metrics
+
+	fmt.Fprintf(w, "Classifier evaluation (%s) on %s\n\n", report.Mode, report.Dataset)
+	fmt.Fprintf(w, "  Examples:             %d\n", m.Total)
+	fmt.Fprintf(w, "  Category accuracy:    %.3f\n", m.Accuracy)
+	fmt.Fprintf(w, "  Priority agreement:   %.3f\n", m.PriorityAgreement)
+	fmt.Fprintf(w, "  Rejection precision:  %.3f\n", m.RejectionPrecision)
+	fmt.Fprintf(w, "  Rejection recall:     %.3f\n", m.RejectionRecall)
+	fmt.Fprintf(w, "  Fallbacks:            %d\n", m.Fallbacks)
+	fmt.Fprintf(w, "  Errors:               %d\n", m.Errors)
+	fmt.Fprintf(w, "  Latency mean / p95:   %dms / %dms\n", m.LatencyMeanMs, m.LatencyP95Ms)
+	fmt.Fprintf(w, "  Tokens (in / out):    %d / %d\n", m.PromptTokens, m.CompletionTokens)
+	fmt.Fprintf(w, "  Estimated cost:       $%.4f\n", m.CostUSD)
+
+	// Confusion matrix: rows are expected labels, columns predicted labels
+	labelSet := make(map[string]bool)
+	for expected, row := range report.Confusion {
+		labelSet[expected] = true
+		for predicted := range row {
+			labelSet[predicted] = true
+		}
+	}
+	labels := make([]string, 0, len(labelSet))
+	for label := range labelSet {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+
+	width := 12
+	for i, label := range labels {
+		if n := len(fmt.Sprintf("c%d %s", i, label)); n > width {
+			width = n
+		}
+	}
+
+	fmt.Fprintf(w, "\nConfusion matrix (rows = expected, columns = predicted):\n\n")
+	fmt.Fprintf(w, "  %-*s", width, "")
+	for i := range labels {
+		fmt.Fprintf(w, " %5s", fmt.Sprintf("c%d", i))
+	}
+	fmt.Fprintln(w)
+	for i, expected := range labels {
+		fmt.Fprintf(w, "  %-*s", width, fmt.Sprintf("c%d %s", i, expected))
+		for _, predicted := range labels {
+			fmt.Fprintf(w, " %5d", report.Confusion[expected][predicted])
+		}
+		fmt.Fprintln(w)
+	}
+
+	if len(report.Misses) > 0 {
+		fmt.Fprintf(w, "\nMisclassified (%d):\n", len(report.Misses))
+		for _, miss := range report.Misses {
+			line := fmt.Sprintf("  %s: expected %s, got %s", miss.ID, miss.Expected, miss.Predicted)
+			if miss.Error != "" {
+				line += " (" + miss.Error + ")"
+			}
+			fmt.Fprintln(w, line)
+		}
+	}
+}
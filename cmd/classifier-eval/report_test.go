@@ -0,0 +1,120 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestBuildReport(t *testing.T) {
+	outcomes := []Outcome{
+		{
+			Example:  &Example{ID: "1", Category: "Water", Priority: "high"},
+			Category: "WATER", Priority: "high",
+			Latency: 100 * time.Millisecond, PromptTokens: 1000, CompletionTokens: 100,
+		},
+		{
+			Example:  &Example{ID: "2", Category: "Roads", Priority: "medium"},
+			Category: "Water", Priority: "medium",
+			Latency: 200 * time.Millisecond, Fallback: true,
+		},
+		{
+			Example:  &Example{ID: "3", Rejected: true},
+			Rejected: true,
+			Latency:  50 * time.Millisecond,
+		},
+		{
+			Example:  &Example{ID: "4", Category: "Water"},
+			Rejected: true,
+			Latency:  300 * time.Millisecond,
+		},
+		{
+			Example: &Example{ID: "5", Category: "Roads"},
+			Err:     errors.New("timeout"),
+			Latency: time.Second,
+		},
+	}
+
+	report := buildReport(outcomes, 3, 15)
+	m := report.Metrics
+
+	ints := []struct {
+		name      string
+		got, want int64
+	}{
+		{"total", int64(m.Total), 5},
+		{"errors", int64(m.Errors), 1},
+		{"fallbacks", int64(m.Fallbacks), 1},
+		{"prompt tokens", int64(m.PromptTokens), 1000},
+		{"completion tokens", int64(m.CompletionTokens), 100},
+		{"mean latency", m.LatencyMeanMs, 330},
+		{"p95 latency", m.LatencyP95Ms, 1000},
+		{"misses", int64(len(report.Misses)), 3},
+	}
+	for _, tt := range ints {
+		if tt.got != tt.want {
+			t.Errorf("%s = %d, want %d", tt.name, tt.got, tt.want)
+		}
+	}
+
+	rates := []struct {
+		name      string
+		got, want float64
+	}{
+		{"accuracy", m.Accuracy, 0.25},
+		{"priority agreement", m.PriorityAgreement, 1},
+		{"rejection precision", m.RejectionPrecision, 0.5},
+		{"rejection recall", m.RejectionRecall, 1},
+		{"cost", m.CostUSD, 0.0045},
+	}
+	for _, tt := range rates {
+		if math.Abs(tt.got-tt.want) > 1e-9 {
+			t.Errorf("%s = %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+
+	confusion := []struct {
+		expected, predicted string
+		want                int
+	}{
+		{"Water", "Water", 1},
+		{"Water", labelRejected, 1},
+		{"Roads", "Water", 1},
+		{"Roads", labelError, 1},
+		{labelRejected, labelRejected, 1},
+	}
+	for _, tt := range confusion {
+		if got := report.Confusion[tt.expected][tt.predicted]; got != tt.want {
+			t.Errorf("confusion[%s][%s] = %d, want %d", tt.expected, tt.predicted, got, tt.want)
+		}
+	}
+
+	if miss := report.Misses[2]; miss.ID != "5" || miss.Predicted != labelError || miss.Error != "timeout" {
+		t.Errorf("miss for the failed example = %+v", miss)
+	}
+}
+
+func TestBuildReportEmpty(t *testing.T) {
+	m := buildReport(nil, 1, 1).Metrics
+	if m.Total != 0 || m.Accuracy != 1 || m.RejectionRecall != 1 || m.LatencyP95Ms != 0 {
+		t.Errorf("metrics for no outcomes = %+v", m)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	baseline := &Metrics{Accuracy: 0.9, PriorityAgreement: 0.8, RejectionPrecision: 0.95, RejectionRecall: 0.9}
+
+	current := *baseline
+	current.Accuracy = 0.89
+	if regressions := compare(baseline, &current, 0.02); len(regressions) != 0 {
+		t.Errorf("drop within tolerance reported as %v", regressions)
+	}
+
+	current.Accuracy = 0.85
+	current.RejectionRecall = 0.7
+	current.PriorityAgreement = 0.95
+	if regressions := compare(baseline, &current, 0.02); len(regressions) != 2 {
+		t.Errorf("compare() = %v, want accuracy and rejection_recall", regressions)
+	}
+}
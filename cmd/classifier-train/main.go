@@ -0,0 +1,213 @@
+// Command classifier-train trains the local statistical classifier from
+// historical complaints and writes a versioned model file for the server.
+//
+// Usage:
+//
+//	go run ./cmd/classifier-train [-out models/classifier.json] [-holdout 0.2]
+//	go run ./cmd/classifier-train -dataset labeled.jsonl -categories categories.json
+//
+// Without -dataset, examples are loaded from Supabase: staff-reviewed labels
+// first, then resolved and closed complaints with their final category. A
+// dataset uses the classifier-eval format; rejected examples are skipped.
+//
+// The server loads the model at startup, so restart it after retraining.
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/hakim/backend/internal/config"
+	"github.com/hakim/backend/internal/textmodel"
+	"github.com/hakim/backend/pkg/supabase"
+)
+
+func main() {
+	if err := config.Load(); err != nil {
+		fatal("Failed to load config: %v", err)
+	}
+
+	out := flag.String("out", config.AppConfig.LocalModelPath, "where to write the model file")
+	datasetPath := flag.String("dataset", "", "labeled JSONL dataset; loaded from Supabase when empty")
+	categoriesPath := flag.String("categories", "", "JSON category list mapping dataset names to IDs (required with -dataset)")
+	limit := flag.Int("limit", 10000, "maximum examples loaded from Supabase")
+	holdout := flag.Float64("holdout", 0.2, "fraction of examples held out to measure accuracy; 0 to skip")
+	seed := flag.Int64("seed", 1, "random seed for the holdout split")
+	minDF := flag.Int("min-df", 2, "drop terms found in fewer documents than this")
+	minExamples := flag.Int("min-examples", 20, "refuse to train on fewer examples")
+	flag.Parse()
+
+	var examples []textmodel.Example
+	var err error
+	if *datasetPath != "" {
+		if *categoriesPath == "" {
+			fatal("-categories is required with -dataset")
+		}
+		examples, err = loadDataset(*datasetPath, *categoriesPath)
+	} else {
+		examples, err = loadSupabase(supabase.New(), *limit)
+	}
+	if err != nil {
+		fatal("Failed to load training examples: %v", err)
+	}
+	if len(examples) < *minExamples {
+		fatal("Only %d examples, need at least %d", len(examples), *minExamples)
+	}
+
+	printCounts(examples)
+	opts := textmodel.TrainOptions{MinDocFrequency: *minDF}
+
+	var accuracy *float64
+	if *holdout > 0 && *holdout < 1 {
+		rng := rand.New(rand.NewSource(*seed))
+		rng.Shuffle(len(examples), func(i, j int) { examples[i], examples[j] = examples[j], examples[i] })
+
+		split := len(examples) - int(float64(len(examples))**holdout)
+		train, test := examples[:split], examples[split:]
+		if len(test) > 0 {
+			model, err := textmodel.Train(train, opts)
+			if err != nil {
+				fatal("Training failed: %v", err)
+			}
+			correct := 0
+			for _, example := range test {
+				if p := model.Predict(example.Text); p != nil && p.Label.ID == example.Label.ID {
+					correct++
+				}
+			}
+			a := float64(correct) / float64(len(test))
+			accuracy = &a
+			fmt.Printf("\nHoldout accuracy: %.3f (%d/%d)\n", a, correct, len(test))
+		}
+	}
+
+	// The shipped model is trained on everything
+	model, err := textmodel.Train(examples, opts)
+	if err != nil {
+		fatal("Training failed: %v", err)
+	}
+	model.HoldoutAccuracy = accuracy
+
+	if err := model.Save(*out); err != nil {
+		fatal("Failed to write model: %v", err)
+	}
+	fmt.Printf("\nModel %s written to %s (%d examples, %d labels, %d terms)\n",
+		model.Version, *out, model.Examples, len(model.Labels), len(model.Vocabulary))
+}
+
+func loadSupabase(client *supabase.Client, limit int) ([]textmodel.Example, error) {
+	categories, err := client.GetCategories()
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[string]string, len(categories))
+	for _, cat := range categories {
+		names[cat.ID.String()] = cat.Name
+	}
+
+	rows, err := client.GetTrainingExamples(limit)
+	if err != nil {
+		return nil, err
+	}
+
+	examples := make([]textmodel.Example, 0, len(rows))
+	for _, row := range rows {
+		// Categories deactivated since are no longer valid routing targets
+		name, ok := names[row.CategoryID]
+		if !ok {
+			continue
+		}
+		examples = append(examples, textmodel.Example{
+			Text:  row.Title + " " + row.Description,
+			Label: textmodel.Label{ID: row.CategoryID, Name: name},
+		})
+	}
+	return examples, nil
+}
+
+func loadDataset(datasetPath, categoriesPath string) ([]textmodel.Example, error) {
+	data, err := os.ReadFile(categoriesPath)
+	if err != nil {
+		return nil, err
+	}
+	var categories []supabase.Category
+	if err := json.Unmarshal(data, &categories); err != nil {
+		return nil, fmt.Errorf("%s: %w", categoriesPath, err)
+	}
+	byName := make(map[string]supabase.Category, len(categories))
+	for _, cat := range categories {
+		byName[strings.ToLower(cat.Name)] = cat
+	}
+
+	file, err := os.Open(datasetPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var examples []textmodel.Example
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		var row struct {
+			Title       string `json:"title"`
+			Description string `json:"description"`
+			Category    string `json:"category"`
+			Rejected    bool   `json:"rejected"`
+		}
+		if err := json.Unmarshal([]byte(text), &row); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if row.Rejected {
+			continue
+		}
+		cat, ok := byName[strings.ToLower(strings.TrimSpace(row.Category))]
+		if !ok {
+			return nil, fmt.Errorf("line %d: unknown category %q", line, row.Category)
+		}
+		examples = append(examples, textmodel.Example{
+			Text:  row.Title + " " + row.Description,
+			Label: textmodel.Label{ID: cat.ID.String(), Name: cat.Name},
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return examples, nil
+}
+
+func printCounts(examples []textmodel.Example) {
+	counts := make(map[string]int)
+	for _, example := range examples {
+		counts[example.Label.Name]++
+	}
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Printf("Training on %d examples:\n", len(examples))
+	for _, name := range names {
+		fmt.Printf("  %-30s %d\n", name, counts[name])
+	}
+}
+
+func fatal(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+	os.Exit(2)
+}
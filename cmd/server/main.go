@@ -1,19 +1,32 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/gofiber/fiber/v2/middleware/recover"
 	"github.com/hakim/backend/internal/ai"
+	"github.com/hakim/backend/internal/bulk"
 	"github.com/hakim/backend/internal/config"
+	"github.com/hakim/backend/internal/dedup"
 	"github.com/hakim/backend/internal/handlers"
+	"github.com/hakim/backend/internal/imageprep"
+	"github.com/hakim/backend/internal/knowledge"
 	"github.com/hakim/backend/internal/middleware"
+	"github.com/hakim/backend/internal/pipeline"
+	"github.com/hakim/backend/internal/redact"
+	"github.com/hakim/backend/internal/rules"
+	"github.com/hakim/backend/internal/safeurl"
+	"github.com/hakim/backend/internal/textmodel"
+	"github.com/hakim/backend/internal/transcribe"
 	"github.com/hakim/backend/pkg/supabase"
 )
 
@@ -26,8 +39,104 @@ func main() {
 	// Initialize Supabase client
 	supabaseClient := supabase.New()
 
-	// Initialize AI classifier
-	classifier := ai.NewClassifier(supabaseClient)
+	// Initialize LLM provider chain and AI classifier
+	provider, err := ai.NewProviderFromConfig(config.AppConfig)
+	if err != nil {
+		log.Fatalf("Failed to configure LLM provider: %v", err)
+	}
+	if provider == nil {
+		log.Println("⚠️  No LLM provider configured, using keyword classification only")
+	}
+	// Keyword and screening rules are managed in the database and hot-reloaded
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	ruleEngine := rules.NewEngine(supabaseClient)
+	ruleEngine.DemoteOverturned(config.AppConfig.FilterOverrideMinAppeals, config.AppConfig.FilterOverrideRate)
+	ruleEngine.Start(workerCtx, time.Duration(config.AppConfig.RulesReloadSeconds)*time.Second)
+
+	// Categories are cached; a change to the set also invalidates cached verdicts
+	categories := ai.NewCategoryCache(supabaseClient, time.Duration(config.AppConfig.CategoryCacheSeconds)*time.Second)
+	classifier := ai.NewClassifier(categories, provider, ruleEngine)
+	if minutes := config.AppConfig.ClassificationCacheMinutes; minutes > 0 {
+		classifier.UseCache(ai.NewResultCache(config.AppConfig.ClassificationCacheSize, time.Duration(minutes)*time.Minute))
+	}
+
+	// LLM calls are costed, and optionally capped by a monthly budget
+	pricing, err := ai.ParsePricing(ai.Price{
+		Prompt:     config.AppConfig.LLMPromptPrice,
+		Completion: config.AppConfig.LLMCompletionPrice,
+	}, config.AppConfig.LLMModelPrices)
+	if err != nil {
+		log.Fatalf("Failed to configure LLM pricing: %v", err)
+	}
+	classifier.UsePricing(pricing)
+
+	var budget *ai.Budget
+	if config.AppConfig.LLMMonthlyBudget > 0 {
+		budget = ai.NewBudget(supabaseClient, config.AppConfig.LLMMonthlyBudget, config.AppConfig.LLMBudgetLowDetailAt)
+		budget.Start(workerCtx, time.Minute)
+		classifier.UseBudget(budget)
+	}
+
+	// Personal data is stripped before complaint text reaches the LLM
+	var redactor *redact.Redactor
+	if config.AppConfig.PIIRedaction {
+		redactor, err = redact.New(config.AppConfig.PIIRedactionKinds)
+		if err != nil {
+			log.Fatalf("Failed to configure PII redaction: %v", err)
+		}
+		classifier.UseRedactor(redactor)
+	} else if provider != nil {
+		log.Println("⚠️  PII redaction is disabled, complaint text is sent to the LLM as written")
+	}
+
+	// Local statistical model for offline classification and second opinions
+	if localModel, err := textmodel.Load(config.AppConfig.LocalModelPath); err == nil {
+		classifier.UseLocalModel(localModel, config.AppConfig.LocalModelMinConfidence)
+		log.Printf("Local classification model %s loaded (%d examples)", localModel.Version, localModel.Examples)
+	} else if errors.Is(err, os.ErrNotExist) {
+		log.Println("⚠️  No local classification model found, run cmd/classifier-train to create one")
+	} else {
+		log.Printf("⚠️  Failed to load local classification model: %v", err)
+	}
+
+	// Initialize duplicate detector
+	detector := dedup.NewDetector(supabaseClient)
+
+	// Attachment URLs are limited to allowed storage locations
+	attachmentPolicy, err := safeurl.NewPolicy(config.AppConfig.AttachmentURLPatterns)
+	if err != nil {
+		log.Fatalf("Failed to configure attachment URLs: %v", err)
+	}
+
+	// Attachments are fetched and cleaned by the server rather than the LLM
+	images := imageprep.New(imageprep.Options{
+		MaxBytes:     config.AppConfig.ImageMaxBytes,
+		MaxDimension: config.AppConfig.ImageMaxDimension,
+		Timeout:      time.Duration(config.AppConfig.ImageFetchTimeoutSeconds) * time.Second,
+		Policy:       attachmentPolicy,
+		Authorize:    supabaseClient.AuthorizeStorageRequest,
+	})
+
+	// Voice notes are transcribed so they can be classified and read by staff
+	transcriber, err := transcribe.NewFromConfig(config.AppConfig)
+	if err != nil {
+		log.Fatalf("Failed to configure transcriber: %v", err)
+	}
+	var voice *transcribe.Service
+	if transcriber != nil {
+		voice = transcribe.NewService(transcriber, transcribe.Options{
+			MaxBytes:  config.AppConfig.VoiceMaxBytes,
+			Timeout:   time.Duration(config.AppConfig.VoiceTimeoutSeconds) * time.Second,
+			Policy:    attachmentPolicy,
+			Authorize: supabaseClient.AuthorizeStorageRequest,
+		})
+	} else {
+		log.Println("⚠️  No transcriber configured, voice notes are stored without transcripts")
+	}
+
+	// Start background classification workers
+	classificationPipeline := pipeline.NewClassifier(supabaseClient, classifier, detector, knowledge.NewMatcher(supabaseClient), images, voice)
+	classificationPipeline.Start(workerCtx)
 
 	// Create Fiber app
 	app := fiber.New(fiber.Config{
@@ -48,9 +157,18 @@ func main() {
 
 	// Initialize handlers
 	authHandler := handlers.NewAuthHandler(supabaseClient)
-	complaintHandler := handlers.NewComplaintHandler(supabaseClient, classifier)
-	adminHandler := handlers.NewAdminHandler(supabaseClient)
+	complaintHandler := handlers.NewComplaintHandler(supabaseClient, classifier, detector, classificationPipeline, attachmentPolicy)
+	adminHandler := handlers.NewAdminHandler(supabaseClient, detector)
 	publicHandler := handlers.NewPublicHandler(supabaseClient)
+	bulkRunner := bulk.NewRunner(supabaseClient)
+	bulkRunner.Start(workerCtx)
+	bulkHandler := handlers.NewBulkHandler(supabaseClient, bulkRunner)
+	reviewHandler := handlers.NewReviewHandler(supabaseClient)
+	ruleHandler := handlers.NewRuleHandler(supabaseClient, ruleEngine)
+	appealHandler := handlers.NewAppealHandler(supabaseClient, classificationPipeline, ruleEngine)
+	usageHandler := handlers.NewUsageHandler(supabaseClient, budget)
+	knowledgeHandler := handlers.NewKnowledgeHandler(supabaseClient)
+	draftHandler := handlers.NewDraftHandler(supabaseClient, ai.NewDrafter(provider, redactor, pricing, budget))
 
 	// Routes
 	api := app.Group("/api/v1")
@@ -105,6 +223,7 @@ func main() {
 	api.Get("/categories", adminHandler.ListCategories)
 	api.Get("/public/map", publicHandler.GetPublicMapData)
 	api.Get("/public/map/stats", publicHandler.GetPublicMapStats)
+	api.Get("/knowledge-base", knowledgeHandler.Search)
 
 	// Protected routes
 	protected := api.Group("", middleware.AuthMiddleware(supabaseClient))
@@ -121,14 +240,57 @@ func main() {
 	complaints.Put("/:id", complaintHandler.Update)
 	complaints.Post("/:id/feedback", complaintHandler.SubmitFeedback)
 	complaints.Get("/:id/history", complaintHandler.GetStatusHistory)
+	complaints.Post("/:id/endorse", complaintHandler.Endorse)
+	complaints.Delete("/:id/endorse", complaintHandler.RemoveEndorsement)
+	complaints.Post("/:id/appeal", complaintHandler.Appeal)
+	complaints.Get("/:id/appeal", complaintHandler.GetAppeal)
+	complaints.Get("/:id/clarifications", complaintHandler.GetClarifications)
+	complaints.Post("/:id/clarifications", complaintHandler.AnswerClarifications)
+	complaints.Get("/:id/deflection", complaintHandler.GetDeflection)
+	complaints.Post("/:id/deflection/escalate", complaintHandler.EscalateDeflection)
 
 	// Admin routes (requires admin role)
 	admin := protected.Group("/admin", middleware.AdminMiddleware(supabaseClient))
 	admin.Get("/complaints", adminHandler.ListComplaints)
+	admin.Post("/complaints/bulk", bulkHandler.Apply)
+	admin.Get("/bulk-jobs/:id", bulkHandler.GetJob)
 	admin.Get("/complaints/:id", adminHandler.GetComplaint)
 	admin.Put("/complaints/:id/assign", adminHandler.AssignComplaint)
 	admin.Put("/complaints/:id/status", adminHandler.UpdateStatus)
+	admin.Get("/complaints/:id/duplicates", adminHandler.GetDuplicates)
+	admin.Get("/complaints/:id/attachments", adminHandler.GetAttachments)
+	admin.Post("/complaints/:id/merge", adminHandler.MergeComplaints)
+	admin.Post("/complaints/:id/transfer", adminHandler.TransferComplaint)
+	admin.Post("/complaints/:id/draft-reply", draftHandler.DraftReply)
+	admin.Post("/complaints/:id/resolution-summary", draftHandler.ResolutionSummary)
+	admin.Get("/drafts", draftHandler.List)
+	admin.Put("/drafts/:id", draftHandler.Finalize)
+	admin.Get("/departments/:id/reply-guidelines", draftHandler.GetGuidelines)
+	admin.Put("/departments/:id/reply-guidelines", draftHandler.SetGuidelines)
+	admin.Get("/transfers", adminHandler.ListTransfers)
+	admin.Post("/transfers/:id/accept", adminHandler.AcceptTransfer)
+	admin.Post("/transfers/:id/reject", adminHandler.RejectTransfer)
+	admin.Get("/reviews", reviewHandler.List)
+	admin.Post("/reviews/:id/confirm", reviewHandler.Confirm)
+	admin.Post("/reviews/:id/correct", reviewHandler.Correct)
+	admin.Get("/appeals", appealHandler.List)
+	admin.Get("/appeals/stats", appealHandler.Stats)
+	admin.Post("/appeals/:id/uphold", appealHandler.Uphold)
+	admin.Post("/appeals/:id/overturn", appealHandler.Overturn)
+	admin.Get("/classification-rules", ruleHandler.List)
+	admin.Post("/classification-rules", ruleHandler.Create)
+	admin.Post("/classification-rules/dry-run", ruleHandler.DryRun)
+	admin.Put("/classification-rules/:id", ruleHandler.Update)
+	admin.Delete("/classification-rules/:id", ruleHandler.Delete)
+	admin.Get("/knowledge-base", knowledgeHandler.List)
+	admin.Post("/knowledge-base", knowledgeHandler.Create)
+	admin.Put("/knowledge-base/:id", knowledgeHandler.Update)
+	admin.Delete("/knowledge-base/:id", knowledgeHandler.Delete)
 	admin.Get("/analytics", adminHandler.GetAnalytics)
+	admin.Get("/analytics/transfers", adminHandler.GetTransferStats)
+	admin.Get("/analytics/ai-usage", usageHandler.GetAIUsage)
+	admin.Get("/analytics/category-disagreements", adminHandler.GetCategoryDisagreements)
+	admin.Get("/analytics/deflections", knowledgeHandler.Stats)
 	admin.Get("/employees", adminHandler.ListEmployees)
 
 	// Graceful shutdown
@@ -152,6 +314,12 @@ func main() {
 	if err := app.Listen(":" + port); err != nil {
 		log.Fatalf("Failed to start server: %v", err)
 	}
+
+	// Let in-flight classifications finish; unfinished jobs stay queued, and
+	// a running bulk job is handed back to the queue after its current item
+	stopWorkers()
+	classificationPipeline.Wait()
+	bulkRunner.Wait()
 }
 
 // errorHandler handles global errors
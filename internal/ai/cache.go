@@ -0,0 +1,211 @@
+package ai
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hakim/backend/internal/arabic"
+	"github.com/hakim/backend/pkg/supabase"
+)
+
+// CategoryCache keeps the active category list in memory so a burst of
+// classifications does not fetch it once per complaint. Categories are
+// edited in Supabase rather than through this server, so a change is picked
+// up only when the TTL expires; cached verdicts made against the old list
+// are discarded then.
+type CategoryCache struct {
+	source CategorySource
+	ttl    time.Duration
+
+	mu         sync.Mutex
+	categories []supabase.Category
+	fetchedAt  time.Time
+}
+
+// NewCategoryCache wraps a category source with a cache of the given TTL
+func NewCategoryCache(source CategorySource, ttl time.Duration) *CategoryCache {
+	return &CategoryCache{source: source, ttl: ttl}
+}
+
+// GetCategories returns the cached list, refetching it once the TTL has
+// passed. A stale list is served if the refetch fails.
+func (c *CategoryCache) GetCategories() ([]supabase.Category, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.categories != nil && time.Since(c.fetchedAt) < c.ttl {
+		return c.categories, nil
+	}
+
+	categories, err := c.source.GetCategories()
+	if err != nil {
+		if c.categories != nil {
+			slog.Warn("Failed to refresh categories, using cached list", "error", err)
+			return c.categories, nil
+		}
+		return nil, err
+	}
+
+	c.categories = categories
+	c.fetchedAt = time.Now()
+	return categories, nil
+}
+
+// categoryFingerprint identifies a category set; cached classifications made
+// against a different set are discarded
+func categoryFingerprint(categories []supabase.Category) string {
+	data, _ := json.Marshal(categories)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// contentKey hashes the normalized complaint text and its images, so
+// re-submissions that differ only in spelling variants or spacing share an
+// entry
+func contentKey(title, description string, imageURLs []string) string {
+	images := append([]string(nil), imageURLs...)
+	sort.Strings(images)
+
+	h := sha256.New()
+	h.Write([]byte(arabic.Normalize(title)))
+	h.Write([]byte{0})
+	h.Write([]byte(arabic.Normalize(description)))
+	h.Write([]byte{0})
+	h.Write([]byte(strings.Join(images, "\x00")))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// outcome is a cacheable LLM verdict: a classification or a rejection
+type outcome struct {
+	result    *supabase.ClassificationResult
+	rejection *RejectionError
+}
+
+// copy returns the outcome for reuse. No LLM call is made for it, so usage
+// and token counts are cleared.
+func (o *outcome) copy() (*supabase.ClassificationResult, error) {
+	if o.rejection != nil {
+		rejection := *o.rejection
+		rejection.Usage = nil
+		return nil, &rejection
+	}
+	result := *o.result
+	result.Usage = nil
+	result.PromptTokens = 0
+	result.CompletionTokens = 0
+	result.Cached = true
+	return &result, nil
+}
+
+type cacheEntry struct {
+	outcome *outcome
+	expires time.Time
+}
+
+// pendingCall is an LLM call in flight that identical complaints wait for
+type pendingCall struct {
+	done    chan struct{}
+	outcome *outcome
+	err     error
+}
+
+// ResultCache stores LLM classifications by content hash. Identical
+// complaints arriving together share one LLM call, and every entry is dropped
+// when the category set changes.
+type ResultCache struct {
+	ttl  time.Duration
+	size int
+
+	mu          sync.Mutex
+	fingerprint string
+	entries     map[string]*cacheEntry
+	pending     map[string]*pendingCall
+}
+
+// NewResultCache creates a cache holding up to size results for ttl
+func NewResultCache(size int, ttl time.Duration) *ResultCache {
+	if size < 1 {
+		size = 1000
+	}
+	return &ResultCache{
+		ttl:     ttl,
+		size:    size,
+		entries: make(map[string]*cacheEntry),
+		pending: make(map[string]*pendingCall),
+	}
+}
+
+// do returns the cached outcome for the key, or runs classify once for all
+// concurrent callers with the same key. Only classifications and rejections
+// are cached; provider errors are returned to every waiting caller.
+func (c *ResultCache) do(key, fingerprint string, classify func() (*supabase.ClassificationResult, error)) (*supabase.ClassificationResult, error) {
+	c.mu.Lock()
+	if fingerprint != c.fingerprint {
+		c.fingerprint = fingerprint
+		c.entries = make(map[string]*cacheEntry)
+	}
+	if entry, ok := c.entries[key]; ok && time.Now().Before(entry.expires) {
+		c.mu.Unlock()
+		return entry.outcome.copy()
+	}
+	if call, ok := c.pending[key]; ok {
+		c.mu.Unlock()
+		<-call.done
+		if call.outcome == nil {
+			return nil, call.err
+		}
+		return call.outcome.copy()
+	}
+	call := &pendingCall{done: make(chan struct{})}
+	c.pending[key] = call
+	c.mu.Unlock()
+
+	result, err := classify()
+	if err == nil {
+		// The caller goes on to modify its result, so the cache keeps a copy
+		snapshot := *result
+		call.outcome = &outcome{result: &snapshot}
+	} else if rejection, rejected := AsRejection(err); rejected {
+		call.outcome = &outcome{rejection: rejection}
+	}
+	call.err = err
+
+	c.mu.Lock()
+	delete(c.pending, key)
+	if call.outcome != nil && fingerprint == c.fingerprint {
+		c.store(key, call.outcome)
+	}
+	c.mu.Unlock()
+	close(call.done)
+
+	return result, err
+}
+
+// store adds an entry, evicting expired entries and then the oldest when
+// the cache is full. Callers hold the lock.
+func (c *ResultCache) store(key string, o *outcome) {
+	if len(c.entries) >= c.size {
+		now := time.Now()
+		oldestKey := ""
+		var oldest time.Time
+		for k, entry := range c.entries {
+			if now.After(entry.expires) {
+				delete(c.entries, k)
+				continue
+			}
+			if oldestKey == "" || entry.expires.Before(oldest) {
+				oldestKey, oldest = k, entry.expires
+			}
+		}
+		if len(c.entries) >= c.size && oldestKey != "" {
+			delete(c.entries, oldestKey)
+		}
+	}
+	c.entries[key] = &cacheEntry{outcome: o, expires: time.Now().Add(c.ttl)}
+}
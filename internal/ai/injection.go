@@ -0,0 +1,86 @@
+package ai
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"regexp"
+	"strings"
+
+	"github.com/hakim/backend/internal/arabic"
+)
+
+// Manipulation signals recorded on a classification; any of them sends it
+// to staff review
+const (
+	SignalIgnoreInstructions = "ignore_instructions"
+	SignalRoleOverride       = "role_override"
+	SignalSetClassification  = "set_classification"
+	SignalOutputFormat       = "output_format"
+	SignalPriorityMismatch   = "priority_mismatch"
+)
+
+// injectionPatterns match text that addresses the model instead of
+// describing a problem. They run on normalized text, so Arabic patterns are
+// written without hamza or taa marbuta.
+var injectionPatterns = []struct {
+	signal string
+	re     *regexp.Regexp
+}{
+	{SignalIgnoreInstructions, regexp.MustCompile(`\b(ignore|disregard|forget|override)\b.{0,30}\b(instructions?|rules|prompt|above|previous)\b`)},
+	{SignalIgnoreInstructions, regexp.MustCompile(`(تجاهل|انس|تناس|الغ)\S*.{0,30}(تعليمات|اوامر|قواعد|ما سبق|السابق)`)},
+	{SignalRoleOverride, regexp.MustCompile(`\b(you are now|act as|pretend to be|system prompt|developer mode|jailbreak)\b`)},
+	{SignalRoleOverride, regexp.MustCompile(`(انت الان|تصرف ك|تظاهر بانك|موجه النظام|تعليمات النظام)`)},
+	{SignalSetClassification, regexp.MustCompile(`\b(set|change|make|mark|classify)\b.{0,20}\b(priority|category|classification)\b`)},
+	{SignalSetClassification, regexp.MustCompile(`(اجعل|ضع|عين|غير|حدد|صنف)\S* (ال)?(اولويه|تصنيف|فئه)`)},
+	{SignalOutputFormat, regexp.MustCompile(`"?\b(category_name|rejected|rejection_reason|confidence)\b"? ?:|</?(system|assistant|user|complaint)|\[/?inst\]`)},
+}
+
+// priorityRank orders priorities for the consistency check
+var priorityRank = map[string]int{
+	"low":      0,
+	"medium":   1,
+	"high":     2,
+	"critical": 3,
+}
+
+// injectionSignals lists the instruction-like patterns found in the text
+func injectionSignals(text string) []string {
+	normalized := arabic.Normalize(text)
+
+	var signals []string
+	for _, p := range injectionPatterns {
+		if p.re.MatchString(normalized) && !containsString(signals, p.signal) {
+			signals = append(signals, p.signal)
+		}
+	}
+	return signals
+}
+
+// priorityMismatch reports whether the LLM priority is two or more levels
+// away from the one keyword rules found. Without a keyword signal there is
+// nothing to compare against.
+func priorityMismatch(llmPriority, keywordPriority string) bool {
+	if keywordPriority == "" {
+		return false
+	}
+	diff := priorityRank[llmPriority] - priorityRank[keywordPriority]
+	return diff >= 2 || diff <= -2
+}
+
+// contentTag returns a random tag name for wrapping citizen text, so the
+// text cannot close the section it is placed in
+func contentTag() string {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return "complaint"
+	}
+	return "complaint-" + hex.EncodeToString(b)
+}
+
+// wrapUntrusted places citizen text between the tags, dropping anything that
+// looks like the tag itself
+func wrapUntrusted(tag, text string) string {
+	text = strings.ReplaceAll(text, "<"+tag, "")
+	text = strings.ReplaceAll(text, "</"+tag, "")
+	return "<" + tag + ">\n" + text + "\n</" + tag + ">"
+}
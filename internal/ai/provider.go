@@ -0,0 +1,165 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hakim/backend/internal/config"
+)
+
+// Provider sends chat completion requests to an LLM backend
+type Provider interface {
+	// Name identifies the provider in logs and stored classification output
+	Name() string
+	Complete(ctx context.Context, req *ChatRequest) (*ChatResponse, error)
+}
+
+// ChatRequest is a provider-neutral chat completion request. Zero values
+// for Temperature and MaxTokens use the provider's configured defaults.
+// ResponseSchema is sent only to providers that support structured output.
+type ChatRequest struct {
+	Messages       []OpenAIMessage
+	Temperature    float64
+	MaxTokens      int
+	ResponseSchema *JSONSchema
+}
+
+// ChatResponse is the text reply and the model that actually produced it.
+// Token counts are zero when the provider does not report usage.
+type ChatResponse struct {
+	Content          string
+	Model            string
+	Provider         string
+	Latency          time.Duration
+	PromptTokens     int
+	CompletionTokens int
+}
+
+// FallbackProvider tries each provider in order until one succeeds
+type FallbackProvider struct {
+	providers []Provider
+}
+
+func NewFallbackProvider(providers ...Provider) *FallbackProvider {
+	return &FallbackProvider{providers: providers}
+}
+
+func (f *FallbackProvider) Name() string {
+	names := make([]string, 0, len(f.providers))
+	for _, p := range f.providers {
+		names = append(names, p.Name())
+	}
+	return strings.Join(names, ">")
+}
+
+func (f *FallbackProvider) Complete(ctx context.Context, req *ChatRequest) (*ChatResponse, error) {
+	var errs []error
+	for _, p := range f.providers {
+		resp, err := p.Complete(ctx, req)
+		if err == nil {
+			return resp, nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", p.Name(), err))
+
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, errors.Join(errs...)
+}
+
+// NewProviderFromConfig builds the provider chain listed in LLM_PROVIDERS.
+// Providers missing required settings are skipped; nil means no LLM is
+// available and classification uses keywords only. Listing a provider twice,
+// or two providers with the same endpoint and model, is an error.
+func NewProviderFromConfig(cfg *config.Config) (Provider, error) {
+	timeout := time.Duration(cfg.LLMTimeoutSeconds) * time.Second
+
+	var providers []Provider
+	// Each entry must be a different endpoint or model, or falling back to
+	// it would only repeat the failed call
+	seen := make(map[string]string)
+	checkDistinct := func(name, baseURL, model string) error {
+		key := strings.ToLower(strings.TrimSuffix(baseURL, "/")) + " " + model
+		if other, ok := seen[key]; ok {
+			return fmt.Errorf("LLM providers %q and %q use the same endpoint and model", other, name)
+		}
+		seen[key] = name
+		return nil
+	}
+
+	listed := make(map[string]bool)
+	for _, name := range cfg.LLMProviders {
+		name = strings.ToLower(name)
+		if listed[name] {
+			return nil, fmt.Errorf("LLM provider %q is listed twice", name)
+		}
+		listed[name] = true
+
+		switch name {
+		case "openrouter":
+			if cfg.OpenAIKey == "" {
+				continue
+			}
+			model := cfg.OpenRouterModel
+			if model == "" {
+				model = cfg.LLMModel
+			}
+			if err := checkDistinct(name, cfg.OpenRouterBaseURL, model); err != nil {
+				return nil, err
+			}
+			providers = append(providers, NewOpenAICompatibleProvider(OpenAICompatibleOptions{
+				Name:        "openrouter",
+				BaseURL:     cfg.OpenRouterBaseURL,
+				APIKey:      cfg.OpenAIKey,
+				Model:       model,
+				Temperature: cfg.LLMTemperature,
+				MaxTokens:   cfg.LLMMaxTokens,
+				Timeout:     timeout,
+				Structured:  cfg.OpenRouterStructuredOutput,
+				Headers: map[string]string{
+					"HTTP-Referer": cfg.LLMHTTPReferer,
+					"X-Title":      cfg.LLMAppTitle,
+				},
+			}))
+		case "openai", "openai-compatible", "ollama", "vllm":
+			endpoint := cfg.LLMEndpoints[name]
+			if endpoint.BaseURL == "" {
+				continue
+			}
+			model := endpoint.Model
+			if model == "" {
+				model = cfg.LLMModel
+			}
+			if err := checkDistinct(name, endpoint.BaseURL, model); err != nil {
+				return nil, err
+			}
+			providers = append(providers, NewOpenAICompatibleProvider(OpenAICompatibleOptions{
+				Name:        name,
+				BaseURL:     endpoint.BaseURL,
+				APIKey:      endpoint.APIKey,
+				Model:       model,
+				Temperature: cfg.LLMTemperature,
+				MaxTokens:   cfg.LLMMaxTokens,
+				Timeout:     timeout,
+				Structured:  endpoint.StructuredOutput,
+			}))
+		case "fake":
+			providers = append(providers, &FakeProvider{})
+		default:
+			return nil, fmt.Errorf("unknown LLM provider: %q", name)
+		}
+	}
+
+	switch len(providers) {
+	case 0:
+		return nil, nil
+	case 1:
+		return providers[0], nil
+	default:
+		return NewFallbackProvider(providers...), nil
+	}
+}
@@ -0,0 +1,190 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// OpenAI request/response structures
+type OpenAIRequest struct {
+	Model          string          `json:"model"`
+	Messages       []OpenAIMessage `json:"messages"`
+	Temperature    float64         `json:"temperature"`
+	MaxTokens      int             `json:"max_tokens"`
+	ResponseFormat *ResponseFormat `json:"response_format,omitempty"`
+}
+
+// ResponseFormat requests structured output validated against a JSON schema
+type ResponseFormat struct {
+	Type       string            `json:"type"`
+	JSONSchema *ResponseFormatJS `json:"json_schema,omitempty"`
+}
+
+type ResponseFormatJS struct {
+	Name   string                 `json:"name"`
+	Strict bool                   `json:"strict"`
+	Schema map[string]interface{} `json:"schema"`
+}
+
+// ContentPart represents either text or image content
+type ContentPart struct {
+	Type     string    `json:"type"`
+	Text     string    `json:"text,omitempty"`
+	ImageURL *ImageURL `json:"image_url,omitempty"`
+}
+
+type ImageURL struct {
+	URL    string `json:"url"`
+	Detail string `json:"detail,omitempty"` // "low", "high", or "auto"
+}
+
+type OpenAIMessage struct {
+	Role    string      `json:"role"`
+	Content interface{} `json:"content"` // Can be string or []ContentPart
+}
+
+type OpenAIResponse struct {
+	Model   string `json:"model"`
+	Choices []struct {
+		Message struct {
+			Content string `json:"content"`
+		} `json:"message"`
+	} `json:"choices"`
+	Usage *struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+type OpenAICompatibleOptions struct {
+	Name        string
+	BaseURL     string
+	APIKey      string
+	Model       string
+	Temperature float64
+	MaxTokens   int
+	Timeout     time.Duration
+	// Structured enables json_schema response_format for endpoints that support it
+	Structured bool
+	Headers    map[string]string
+}
+
+// OpenAICompatibleProvider talks to any /chat/completions endpoint in the
+// OpenAI format: OpenRouter, OpenAI itself, or self-hosted Ollama/vLLM
+type OpenAICompatibleProvider struct {
+	opts       OpenAICompatibleOptions
+	httpClient *http.Client
+}
+
+func NewOpenAICompatibleProvider(opts OpenAICompatibleOptions) *OpenAICompatibleProvider {
+	if opts.Timeout == 0 {
+		opts.Timeout = 30 * time.Second
+	}
+	opts.BaseURL = strings.TrimSuffix(opts.BaseURL, "/")
+
+	return &OpenAICompatibleProvider{
+		opts:       opts,
+		httpClient: &http.Client{Timeout: opts.Timeout},
+	}
+}
+
+func (p *OpenAICompatibleProvider) Name() string {
+	return p.opts.Name
+}
+
+func (p *OpenAICompatibleProvider) Complete(ctx context.Context, chatReq *ChatRequest) (*ChatResponse, error) {
+	reqBody := OpenAIRequest{
+		Model:       p.opts.Model,
+		Messages:    chatReq.Messages,
+		Temperature: p.opts.Temperature,
+		MaxTokens:   p.opts.MaxTokens,
+	}
+	if chatReq.Temperature != 0 {
+		reqBody.Temperature = chatReq.Temperature
+	}
+	if chatReq.MaxTokens != 0 {
+		reqBody.MaxTokens = chatReq.MaxTokens
+	}
+	if chatReq.ResponseSchema != nil && p.opts.Structured {
+		reqBody.ResponseFormat = &ResponseFormat{
+			Type: "json_schema",
+			JSONSchema: &ResponseFormatJS{
+				Name:   chatReq.ResponseSchema.Name,
+				Strict: true,
+				Schema: chatReq.ResponseSchema.Schema,
+			},
+		}
+	}
+
+	jsonBody, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", p.opts.BaseURL+"/chat/completions", bytes.NewReader(jsonBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if p.opts.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.opts.APIKey)
+	}
+	for key, value := range p.opts.Headers {
+		if value != "" {
+			req.Header.Set(key, value)
+		}
+	}
+
+	start := time.Now()
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("API request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	var openAIResp OpenAIResponse
+	if err := json.Unmarshal(body, &openAIResp); err != nil {
+		return nil, fmt.Errorf("failed to parse response (status %d): %w", resp.StatusCode, err)
+	}
+
+	if openAIResp.Error != nil {
+		return nil, fmt.Errorf("provider error: %s", openAIResp.Error.Message)
+	}
+
+	if len(openAIResp.Choices) == 0 {
+		return nil, fmt.Errorf("no response from AI")
+	}
+
+	model := openAIResp.Model
+	if model == "" {
+		model = p.opts.Model
+	}
+
+	chatResp := &ChatResponse{
+		Content:  openAIResp.Choices[0].Message.Content,
+		Model:    model,
+		Provider: p.opts.Name,
+		Latency:  time.Since(start),
+	}
+	if openAIResp.Usage != nil {
+		chatResp.PromptTokens = openAIResp.Usage.PromptTokens
+		chatResp.CompletionTokens = openAIResp.Usage.CompletionTokens
+	}
+
+	return chatResp, nil
+}
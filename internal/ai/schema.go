@@ -0,0 +1,196 @@
+package ai
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hakim/backend/internal/models"
+	"github.com/hakim/backend/pkg/supabase"
+)
+
+// maxTags is the most tags a classification may carry
+const maxTags = 5
+
+var (
+	validPriorities = []string{
+		string(models.PriorityLow),
+		string(models.PriorityMedium),
+		string(models.PriorityHigh),
+		string(models.PriorityCritical),
+	}
+	validSentiments = []string{"neutral", "frustrated", "angry", "satisfied"}
+)
+
+// JSONSchema asks the provider for structured output matching Schema
+type JSONSchema struct {
+	Name   string
+	Schema map[string]interface{}
+}
+
+// classificationSchemaName names the classification reply schema
+const classificationSchemaName = "complaint_classification"
+
+// classificationSchema describes the reply expected from the model. The
+// category enum is built from the live category list, plus "" for rejections.
+// Numeric ranges and list sizes are left to validateClassification because
+// not every provider accepts those keywords in strict mode.
+func classificationSchema(categories []supabase.Category) *JSONSchema {
+	names := []string{""}
+	for _, cat := range categories {
+		names = append(names, cat.Name)
+	}
+
+	return &JSONSchema{
+		Name: classificationSchemaName,
+		Schema: map[string]interface{}{
+			"type":                 "object",
+			"additionalProperties": false,
+			"required": []string{
+				"rejected", "rejection_reason", "category_name", "priority",
+				"confidence", "summary_ar", "sentiment", "image_analysis", "tags",
+				"needs_clarification", "questions", "information_request", "images",
+			},
+			"properties": map[string]interface{}{
+				"rejected":         map[string]interface{}{"type": "boolean"},
+				"rejection_reason": map[string]interface{}{"type": "string"},
+				"category_name":    map[string]interface{}{"type": "string", "enum": names},
+				"priority":         map[string]interface{}{"type": "string", "enum": validPriorities},
+				"confidence":       map[string]interface{}{"type": "number"},
+				"summary_ar":       map[string]interface{}{"type": "string"},
+				"sentiment":        map[string]interface{}{"type": "string", "enum": validSentiments},
+				"image_analysis":   map[string]interface{}{"type": "string"},
+				"tags": map[string]interface{}{
+					"type":  "array",
+					"items": map[string]interface{}{"type": "string"},
+				},
+				"needs_clarification": map[string]interface{}{"type": "boolean"},
+				"questions": map[string]interface{}{
+					"type":  "array",
+					"items": map[string]interface{}{"type": "string"},
+				},
+				"information_request": map[string]interface{}{"type": "boolean"},
+				"images": map[string]interface{}{
+					"type": "array",
+					"items": map[string]interface{}{
+						"type":                 "object",
+						"additionalProperties": false,
+						"required":             []string{"labels", "description"},
+						"properties": map[string]interface{}{
+							"labels": map[string]interface{}{
+								"type":  "array",
+								"items": map[string]interface{}{"type": "string"},
+							},
+							"description": map[string]interface{}{"type": "string"},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// parseClassification decodes a model reply strictly. Markdown code fences are
+// tolerated because some providers add them even in JSON mode.
+func parseClassification(content string) (*AIClassification, error) {
+	content = strings.TrimSpace(content)
+	content = strings.TrimPrefix(content, "```json")
+	content = strings.TrimPrefix(content, "```")
+	content = strings.TrimSuffix(content, "```")
+	content = strings.TrimSpace(content)
+
+	decoder := json.NewDecoder(bytes.NewReader([]byte(content)))
+	decoder.DisallowUnknownFields()
+
+	var result AIClassification
+	if err := decoder.Decode(&result); err != nil {
+		return nil, fmt.Errorf("reply is not valid JSON for the schema: %w", err)
+	}
+	if decoder.More() {
+		return nil, errors.New("reply contains data after the JSON object")
+	}
+
+	return &result, nil
+}
+
+// validateClassification checks a parsed reply against the allowed values and
+// returns the matched category. All violations are reported together so the
+// repair prompt can fix them in one pass. Questions are only valid when
+// clarify allows them, and an accepted complaint needs one images entry per
+// image sent.
+func validateClassification(result *AIClassification, categoryMap map[string]supabase.Category, clarify bool, imageCount int) (*supabase.Category, error) {
+	var problems []string
+
+	if result.NeedsClarification && !result.Rejected {
+		switch {
+		case !clarify:
+			problems = append(problems, "needs_clarification must be false: classify the complaint with the details given")
+		case len(result.Questions) == 0 || len(result.Questions) > maxQuestions:
+			problems = append(problems, fmt.Sprintf("questions has %d items, 1 to %d required when needs_clarification is true", len(result.Questions), maxQuestions))
+		}
+		for _, q := range result.Questions {
+			if strings.TrimSpace(q) == "" {
+				problems = append(problems, "questions must not be empty strings")
+				break
+			}
+		}
+		if len(problems) > 0 {
+			return nil, errors.New(strings.Join(problems, "; "))
+		}
+		return nil, nil
+	}
+
+	if result.Rejected {
+		if strings.TrimSpace(result.RejectionReason) == "" {
+			problems = append(problems, "rejection_reason is required when rejected is true")
+		}
+		if len(problems) > 0 {
+			return nil, errors.New(strings.Join(problems, "; "))
+		}
+		return nil, nil
+	}
+
+	var category *supabase.Category
+	if cat, ok := categoryMap[strings.ToLower(strings.TrimSpace(result.CategoryName))]; ok {
+		category = &cat
+	} else {
+		problems = append(problems, fmt.Sprintf("category_name %q is not one of the available categories", result.CategoryName))
+	}
+
+	if !containsString(validPriorities, result.Priority) {
+		problems = append(problems, fmt.Sprintf("priority %q must be one of %s", result.Priority, strings.Join(validPriorities, ", ")))
+	}
+	if result.Confidence < 0 || result.Confidence > 1 {
+		problems = append(problems, fmt.Sprintf("confidence %v must be between 0 and 1", result.Confidence))
+	}
+	if !containsString(validSentiments, result.Sentiment) {
+		problems = append(problems, fmt.Sprintf("sentiment %q must be one of %s", result.Sentiment, strings.Join(validSentiments, ", ")))
+	}
+	if len(result.Tags) > maxTags {
+		problems = append(problems, fmt.Sprintf("tags has %d items, at most %d allowed", len(result.Tags), maxTags))
+	}
+	if len(result.Images) != imageCount {
+		problems = append(problems, fmt.Sprintf("images has %d items, one per attached image (%d) required", len(result.Images), imageCount))
+	}
+	for i, img := range result.Images {
+		if len(img.Labels) > maxTags {
+			problems = append(problems, fmt.Sprintf("images[%d].labels has %d items, at most %d allowed", i, len(img.Labels), maxTags))
+		}
+	}
+
+	if len(problems) > 0 {
+		return nil, errors.New(strings.Join(problems, "; "))
+	}
+	return category, nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
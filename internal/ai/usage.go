@@ -0,0 +1,206 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hakim/backend/pkg/supabase"
+)
+
+// Price is what a model costs in USD per million tokens
+type Price struct {
+	Prompt     float64
+	Completion float64
+}
+
+// Pricing turns token counts into cost. Models without their own price use
+// the default.
+type Pricing struct {
+	Default Price
+	Models  map[string]Price
+}
+
+// ParsePricing reads per-model prices written as "model=prompt:completion"
+func ParsePricing(defaultPrice Price, entries []string) (Pricing, error) {
+	pricing := Pricing{Default: defaultPrice, Models: make(map[string]Price)}
+	for _, entry := range entries {
+		eq := strings.LastIndex(entry, "=")
+		if eq <= 0 {
+			return pricing, fmt.Errorf("invalid model price %q, expected model=prompt:completion", entry)
+		}
+		prompt, completion, ok := strings.Cut(entry[eq+1:], ":")
+		if !ok {
+			return pricing, fmt.Errorf("invalid model price %q, expected model=prompt:completion", entry)
+		}
+		var price Price
+		var err error
+		if price.Prompt, err = strconv.ParseFloat(prompt, 64); err != nil {
+			return pricing, fmt.Errorf("invalid prompt price in %q: %w", entry, err)
+		}
+		if price.Completion, err = strconv.ParseFloat(completion, 64); err != nil {
+			return pricing, fmt.Errorf("invalid completion price in %q: %w", entry, err)
+		}
+		pricing.Models[strings.TrimSpace(entry[:eq])] = price
+	}
+	return pricing, nil
+}
+
+// Cost returns the USD cost of one call
+func (p Pricing) Cost(model string, promptTokens, completionTokens int) float64 {
+	price, ok := p.Models[model]
+	if !ok {
+		price = p.Default
+	}
+	return float64(promptTokens)/1e6*price.Prompt + float64(completionTokens)/1e6*price.Completion
+}
+
+// BudgetMode is how much LLM spend the remaining budget allows
+type BudgetMode string
+
+const (
+	// BudgetNormal classifies with the LLM and high-detail images
+	BudgetNormal BudgetMode = "normal"
+	// BudgetLowDetail still uses the LLM but sends images at low detail
+	BudgetLowDetail BudgetMode = "low_detail"
+	// BudgetExhausted skips the LLM and classifies offline
+	BudgetExhausted BudgetMode = "exhausted"
+)
+
+// SpendSource reports LLM spend since the start of the current month.
+// *supabase.Client implements it.
+type SpendSource interface {
+	GetMonthlyLLMSpend() (float64, error)
+}
+
+// Budget tracks month-to-date LLM spend against a monthly limit. Spend is
+// reloaded from the database so every server instance sees the same total,
+// and calls made in between are added locally.
+type Budget struct {
+	source      SpendSource
+	limit       float64
+	lowDetailAt float64
+
+	mu    sync.Mutex
+	spent float64
+	month time.Month
+}
+
+// NewBudget creates a budget of limit USD per calendar month (UTC). Images
+// drop to low detail once lowDetailAt (a fraction of the limit) is spent.
+func NewBudget(source SpendSource, limit, lowDetailAt float64) *Budget {
+	return &Budget{
+		source:      source,
+		limit:       limit,
+		lowDetailAt: lowDetailAt,
+		month:       time.Now().UTC().Month(),
+	}
+}
+
+// Reload fetches the month-to-date spend
+func (b *Budget) Reload() error {
+	spent, err := b.source.GetMonthlyLLMSpend()
+	if err != nil {
+		return err
+	}
+
+	before := b.Mode()
+	b.mu.Lock()
+	b.spent = spent
+	b.month = time.Now().UTC().Month()
+	b.mu.Unlock()
+
+	if after := b.Mode(); after != before {
+		slog.Warn("LLM budget mode changed", "mode", after, "spent_usd", spent, "limit_usd", b.limit)
+	}
+	return nil
+}
+
+// Start reloads the spend on the interval until the context is cancelled
+func (b *Budget) Start(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	if err := b.Reload(); err != nil {
+		slog.Warn("Failed to load LLM spend", "error", err)
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := b.Reload(); err != nil {
+					slog.Warn("Failed to reload LLM spend", "error", err)
+				}
+			}
+		}
+	}()
+}
+
+// Add counts a call made since the last reload
+func (b *Budget) Add(cost float64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.rollover()
+	b.spent += cost
+}
+
+// Spent returns the month-to-date spend
+func (b *Budget) Spent() float64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.rollover()
+	return b.spent
+}
+
+// Limit returns the monthly limit in USD
+func (b *Budget) Limit() float64 {
+	return b.limit
+}
+
+// Mode reports how the classifier should degrade at the current spend
+func (b *Budget) Mode() BudgetMode {
+	spent := b.Spent()
+	switch {
+	case spent >= b.limit:
+		return BudgetExhausted
+	case b.lowDetailAt > 0 && spent >= b.limit*b.lowDetailAt:
+		return BudgetLowDetail
+	}
+	return BudgetNormal
+}
+
+// rollover resets the spend when a new month starts between reloads.
+// Callers hold the lock.
+func (b *Budget) rollover() {
+	if month := time.Now().UTC().Month(); month != b.month {
+		b.month = month
+		b.spent = 0
+	}
+}
+
+// meterCall costs one LLM call and adds it to the budget, if there is one
+func meterCall(pricing Pricing, budget *Budget, purpose string, resp *ChatResponse, imageDetail string) supabase.LLMUsage {
+	cost := pricing.Cost(resp.Model, resp.PromptTokens, resp.CompletionTokens)
+	if budget != nil {
+		budget.Add(cost)
+	}
+	return supabase.LLMUsage{
+		Purpose:          purpose,
+		Provider:         resp.Provider,
+		Model:            resp.Model,
+		PromptTokens:     resp.PromptTokens,
+		CompletionTokens: resp.CompletionTokens,
+		CostUSD:          cost,
+		LatencyMs:        resp.Latency.Milliseconds(),
+		ImageDetail:      imageDetail,
+	}
+}
@@ -0,0 +1,172 @@
+// Package arabic normalizes and lightly stems Arabic text so that spelling
+// variants of the same word compare equal. It is used wherever complaint text
+// is matched against keywords or other complaints.
+package arabic
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// minStemLength is the fewest runes a word may have after affix removal.
+// Shorter results are too ambiguous, so the affix is kept.
+const minStemLength = 3
+
+// letterMap unifies letter variants. Keep in sync with normalize_arabic() in
+// supabase/migrations/015_arabic_search.sql, which indexes complaint text.
+var letterMap = map[rune]rune{
+	'أ': 'ا', 'إ': 'ا', 'آ': 'ا', 'ٱ': 'ا', // hamza and madda forms of alef
+	'ة': 'ه', // taa marbuta
+	'ى': 'ي', // alef maqsura
+	'ؤ': 'و', // hamza on waw
+	'ئ': 'ي', // hamza on yaa
+}
+
+// Prefixes and suffixes removed by Stem, longest first
+var (
+	prefixes = []string{"وال", "بال", "كال", "فال", "لل", "ال", "و"}
+	suffixes = []string{"ها", "ان", "ات", "ون", "ين", "يه", "ه", "ي"}
+)
+
+// Normalize lowercases text, removes diacritics and tatweel, unifies alef,
+// taa marbuta, alef maqsura and hamza forms, and converts Arabic-Indic digits
+// to ASCII. Runs of whitespace collapse to one space.
+func Normalize(text string) string {
+	var b strings.Builder
+	b.Grow(len(text))
+
+	space := false
+	for _, r := range text {
+		switch {
+		case isDiacritic(r) || r == '\u0640': // tatweel
+			continue
+		case unicode.IsSpace(r):
+			space = true
+			continue
+		}
+
+		if space && b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		space = false
+
+		if mapped, ok := letterMap[r]; ok {
+			r = mapped
+		} else if r >= '\u0660' && r <= '\u0669' { // Arabic-Indic digits
+			r = '0' + (r - '\u0660')
+		} else if r >= '\u06F0' && r <= '\u06F9' { // Extended (Persian) digits
+			r = '0' + (r - '\u06F0')
+		} else {
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+// Stem removes one common prefix and one common suffix from a normalized
+// Arabic word ("الكهرباء" -> "كهرباء", "المياه" -> "ميا"). Non-Arabic words
+// are returned unchanged.
+func Stem(word string) string {
+	if !isArabicWord(word) {
+		return word
+	}
+
+	for _, prefix := range prefixes {
+		if !strings.HasPrefix(word, prefix) {
+			continue
+		}
+		// A word too short to lose its prefix ("الحي", "والد") is too short
+		// to lose a suffix as well
+		if utf8.RuneCountInString(word)-utf8.RuneCountInString(prefix) < minStemLength {
+			return word
+		}
+		word = strings.TrimPrefix(word, prefix)
+		break
+	}
+
+	for _, suffix := range suffixes {
+		if strings.HasSuffix(word, suffix) && utf8.RuneCountInString(word)-utf8.RuneCountInString(suffix) >= minStemLength {
+			word = strings.TrimSuffix(word, suffix)
+			break
+		}
+	}
+
+	return word
+}
+
+// Words normalizes text and splits it into words on anything that is not a
+// letter or digit
+func Words(text string) []string {
+	return strings.FieldsFunc(Normalize(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// Tokens returns the stems of every word in text, in order
+func Tokens(text string) []string {
+	words := Words(text)
+	for i, word := range words {
+		words[i] = Stem(word)
+	}
+	return words
+}
+
+// RuneLen counts characters rather than bytes
+func RuneLen(s string) int {
+	return utf8.RuneCountInString(s)
+}
+
+// Text is complaint text prepared for repeated keyword lookups
+type Text struct {
+	normalized string
+	stems      string // " stem stem stem " for whole-token phrase matching
+}
+
+func NewText(text string) *Text {
+	return &Text{
+		normalized: Normalize(text),
+		stems:      " " + strings.Join(Tokens(text), " ") + " ",
+	}
+}
+
+// Normalized returns the normalized text for substring checks
+func (t *Text) Normalized() string {
+	return t.normalized
+}
+
+// Contains reports whether term appears as whole words, after normalization
+// and stemming of both sides. Multi-word terms must appear in order.
+func (t *Text) Contains(term string) bool {
+	stems := Tokens(term)
+	if len(stems) == 0 {
+		return false
+	}
+	return strings.Contains(t.stems, " "+strings.Join(stems, " ")+" ")
+}
+
+// ContainsAny reports whether any term appears in the text
+func (t *Text) ContainsAny(terms []string) bool {
+	for _, term := range terms {
+		if t.Contains(term) {
+			return true
+		}
+	}
+	return false
+}
+
+func isDiacritic(r rune) bool {
+	// Harakat, tanween, shadda and sukun, plus superscript alef
+	return (r >= '\u064B' && r <= '\u0652') || r == '\u0670'
+}
+
+func isArabicWord(word string) bool {
+	for _, r := range word {
+		if unicode.Is(unicode.Arabic, r) {
+			return true
+		}
+	}
+	return false
+}
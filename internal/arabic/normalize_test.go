@@ -0,0 +1,118 @@
+package arabic
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"alef with hamza above", "أحمد", "احمد"},
+		{"alef with hamza below", "إسلام", "اسلام"},
+		{"alef with madda", "آمن", "امن"},
+		{"alef wasla", "ٱلماء", "الماء"},
+		{"taa marbuta", "مدرسة", "مدرسه"},
+		{"alef maqsura", "مستشفى", "مستشفي"},
+		{"hamza on waw", "مؤسسة", "موسسه"},
+		{"hamza on yaa", "شاطئ", "شاطي"},
+		{"diacritics", "مَدْرَسَةٌ", "مدرسه"},
+		{"shadda and tanween", "مُهِمٌّ جِدًّا", "مهم جدا"},
+		{"superscript alef", "هٰذا", "هذا"},
+		{"tatweel", "شـــارع", "شارع"},
+		{"arabic-indic digits", "٠١٢٣٤٥٦٧٨٩", "0123456789"},
+		{"extended digits", "۱۲۳", "123"},
+		{"mixed digits and text", "منذ ٣ أيام", "منذ 3 ايام"},
+		{"whitespace collapses", "  المياه \t\n مقطوعة  ", "المياه مقطوعه"},
+		{"latin lowercased", "Water LEAK", "water leak"},
+		{"empty", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Normalize(tt.in); got != tt.want {
+				t.Errorf("Normalize(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStem(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"definite article", "الكهرباء", "كهرباء"},
+		{"article and suffix", "المياه", "ميا"},
+		{"waw and article", "والشارع", "شارع"},
+		{"baa and article", "بالمدرسه", "مدرس"},
+		{"lam lam", "للبلديه", "بلد"},
+		{"waw", "وشكرا", "شكرا"},
+		{"plural -ين", "موظفين", "موظف"},
+		{"plural -ون", "مهندسون", "مهندس"},
+		{"plural -ات", "سيارات", "سيار"},
+		{"dual -ان", "عمودان", "عمود"},
+		{"possessive -ها", "اسلاكها", "اسلاك"},
+		{"only one prefix", "والمياه", "ميا"},
+
+		// Words that must not be over-stemmed
+		{"waw of the root", "ولد", "ولد"},
+		{"short word with waw", "وطن", "وطن"},
+		{"article on a short word", "الحي", "الحي"},
+		{"article-like root", "والد", "والد"},
+		{"suffix of the root", "دين", "دين"},
+		{"no affixes", "شارع", "شارع"},
+		{"three letters", "بيت", "بيت"},
+
+		{"latin", "water", "water"},
+		{"digits", "2024", "2024"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Stem(tt.in); got != tt.want {
+				t.Errorf("Stem(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTokens(t *testing.T) {
+	got := Tokens("المياهُ مقطوعة عن الحيّ، منذ ٣ أيام!")
+	want := []string{"ميا", "مقطوع", "عن", "الحي", "منذ", "3", "ايام"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Tokens() = %q, want %q", got, want)
+	}
+}
+
+func TestTextContains(t *testing.T) {
+	text := NewText("انقطاع المياه في الحيّ منذ أسبوع")
+
+	tests := []struct {
+		term string
+		want bool
+	}{
+		{"مياه", true},
+		{"الميَاه", true},
+		{"انقطاع المياه", true},
+		{"المياه انقطاع", false},
+		{"قطاع", false},
+		{"الكهرباء", false},
+		{"اسبوع", true},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		if got := text.Contains(tt.term); got != tt.want {
+			t.Errorf("Contains(%q) = %v, want %v", tt.term, got, tt.want)
+		}
+	}
+
+	if !text.ContainsAny([]string{"الكهرباء", "المياه"}) {
+		t.Error("ContainsAny() = false, want true")
+	}
+}
@@ -0,0 +1,277 @@
+package bulk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hakim/backend/internal/config"
+	"github.com/hakim/backend/internal/models"
+	"github.com/hakim/backend/pkg/supabase"
+)
+
+const (
+	// progressInterval is how many items are processed between job progress saves
+	progressInterval = 25
+	// saveInterval bounds the time between progress saves, which refresh the
+	// job's lock while slow items run
+	saveInterval = 30 * time.Second
+	// staleAfter is how long a running job's lock may go unrefreshed before
+	// the job is considered abandoned. It is well above saveInterval plus
+	// the time one item can take.
+	staleAfter   = 10 * time.Minute
+	pollInterval = 5 * time.Second
+)
+
+// Runner applies bulk operations. Small ones run inline; large ones are
+// queued in the database and run by a background worker, so they survive
+// restarts and do not depend on the caller's session.
+type Runner struct {
+	client   *supabase.Client
+	workerID string
+	wg       sync.WaitGroup
+}
+
+func NewRunner(client *supabase.Client) *Runner {
+	host, _ := os.Hostname()
+	return &Runner{
+		client:   client,
+		workerID: fmt.Sprintf("%s-%d/bulk", host, os.Getpid()),
+	}
+}
+
+// Validate checks that the request carries the fields its action needs
+func Validate(req *models.BulkOperationRequest) error {
+	if len(req.IDs) == 0 && (req.Filter == nil || req.Filter.IsEmpty()) {
+		return fmt.Errorf("either ids or a non-empty filter is required")
+	}
+
+	switch req.Action {
+	case models.BulkActionStatus:
+		switch models.ComplaintStatus(req.Status) {
+		case models.StatusSubmitted, models.StatusInReview, models.StatusAssigned, models.StatusInProgress,
+			models.StatusResolved, models.StatusClosed, models.StatusRejected:
+		default:
+			return fmt.Errorf("invalid status: %q", req.Status)
+		}
+	case models.BulkActionAssign:
+		if req.AssigneeID == "" {
+			return fmt.Errorf("assignee_id is required")
+		}
+	case models.BulkActionPriority:
+		switch models.ComplaintPriority(req.Priority) {
+		case models.PriorityLow, models.PriorityMedium, models.PriorityHigh, models.PriorityCritical:
+		default:
+			return fmt.Errorf("invalid priority: %q", req.Priority)
+		}
+	case models.BulkActionTag:
+		if strings.TrimSpace(req.Tag) == "" {
+			return fmt.Errorf("tag is required")
+		}
+	case models.BulkActionTransfer:
+		if req.Transfer == nil || req.Transfer.ToDepartmentID == uuid.Nil || strings.TrimSpace(req.Transfer.Reason) == "" {
+			return fmt.Errorf("transfer with to_department_id and reason is required")
+		}
+	default:
+		return fmt.Errorf("unknown action: %q", req.Action)
+	}
+
+	return nil
+}
+
+// ResolveIDs returns the complaint IDs targeted by the request
+func (r *Runner) ResolveIDs(token string, req *models.BulkOperationRequest) ([]string, error) {
+	if len(req.IDs) > 0 {
+		ids := make([]string, 0, len(req.IDs))
+		for _, id := range req.IDs {
+			ids = append(ids, id.String())
+		}
+		return ids, nil
+	}
+
+	return r.client.FindComplaintIDs(token, req.Filter, config.AppConfig.BulkMaxItems)
+}
+
+// Execute applies the operation to every complaint and reports per-item
+// results. Each underlying operation writes its own history entry.
+func (r *Runner) Execute(token string, user *supabase.UserProfile, req *models.BulkOperationRequest, ids []string) *models.BulkResult {
+	result := &models.BulkResult{
+		Total:   len(ids),
+		Results: make([]models.BulkItemResult, 0, len(ids)),
+	}
+
+	for _, id := range ids {
+		item := r.apply(token, user.ID.String(), req, id)
+		if item.Success {
+			result.Succeeded++
+		} else {
+			result.Failed++
+		}
+		result.Results = append(result.Results, item)
+	}
+
+	return result
+}
+
+// Enqueue queues the operation as a durable job. Complaints the caller
+// cannot see are recorded as failed straight away, since workers act with
+// the service key.
+func (r *Runner) Enqueue(token string, user *supabase.UserProfile, req *models.BulkOperationRequest, ids []string) (*models.BulkJob, error) {
+	visible, err := r.client.VisibleComplaintIDs(token, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []models.BulkItemResult
+	if len(visible) < len(ids) {
+		allowed := make(map[string]bool, len(visible))
+		for _, id := range visible {
+			allowed[id] = true
+		}
+		for _, id := range ids {
+			if !allowed[id] {
+				results = append(results, models.BulkItemResult{ComplaintID: id, Error: "complaint not found"})
+			}
+		}
+	}
+
+	return r.client.CreateBulkJob(token, req, visible, results, user.ID.String())
+}
+
+// Start launches the bulk job worker. It stops when ctx is cancelled,
+// handing an unfinished job back to the queue; Wait blocks until it has.
+func (r *Runner) Start(ctx context.Context) {
+	r.wg.Add(1)
+	go r.work(ctx)
+
+	slog.Info("Bulk job worker started", "poll_interval", pollInterval)
+}
+
+// Wait blocks until the worker has exited
+func (r *Runner) Wait() {
+	r.wg.Wait()
+}
+
+func (r *Runner) work(ctx context.Context) {
+	defer r.wg.Done()
+
+	for {
+		if err := r.client.FailStaleBulkJobs(staleAfter); err != nil {
+			slog.Warn("Failed to sweep abandoned bulk jobs", "error", err)
+		}
+
+		queued, err := r.client.ClaimBulkJob(r.workerID)
+		if err != nil {
+			slog.Warn("Failed to claim bulk job", "worker", r.workerID, "error", err)
+		}
+
+		if queued != nil {
+			r.run(ctx, queued)
+			if ctx.Err() != nil {
+				return
+			}
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// run applies a claimed job item by item, resuming after any items a
+// previous worker processed. Progress is saved regularly, which also keeps
+// the job's lock fresh.
+func (r *Runner) run(ctx context.Context, queued *supabase.QueuedBulkJob) {
+	job := queued.Job
+	req := &queued.Request
+	changedBy := job.CreatedBy.String()
+	if job.Results == nil {
+		job.Results = make([]models.BulkItemResult, 0, job.Total)
+	}
+
+	// Items settled when the job was queued are counted in Processed but
+	// are not in ItemIDs
+	done := job.Processed - (job.Total - len(queued.ItemIDs))
+	if done < 0 {
+		done = 0
+	}
+	lastSave := time.Now()
+
+	for i := done; i < len(queued.ItemIDs); i++ {
+		if ctx.Err() != nil {
+			// Shutting down: hand the rest back to the queue
+			job.Status = models.BulkJobQueued
+			if err := r.client.SaveBulkJob(r.workerID, job); err != nil {
+				slog.Warn("Failed to release bulk job", "job_id", job.ID, "error", err)
+			}
+			return
+		}
+
+		item := r.apply("", changedBy, req, queued.ItemIDs[i])
+		job.Processed++
+		if item.Success {
+			job.Succeeded++
+		} else {
+			job.Failed++
+		}
+		job.Results = append(job.Results, item)
+
+		if (i+1)%progressInterval == 0 || time.Since(lastSave) > saveInterval {
+			lastSave = time.Now()
+			if err := r.client.SaveBulkJob(r.workerID, job); err != nil {
+				slog.Warn("Failed to save bulk job progress", "job_id", job.ID, "error", err)
+				if errors.Is(err, supabase.ErrBulkJobLost) {
+					return
+				}
+			}
+		}
+	}
+
+	now := time.Now()
+	job.Status = models.BulkJobCompleted
+	job.CompletedAt = &now
+	if err := r.client.SaveBulkJob(r.workerID, job); err != nil {
+		slog.Error("Failed to save bulk job result", "job_id", job.ID, "error", err)
+	}
+
+	slog.Info("Bulk job finished", "job_id", job.ID, "action", job.Action,
+		"succeeded", job.Succeeded, "failed", job.Failed)
+}
+
+// apply runs the operation on one complaint. changedBy is recorded in the
+// history entries the operation writes.
+func (r *Runner) apply(token, changedBy string, req *models.BulkOperationRequest, id string) models.BulkItemResult {
+	var err error
+	switch req.Action {
+	case models.BulkActionStatus:
+		_, err = r.client.UpdateComplaintStatus(token, id, req.Status, req.Note, changedBy)
+	case models.BulkActionAssign:
+		_, err = r.client.AssignComplaint(token, id, req.AssigneeID, changedBy)
+	case models.BulkActionPriority:
+		_, err = r.client.UpdateComplaintPriority(token, id, req.Priority, req.Note, changedBy)
+	case models.BulkActionTag:
+		err = r.client.AddComplaintTag(token, id, strings.TrimSpace(req.Tag), changedBy)
+	case models.BulkActionTransfer:
+		requireAcceptance := config.AppConfig.TransferRequireAcceptance
+		if req.Transfer.RequireAcceptance != nil {
+			requireAcceptance = *req.Transfer.RequireAcceptance
+		}
+		_, err = r.client.TransferComplaint(token, id, req.Transfer, changedBy, requireAcceptance)
+	default:
+		err = fmt.Errorf("unknown action: %q", req.Action)
+	}
+
+	if err != nil {
+		return models.BulkItemResult{ComplaintID: id, Error: err.Error()}
+	}
+	return models.BulkItemResult{ComplaintID: id, Success: true}
+}
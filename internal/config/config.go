@@ -1,7 +1,11 @@
 package config
 
 import (
+	"fmt"
+	"net/url"
 	"os"
+	"strconv"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
@@ -13,6 +17,132 @@ type Config struct {
 	SupabaseKey       string
 	SupabaseJWTSecret string
 	OpenAIKey         string
+
+	// LLM providers, tried in order (openrouter, openai, fake)
+	LLMProviders      []string
+	LLMModel          string
+	LLMTemperature    float64
+	LLMMaxTokens      int
+	LLMTimeoutSeconds int
+
+	OpenRouterBaseURL string
+	OpenRouterModel   string
+	LLMHTTPReferer    string
+	LLMAppTitle       string
+
+	// Settings of each OpenAI-compatible entry in LLMProviders (openai,
+	// openai-compatible, ollama, vllm), keyed by the lower-case name
+	LLMEndpoints map[string]LLMEndpoint
+
+	// Shared OpenAI-compatible endpoint, used by entries that have no
+	// LLM_<NAME>_BASE_URL of their own
+	OpenAICompatBaseURL string
+	OpenAICompatAPIKey  string
+	OpenAICompatModel   string
+
+	// Request json_schema structured output from providers that support it
+	OpenRouterStructuredOutput   bool
+	OpenAICompatStructuredOutput bool
+
+	// LLM prices in USD per million tokens; LLMModelPrices entries are
+	// "model=prompt:completion" and override the defaults
+	LLMPromptPrice     float64
+	LLMCompletionPrice float64
+	LLMModelPrices     []string
+
+	// Monthly LLM budget in USD (0 = unlimited). Images drop to low detail
+	// once LLMBudgetLowDetailAt of it is spent, and classification goes
+	// offline when it is exhausted.
+	LLMMonthlyBudget     float64
+	LLMBudgetLowDetailAt float64
+
+	// Duplicate detection
+	DuplicateRadiusMeters  float64
+	DuplicateWindowHours   int
+	DuplicateMinSimilarity float64
+
+	// Transfers between departments wait for the receiving side to accept
+	TransferRequireAcceptance bool
+
+	// Bulk operations above the threshold run as background jobs
+	BulkAsyncThreshold int
+	BulkMaxItems       int
+
+	// Background classification workers
+	ClassificationWorkers     int
+	ClassificationPollSeconds int
+	ClassificationMaxAttempts int
+
+	// Vague complaints get clarifying questions for up to this many rounds
+	// before they are classified with the details given
+	ClarificationMaxRounds int
+
+	// Image attachments are downloaded by the server, limited in size and
+	// scaled down before they are sent to the LLM
+	ImageMaxBytes            int64
+	ImageMaxDimension        int
+	ImageFetchTimeoutSeconds int
+
+	// Voice notes are transcribed by a Whisper-compatible server ("whisper")
+	// or a canned fake ("fake"); empty disables transcription. Descriptions
+	// shorter than VoiceTranscriptMinDescription runes are classified
+	// together with the transcripts.
+	Transcriber                   string
+	WhisperBaseURL                string
+	WhisperAPIKey                 string
+	WhisperModel                  string
+	WhisperLanguage               string
+	WhisperPrompt                 string
+	VoiceMaxBytes                 int64
+	VoiceTimeoutSeconds           int
+	VoiceTranscriptMinDescription int
+
+	// Attachment URLs must match one of these "host/path/prefix" patterns
+	// ("*.host" for subdomains). Defaults to this project's Supabase storage.
+	AttachmentURLPatterns []string
+
+	// Information requests are answered from the knowledge base with up to
+	// KnowledgeMaxAnswers entries scoring at least KnowledgeMinScore
+	KnowledgeMinScore   float64
+	KnowledgeMaxAnswers int
+
+	// Classifications below this confidence go to the human review queue
+	ReviewConfidenceThreshold float64
+
+	// An AI category that contradicts the citizen's choice with at least this
+	// confidence sends the complaint to triage; below it the citizen's stands
+	CitizenCategoryDisputeConfidence float64
+
+	// Classification rules are reloaded from the database on this interval
+	RulesReloadSeconds int
+
+	// Screening rules whose rejections are overturned on appeal at this rate,
+	// over at least this many decided appeals, stop rejecting complaints
+	FilterOverrideMinAppeals int
+	FilterOverrideRate       float64
+
+	// Local statistical classifier, trained with cmd/classifier-train
+	LocalModelPath          string
+	LocalModelMinConfidence float64
+
+	// Personal data is redacted from complaint text before LLM calls.
+	// An empty kind list redacts every kind the redact package knows.
+	PIIRedaction      bool
+	PIIRedactionKinds []string
+
+	// Categories given to the classifier are refetched on this interval
+	CategoryCacheSeconds int
+	// LLM verdicts are reused for identical content (0 minutes disables)
+	ClassificationCacheMinutes int
+	ClassificationCacheSize    int
+}
+
+// LLMEndpoint is the connection to one OpenAI-compatible LLM provider
+type LLMEndpoint struct {
+	BaseURL          string
+	APIKey           string
+	Model            string
+	StructuredOutput bool
 }
 
 var AppConfig *Config
@@ -27,15 +157,176 @@ func Load() error {
 		SupabaseURL:       getEnv("SUPABASE_URL", ""),
 		SupabaseKey:       getEnv("SUPABASE_KEY", ""),
 		SupabaseJWTSecret: getEnv("SUPABASE_JWT_SECRET", ""),
-		OpenAIKey:         getEnv("OPENAI_API_KEY", ""),
+		OpenAIKey:         getEnv("OPENROUTER_API_KEY", getEnv("OPENAI_API_KEY", "")),
+
+		LLMProviders:      getEnvList("LLM_PROVIDERS", []string{"openrouter"}),
+		LLMModel:          getEnv("LLM_MODEL", "openai/gpt-5.1-codex-max"),
+		LLMTemperature:    getEnvFloat("LLM_TEMPERATURE", 0.3),
+		LLMMaxTokens:      getEnvInt("LLM_MAX_TOKENS", 2000),
+		LLMTimeoutSeconds: getEnvInt("LLM_TIMEOUT_SECONDS", 30),
+
+		OpenRouterBaseURL: getEnv("OPENROUTER_BASE_URL", "https://openrouter.ai/api/v1"),
+		OpenRouterModel:   getEnv("OPENROUTER_MODEL", ""),
+		LLMHTTPReferer:    getEnv("LLM_HTTP_REFERER", "https://hakim.sa"),
+		LLMAppTitle:       getEnv("LLM_APP_TITLE", "HAKIM Complaint System"),
+
+		OpenAICompatBaseURL: getEnv("OPENAI_COMPAT_BASE_URL", ""),
+		OpenAICompatAPIKey:  getEnv("OPENAI_COMPAT_API_KEY", ""),
+		OpenAICompatModel:   getEnv("OPENAI_COMPAT_MODEL", ""),
+
+		OpenRouterStructuredOutput:   getEnvBool("OPENROUTER_STRUCTURED_OUTPUT", true),
+		OpenAICompatStructuredOutput: getEnvBool("OPENAI_COMPAT_STRUCTURED_OUTPUT", false),
+
+		LLMPromptPrice:     getEnvFloat("LLM_PROMPT_PRICE", 0),
+		LLMCompletionPrice: getEnvFloat("LLM_COMPLETION_PRICE", 0),
+		LLMModelPrices:     getEnvList("LLM_MODEL_PRICES", nil),
+
+		LLMMonthlyBudget:     getEnvFloat("LLM_MONTHLY_BUDGET_USD", 0),
+		LLMBudgetLowDetailAt: getEnvFloat("LLM_BUDGET_LOW_DETAIL_AT", 0.8),
+
+		DuplicateRadiusMeters:  getEnvFloat("DUPLICATE_RADIUS_METERS", 150),
+		DuplicateWindowHours:   getEnvInt("DUPLICATE_WINDOW_HOURS", 72),
+		DuplicateMinSimilarity: getEnvFloat("DUPLICATE_MIN_SIMILARITY", 0.35),
+
+		TransferRequireAcceptance: getEnvBool("TRANSFER_REQUIRE_ACCEPTANCE", false),
+
+		BulkAsyncThreshold: getEnvInt("BULK_ASYNC_THRESHOLD", 50),
+		BulkMaxItems:       getEnvInt("BULK_MAX_ITEMS", 2000),
+
+		ClassificationWorkers:     getEnvInt("CLASSIFICATION_WORKERS", 4),
+		ClassificationPollSeconds: getEnvInt("CLASSIFICATION_POLL_SECONDS", 2),
+		ClassificationMaxAttempts: getEnvInt("CLASSIFICATION_MAX_ATTEMPTS", 3),
+
+		ClarificationMaxRounds: getEnvInt("CLARIFICATION_MAX_ROUNDS", 2),
+
+		ImageMaxBytes:            int64(getEnvInt("IMAGE_MAX_BYTES", 10*1024*1024)),
+		ImageMaxDimension:        getEnvInt("IMAGE_MAX_DIMENSION", 1024),
+		ImageFetchTimeoutSeconds: getEnvInt("IMAGE_FETCH_TIMEOUT_SECONDS", 10),
+
+		Transcriber:                   getEnv("TRANSCRIBER", ""),
+		WhisperBaseURL:                getEnv("WHISPER_BASE_URL", ""),
+		WhisperAPIKey:                 getEnv("WHISPER_API_KEY", ""),
+		WhisperModel:                  getEnv("WHISPER_MODEL", "large-v3"),
+		WhisperLanguage:               getEnv("WHISPER_LANGUAGE", "ar"),
+		WhisperPrompt:                 getEnv("WHISPER_PROMPT", "شكوى من مواطن أردني باللهجة الأردنية عن خدمة حكومية."),
+		VoiceMaxBytes:                 int64(getEnvInt("VOICE_MAX_BYTES", 25*1024*1024)),
+		VoiceTimeoutSeconds:           getEnvInt("VOICE_TIMEOUT_SECONDS", 120),
+		VoiceTranscriptMinDescription: getEnvInt("VOICE_TRANSCRIPT_MIN_DESCRIPTION", 100),
+
+		AttachmentURLPatterns: getEnvList("ATTACHMENT_URL_PATTERNS", nil),
+
+		KnowledgeMinScore:   getEnvFloat("KNOWLEDGE_MIN_SCORE", 0.5),
+		KnowledgeMaxAnswers: getEnvInt("KNOWLEDGE_MAX_ANSWERS", 3),
+
+		ReviewConfidenceThreshold: getEnvFloat("REVIEW_CONFIDENCE_THRESHOLD", 0.6),
+
+		CitizenCategoryDisputeConfidence: getEnvFloat("CITIZEN_CATEGORY_DISPUTE_CONFIDENCE", 0.85),
+
+		RulesReloadSeconds: getEnvInt("RULES_RELOAD_SECONDS", 60),
+
+		FilterOverrideMinAppeals: getEnvInt("FILTER_OVERRIDE_MIN_APPEALS", 5),
+		FilterOverrideRate:       getEnvFloat("FILTER_OVERRIDE_RATE", 0.5),
+
+		LocalModelPath:          getEnv("LOCAL_MODEL_PATH", "models/classifier.json"),
+		LocalModelMinConfidence: getEnvFloat("LOCAL_MODEL_MIN_CONFIDENCE", 0.6),
+
+		PIIRedaction:      getEnvBool("PII_REDACTION", true),
+		PIIRedactionKinds: getEnvList("PII_REDACTION_KINDS", nil),
+
+		CategoryCacheSeconds:       getEnvInt("CATEGORY_CACHE_SECONDS", 60),
+		ClassificationCacheMinutes: getEnvInt("CLASSIFICATION_CACHE_MINUTES", 60),
+		ClassificationCacheSize:    getEnvInt("CLASSIFICATION_CACHE_SIZE", 1000),
+	}
+
+	// Duplicate scoring divides by the radius
+	if AppConfig.DuplicateRadiusMeters <= 0 {
+		return fmt.Errorf("DUPLICATE_RADIUS_METERS must be positive, got %v", AppConfig.DuplicateRadiusMeters)
+	}
+
+	AppConfig.LLMEndpoints = make(map[string]LLMEndpoint)
+	for _, name := range AppConfig.LLMProviders {
+		name = strings.ToLower(name)
+		if name != "openrouter" && name != "fake" {
+			AppConfig.LLMEndpoints[name] = loadLLMEndpoint(name)
+		}
+	}
+
+	if len(AppConfig.AttachmentURLPatterns) == 0 {
+		if u, err := url.Parse(AppConfig.SupabaseURL); err == nil && u.Host != "" {
+			AppConfig.AttachmentURLPatterns = []string{u.Host + "/storage/v1/object/"}
+		}
 	}
 
 	return nil
 }
 
+// loadLLMEndpoint reads LLM_<NAME>_BASE_URL, _API_KEY, _MODEL and
+// _STRUCTURED_OUTPUT for a provider. Without a base URL of its own the
+// provider uses the shared OPENAI_COMPAT_* endpoint, so a key is never sent
+// to another provider's server.
+func loadLLMEndpoint(name string) LLMEndpoint {
+	prefix := "LLM_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+	if baseURL := getEnv(prefix+"BASE_URL", ""); baseURL != "" {
+		return LLMEndpoint{
+			BaseURL:          baseURL,
+			APIKey:           getEnv(prefix+"API_KEY", ""),
+			Model:            getEnv(prefix+"MODEL", ""),
+			StructuredOutput: getEnvBool(prefix+"STRUCTURED_OUTPUT", false),
+		}
+	}
+	return LLMEndpoint{
+		BaseURL:          AppConfig.OpenAICompatBaseURL,
+		APIKey:           AppConfig.OpenAICompatAPIKey,
+		Model:            AppConfig.OpenAICompatModel,
+		StructuredOutput: AppConfig.OpenAICompatStructuredOutput,
+	}
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
 	}
 	return defaultValue
 }
+
+func getEnvInt(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
+
+func getEnvFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
+
+func getEnvBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := strconv.ParseBool(value); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
+
+func getEnvList(key string, defaultValue []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
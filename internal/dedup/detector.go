@@ -0,0 +1,172 @@
+package dedup
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hakim/backend/internal/arabic"
+	"github.com/hakim/backend/internal/config"
+	"github.com/hakim/backend/internal/models"
+	"github.com/hakim/backend/pkg/supabase"
+)
+
+// earthRadiusMeters is the mean Earth radius used for haversine distances
+const earthRadiusMeters = 6371000.0
+
+// maxCandidates caps how many suggestions are returned to the caller
+const maxCandidates = 5
+
+type Detector struct {
+	client *supabase.Client
+}
+
+// Submission is the subset of a complaint used for duplicate matching
+type Submission struct {
+	ExcludeID   uuid.UUID
+	CategoryID  uuid.UUID
+	Title       string
+	Description string
+	Latitude    *float64
+	Longitude   *float64
+}
+
+func NewDetector(client *supabase.Client) *Detector {
+	return &Detector{client: client}
+}
+
+// FindCandidates returns existing complaints that likely describe the same
+// incident, best match first. Matching uses category, a time window,
+// distance from the reported coordinates and text similarity.
+func (d *Detector) FindCandidates(s Submission) ([]models.DuplicateCandidate, error) {
+	cfg := config.AppConfig
+
+	query := supabase.NearbyComplaintsQuery{
+		Latitude:     s.Latitude,
+		Longitude:    s.Longitude,
+		RadiusMeters: cfg.DuplicateRadiusMeters,
+		Since:        time.Now().Add(-time.Duration(cfg.DuplicateWindowHours) * time.Hour),
+	}
+	if s.CategoryID != uuid.Nil {
+		query.CategoryID = s.CategoryID.String()
+	}
+	if s.ExcludeID != uuid.Nil {
+		query.ExcludeID = s.ExcludeID.String()
+	}
+
+	// Without a location or a category there is nothing to narrow the search
+	hasLocation := s.Latitude != nil && s.Longitude != nil
+	if !hasLocation && query.CategoryID == "" {
+		return nil, nil
+	}
+
+	pool, err := d.client.FindNearbyComplaints(query)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens := tokenize(s.Title + " " + s.Description)
+	candidates := make([]models.DuplicateCandidate, 0)
+
+	for _, existing := range pool {
+		similarity := jaccard(tokens, tokenize(existing.Title+" "+existing.Description))
+
+		candidate := models.DuplicateCandidate{
+			ComplaintID:    existing.ID,
+			TrackingNumber: existing.TrackingNumber,
+			Title:          existing.Title,
+			Status:         existing.Status,
+			Similarity:     similarity,
+			CreatedAt:      existing.CreatedAt,
+		}
+
+		if hasLocation && existing.Latitude != nil && existing.Longitude != nil {
+			distance := haversine(*s.Latitude, *s.Longitude, *existing.Latitude, *existing.Longitude)
+			if distance > cfg.DuplicateRadiusMeters {
+				continue
+			}
+			candidate.DistanceMeters = &distance
+
+			score, ok := scoreNearby(similarity, distance, cfg.DuplicateRadiusMeters, cfg.DuplicateMinSimilarity)
+			if !ok {
+				continue
+			}
+			candidate.Score = score
+		} else {
+			if similarity < cfg.DuplicateMinSimilarity {
+				continue
+			}
+			candidate.Score = similarity
+		}
+
+		candidates = append(candidates, candidate)
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].Score > candidates[j].Score
+	})
+
+	if len(candidates) > maxCandidates {
+		candidates = candidates[:maxCandidates]
+	}
+
+	return candidates, nil
+}
+
+// scoreNearby scores a located candidate within the search radius and
+// reports whether it is similar enough to suggest. Being at the same spot
+// counts for as much as saying the same thing, and a report at the same spot
+// needs only half the textual overlap of one at the edge of the radius.
+func scoreNearby(similarity, distance, radius, minSimilarity float64) (float64, bool) {
+	proximity := 1 - distance/radius
+	if similarity < minSimilarity*(1-0.5*proximity) {
+		return 0, false
+	}
+	return 0.5*similarity + 0.5*proximity, true
+}
+
+// tokenize normalizes and stems text into a set of words, dropping
+// single-letter tokens
+func tokenize(text string) map[string]struct{} {
+	words := arabic.Tokens(text)
+
+	set := make(map[string]struct{}, len(words))
+	for _, word := range words {
+		if arabic.RuneLen(word) < 2 {
+			continue
+		}
+		set[word] = struct{}{}
+	}
+	return set
+}
+
+// jaccard returns the overlap between two token sets in [0, 1]
+func jaccard(a, b map[string]struct{}) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+
+	intersection := 0
+	for word := range a {
+		if _, ok := b[word]; ok {
+			intersection++
+		}
+	}
+
+	union := len(a) + len(b) - intersection
+	return float64(intersection) / float64(union)
+}
+
+// haversine returns the great-circle distance in meters between two points
+func haversine(lat1, lng1, lat2, lng2 float64) float64 {
+	toRad := func(deg float64) float64 { return deg * math.Pi / 180 }
+
+	dLat := toRad(lat2 - lat1)
+	dLng := toRad(lng2 - lng1)
+
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRad(lat1))*math.Cos(toRad(lat2))*math.Sin(dLng/2)*math.Sin(dLng/2)
+
+	return 2 * earthRadiusMeters * math.Asin(math.Sqrt(a))
+}
@@ -0,0 +1,45 @@
+package dedup
+
+import "testing"
+
+func TestScoreNearby(t *testing.T) {
+	const (
+		radius        = 150.0
+		minSimilarity = 0.4
+	)
+
+	tests := []struct {
+		name       string
+		similarity float64
+		distance   float64
+		want       bool
+	}{
+		{"same spot, high similarity", 0.8, 0, true},
+		{"same spot, moderate similarity", 0.25, 0, true},
+		{"same spot, low similarity", 0.1, 0, false},
+		{"near, high similarity", 0.8, 15, true},
+		{"near, low similarity", 0.1, 15, false},
+		{"far, high similarity", 0.8, 145, true},
+		{"far, moderate similarity", 0.25, 145, false},
+		{"far, low similarity", 0.1, 145, false},
+		{"edge, exactly the minimum", 0.4, radius, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			score, ok := scoreNearby(tt.similarity, tt.distance, radius, minSimilarity)
+			if ok != tt.want {
+				t.Errorf("scoreNearby(%v, %v) ok = %v, want %v", tt.similarity, tt.distance, ok, tt.want)
+			}
+			if ok && (score <= 0 || score > 1) {
+				t.Errorf("scoreNearby(%v, %v) score = %v, want in (0, 1]", tt.similarity, tt.distance, score)
+			}
+		})
+	}
+
+	near, _ := scoreNearby(0.8, 15, radius, minSimilarity)
+	far, _ := scoreNearby(0.8, 145, radius, minSimilarity)
+	if near <= far {
+		t.Errorf("near score %v is not above far score %v", near, far)
+	}
+}
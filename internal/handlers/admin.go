@@ -1,19 +1,30 @@
 package handlers
 
 import (
+	"errors"
 	"log/slog"
+	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/hakim/backend/internal/config"
+	"github.com/hakim/backend/internal/dedup"
+	"github.com/hakim/backend/internal/models"
 	"github.com/hakim/backend/internal/utils"
 	"github.com/hakim/backend/pkg/supabase"
 )
 
 type AdminHandler struct {
-	client *supabase.Client
+	client   *supabase.Client
+	detector *dedup.Detector
 }
 
-func NewAdminHandler(client *supabase.Client) *AdminHandler {
-	return &AdminHandler{client: client}
+func NewAdminHandler(client *supabase.Client, detector *dedup.Detector) *AdminHandler {
+	return &AdminHandler{
+		client:   client,
+		detector: detector,
+	}
 }
 
 func (h *AdminHandler) ListComplaints(c *fiber.Ctx) error {
@@ -24,10 +35,11 @@ func (h *AdminHandler) ListComplaints(c *fiber.Ctx) error {
 
 	status := c.Query("status")
 	departmentID := c.Query("department_id")
+	search := c.Query("q")
 	page := c.QueryInt("page", 1)
 	limit := c.QueryInt("limit", 20)
 
-	complaints, err := h.client.GetAllComplaints(token, status, departmentID, page, limit)
+	complaints, err := h.client.GetAllComplaints(token, status, departmentID, search, page, limit)
 	if err != nil {
 		return utils.JSONInternalError(c, err)
 	}
@@ -53,9 +65,38 @@ func (h *AdminHandler) GetComplaint(c *fiber.Ctx) error {
 		return utils.JSONError(c, fiber.StatusNotFound, "Complaint not found")
 	}
 
+	details, err := h.client.GetLatestClassification(token, id)
+	if err != nil {
+		slog.Warn("Failed to load classification details", "id", id, "error", err)
+	}
+	complaint.AIDetails = details
+
 	return c.JSON(complaint)
 }
 
+// GetAttachments lists a complaint's attachments with the AI's image
+// findings and voice note transcripts
+func (h *AdminHandler) GetAttachments(c *fiber.Ctx) error {
+	token, err := utils.GetToken(c)
+	if err != nil {
+		return utils.JSONError(c, fiber.StatusUnauthorized, "Unauthorized")
+	}
+
+	id := c.Params("id")
+
+	if _, err := h.client.GetComplaintAdmin(token, id); err != nil {
+		slog.Warn("Complaint not found (admin)", "id", id, "error", err)
+		return utils.JSONError(c, fiber.StatusNotFound, "Complaint not found")
+	}
+
+	attachments, err := h.client.GetComplaintAttachments(token, id)
+	if err != nil {
+		return utils.JSONInternalError(c, err)
+	}
+
+	return c.JSON(fiber.Map{"data": attachments})
+}
+
 func (h *AdminHandler) AssignComplaint(c *fiber.Ctx) error {
 	token, err := utils.GetToken(c)
 	if err != nil {
@@ -115,6 +156,228 @@ func (h *AdminHandler) UpdateStatus(c *fiber.Ctx) error {
 	return c.JSON(complaint)
 }
 
+// GetDuplicates returns likely duplicates of a complaint and the complaints
+// already merged into it
+func (h *AdminHandler) GetDuplicates(c *fiber.Ctx) error {
+	token, err := utils.GetToken(c)
+	if err != nil {
+		return utils.JSONError(c, fiber.StatusUnauthorized, "Unauthorized")
+	}
+
+	id := c.Params("id")
+
+	complaint, err := h.client.GetComplaintAdmin(token, id)
+	if err != nil {
+		slog.Warn("Complaint not found (admin)", "id", id, "error", err)
+		return utils.JSONError(c, fiber.StatusNotFound, "Complaint not found")
+	}
+
+	candidates, err := h.detector.FindCandidates(dedup.Submission{
+		ExcludeID:   complaint.ID,
+		CategoryID:  complaint.CategoryID,
+		Title:       complaint.Title,
+		Description: complaint.Description,
+		Latitude:    complaint.Latitude,
+		Longitude:   complaint.Longitude,
+	})
+	if err != nil {
+		return utils.JSONInternalError(c, err)
+	}
+
+	merged, err := h.client.GetMergedComplaints(token, id)
+	if err != nil {
+		return utils.JSONInternalError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"candidates": candidates,
+		"merged":     merged,
+	})
+}
+
+// MergeComplaints keeps the complaint in the path as master and links the
+// given duplicates to it
+func (h *AdminHandler) MergeComplaints(c *fiber.Ctx) error {
+	token, err := utils.GetToken(c)
+	if err != nil {
+		return utils.JSONError(c, fiber.StatusUnauthorized, "Unauthorized")
+	}
+
+	user, err := utils.GetUser(c)
+	if err != nil {
+		return utils.JSONError(c, fiber.StatusUnauthorized, "Unauthorized")
+	}
+
+	id := c.Params("id")
+
+	var req models.MergeComplaintsRequest
+	if err := c.BodyParser(&req); err != nil {
+		slog.Warn("Invalid request body", "error", err)
+		return utils.JSONError(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if len(req.DuplicateIDs) == 0 {
+		return utils.JSONError(c, fiber.StatusBadRequest, "duplicate_ids is required")
+	}
+
+	result, err := h.client.MergeComplaints(token, id, req.DuplicateIDs, req.Note, user.ID.String())
+	if err != nil {
+		return utils.JSONInternalError(c, err)
+	}
+
+	return c.JSON(result)
+}
+
+// TransferComplaint moves a complaint to another department, either directly
+// or pending acceptance by the receiving department
+func (h *AdminHandler) TransferComplaint(c *fiber.Ctx) error {
+	token, err := utils.GetToken(c)
+	if err != nil {
+		return utils.JSONError(c, fiber.StatusUnauthorized, "Unauthorized")
+	}
+
+	user, err := utils.GetUser(c)
+	if err != nil {
+		return utils.JSONError(c, fiber.StatusUnauthorized, "Unauthorized")
+	}
+
+	id := c.Params("id")
+
+	var req models.TransferRequest
+	if err := c.BodyParser(&req); err != nil {
+		slog.Warn("Invalid request body", "error", err)
+		return utils.JSONError(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if req.ToDepartmentID == uuid.Nil {
+		return utils.JSONError(c, fiber.StatusBadRequest, "to_department_id is required")
+	}
+	if strings.TrimSpace(req.Reason) == "" {
+		return utils.JSONError(c, fiber.StatusBadRequest, "Transfer reason is required")
+	}
+
+	requireAcceptance := config.AppConfig.TransferRequireAcceptance
+	if req.RequireAcceptance != nil {
+		requireAcceptance = *req.RequireAcceptance
+	}
+
+	transfer, err := h.client.TransferComplaint(token, id, &req, user.ID.String(), requireAcceptance)
+	if err != nil {
+		switch {
+		case errors.Is(err, supabase.ErrUnknownDepartment),
+			errors.Is(err, supabase.ErrSameDepartment),
+			errors.Is(err, supabase.ErrCategoryNotFound),
+			errors.Is(err, supabase.ErrCategoryNotInTarget):
+			return utils.JSONError(c, fiber.StatusBadRequest, err.Error())
+		case errors.Is(err, supabase.ErrTransferAlreadyPending):
+			return utils.JSONError(c, fiber.StatusConflict, err.Error())
+		}
+		return utils.JSONInternalError(c, err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(transfer)
+}
+
+func (h *AdminHandler) ListTransfers(c *fiber.Ctx) error {
+	token, err := utils.GetToken(c)
+	if err != nil {
+		return utils.JSONError(c, fiber.StatusUnauthorized, "Unauthorized")
+	}
+
+	status := c.Query("status")
+	departmentID := c.Query("department_id")
+	complaintID := c.Query("complaint_id")
+
+	transfers, err := h.client.GetTransfers(token, status, departmentID, complaintID)
+	if err != nil {
+		return utils.JSONInternalError(c, err)
+	}
+
+	return c.JSON(transfers)
+}
+
+func (h *AdminHandler) AcceptTransfer(c *fiber.Ctx) error {
+	return h.respondToTransfer(c, true)
+}
+
+func (h *AdminHandler) RejectTransfer(c *fiber.Ctx) error {
+	return h.respondToTransfer(c, false)
+}
+
+func (h *AdminHandler) respondToTransfer(c *fiber.Ctx, accept bool) error {
+	token, err := utils.GetToken(c)
+	if err != nil {
+		return utils.JSONError(c, fiber.StatusUnauthorized, "Unauthorized")
+	}
+
+	user, err := utils.GetUser(c)
+	if err != nil {
+		return utils.JSONError(c, fiber.StatusUnauthorized, "Unauthorized")
+	}
+
+	id := c.Params("id")
+
+	var req struct {
+		Note string `json:"note"`
+	}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			slog.Warn("Invalid request body", "error", err)
+			return utils.JSONError(c, fiber.StatusBadRequest, "Invalid request body")
+		}
+	}
+
+	transfer, err := h.client.RespondToTransfer(token, id, accept, req.Note, user)
+	if err != nil {
+		switch {
+		case errors.Is(err, supabase.ErrTransferForbidden):
+			return utils.JSONError(c, fiber.StatusForbidden, err.Error())
+		case errors.Is(err, supabase.ErrTransferNotPending):
+			return utils.JSONError(c, fiber.StatusConflict, err.Error())
+		}
+		return utils.JSONInternalError(c, err)
+	}
+
+	return c.JSON(transfer)
+}
+
+// GetTransferStats reports transfer counts per department pair
+func (h *AdminHandler) GetTransferStats(c *fiber.Ctx) error {
+	token, err := utils.GetToken(c)
+	if err != nil {
+		return utils.JSONError(c, fiber.StatusUnauthorized, "Unauthorized")
+	}
+
+	var since *time.Time
+	if days := c.QueryInt("days", 0); days > 0 {
+		from := time.Now().AddDate(0, 0, -days)
+		since = &from
+	}
+
+	stats, err := h.client.GetTransferStats(token, since)
+	if err != nil {
+		return utils.JSONInternalError(c, err)
+	}
+
+	return c.JSON(stats)
+}
+
+// GetCategoryDisagreements reports how often the AI disagreed with the
+// category citizens chose
+func (h *AdminHandler) GetCategoryDisagreements(c *fiber.Ctx) error {
+	token, err := utils.GetToken(c)
+	if err != nil {
+		return utils.JSONError(c, fiber.StatusUnauthorized, "Unauthorized")
+	}
+
+	stats, err := h.client.GetCategoryDisagreementStats(token)
+	if err != nil {
+		return utils.JSONInternalError(c, err)
+	}
+
+	return c.JSON(stats)
+}
+
 func (h *AdminHandler) GetAnalytics(c *fiber.Ctx) error {
 	token, err := utils.GetToken(c)
 	if err != nil {
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/hakim/backend/internal/models"
+	"github.com/hakim/backend/internal/pipeline"
+	"github.com/hakim/backend/internal/rules"
+	"github.com/hakim/backend/internal/utils"
+	"github.com/hakim/backend/pkg/supabase"
+)
+
+type AppealHandler struct {
+	client   *supabase.Client
+	pipeline *pipeline.Classifier
+	engine   *rules.Engine
+}
+
+func NewAppealHandler(client *supabase.Client, pipeline *pipeline.Classifier, engine *rules.Engine) *AppealHandler {
+	return &AppealHandler{client: client, pipeline: pipeline, engine: engine}
+}
+
+// List returns the appeal moderation queue, pending appeals by default
+func (h *AppealHandler) List(c *fiber.Ctx) error {
+	token, err := utils.GetToken(c)
+	if err != nil {
+		return utils.JSONError(c, fiber.StatusUnauthorized, "Unauthorized")
+	}
+
+	status := c.Query("status", string(models.AppealPending))
+	page := c.QueryInt("page", 1)
+	limit := c.QueryInt("limit", 20)
+
+	appeals, err := h.client.GetAppeals(token, status, page, limit)
+	if err != nil {
+		return utils.JSONInternalError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"data":  appeals,
+		"page":  page,
+		"limit": limit,
+	})
+}
+
+// Uphold keeps the rejection in place
+func (h *AppealHandler) Uphold(c *fiber.Ctx) error {
+	return h.decide(c, false)
+}
+
+// Overturn reinstates the complaint and sends it through classification
+// without the screening step
+func (h *AppealHandler) Overturn(c *fiber.Ctx) error {
+	return h.decide(c, true)
+}
+
+// Stats reports how often each source and rule is appealed and overturned
+func (h *AppealHandler) Stats(c *fiber.Ctx) error {
+	token, err := utils.GetToken(c)
+	if err != nil {
+		return utils.JSONError(c, fiber.StatusUnauthorized, "Unauthorized")
+	}
+
+	stats, err := h.client.GetAppealStats(token)
+	if err != nil {
+		return utils.JSONInternalError(c, err)
+	}
+	for i := range stats.Rules {
+		stats.Rules[i].Demoted = h.engine.Demoted(stats.Rules[i].RuleID)
+	}
+
+	return c.JSON(stats)
+}
+
+func (h *AppealHandler) decide(c *fiber.Ctx, overturn bool) error {
+	token, err := utils.GetToken(c)
+	if err != nil {
+		return utils.JSONError(c, fiber.StatusUnauthorized, "Unauthorized")
+	}
+
+	user, err := utils.GetUser(c)
+	if err != nil {
+		return utils.JSONError(c, fiber.StatusUnauthorized, "Unauthorized")
+	}
+
+	var decision models.AppealDecision
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&decision); err != nil {
+			slog.Warn("Invalid request body", "error", err)
+			return utils.JSONError(c, fiber.StatusBadRequest, "Invalid request body")
+		}
+	}
+
+	appeal, err := h.client.DecideAppeal(token, c.Params("id"), overturn, &decision, user)
+	if err != nil {
+		switch {
+		case errors.Is(err, supabase.ErrAppealNotFound):
+			return utils.JSONError(c, fiber.StatusNotFound, err.Error())
+		case errors.Is(err, supabase.ErrAppealNotPending):
+			return utils.JSONError(c, fiber.StatusConflict, err.Error())
+		}
+		return utils.JSONInternalError(c, err)
+	}
+
+	if overturn {
+		complaintID := appeal.ComplaintID.String()
+		if err := h.pipeline.Enqueue(complaintID); err != nil {
+			slog.Error("Failed to queue classification", "complaint_id", complaintID, "error", err)
+			_ = h.client.SetClassificationStatus(complaintID, supabase.ClassificationFailed)
+		}
+	}
+
+	// Decisions change the override rates that demote screening rules
+	if len(appeal.RejectionRuleIDs) > 0 {
+		if err := h.engine.Reload(); err != nil {
+			slog.Warn("Failed to reload classification rules", "error", err)
+		}
+	}
+
+	return c.JSON(appeal)
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"log/slog"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/hakim/backend/internal/bulk"
+	"github.com/hakim/backend/internal/config"
+	"github.com/hakim/backend/internal/models"
+	"github.com/hakim/backend/internal/utils"
+	"github.com/hakim/backend/pkg/supabase"
+)
+
+type BulkHandler struct {
+	client *supabase.Client
+	runner *bulk.Runner
+}
+
+func NewBulkHandler(client *supabase.Client, runner *bulk.Runner) *BulkHandler {
+	return &BulkHandler{
+		client: client,
+		runner: runner,
+	}
+}
+
+// Apply runs a bulk operation. Small sets are processed inline and return
+// per-item results; large sets are queued as a tracked job.
+func (h *BulkHandler) Apply(c *fiber.Ctx) error {
+	token, err := utils.GetToken(c)
+	if err != nil {
+		return utils.JSONError(c, fiber.StatusUnauthorized, "Unauthorized")
+	}
+
+	user, err := utils.GetUser(c)
+	if err != nil {
+		return utils.JSONError(c, fiber.StatusUnauthorized, "Unauthorized")
+	}
+
+	var req models.BulkOperationRequest
+	if err := c.BodyParser(&req); err != nil {
+		slog.Warn("Invalid request body", "error", err)
+		return utils.JSONError(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := bulk.Validate(&req); err != nil {
+		return utils.JSONError(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	if len(req.IDs) > config.AppConfig.BulkMaxItems {
+		return utils.JSONError(c, fiber.StatusBadRequest, "Too many complaints in one bulk operation")
+	}
+
+	ids, err := h.runner.ResolveIDs(token, &req)
+	if err != nil {
+		return utils.JSONInternalError(c, err)
+	}
+
+	if len(ids) == 0 {
+		return c.JSON(&models.BulkResult{Results: []models.BulkItemResult{}})
+	}
+
+	if len(ids) <= config.AppConfig.BulkAsyncThreshold {
+		return c.JSON(h.runner.Execute(token, user, &req, ids))
+	}
+
+	job, err := h.runner.Enqueue(token, user, &req, ids)
+	if err != nil {
+		return utils.JSONInternalError(c, err)
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(job)
+}
+
+// GetJob returns the progress and per-item results of a bulk job
+func (h *BulkHandler) GetJob(c *fiber.Ctx) error {
+	token, err := utils.GetToken(c)
+	if err != nil {
+		return utils.JSONError(c, fiber.StatusUnauthorized, "Unauthorized")
+	}
+
+	id := c.Params("id")
+
+	job, err := h.client.GetBulkJob(token, id)
+	if err != nil {
+		slog.Warn("Bulk job not found", "id", id, "error", err)
+		return utils.JSONError(c, fiber.StatusNotFound, "Bulk job not found")
+	}
+
+	return c.JSON(job)
+}
@@ -1,26 +1,42 @@
 package handlers
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log/slog"
 	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/google/uuid"
 	"github.com/hakim/backend/internal/ai"
+	"github.com/hakim/backend/internal/dedup"
 	"github.com/hakim/backend/internal/models"
+	"github.com/hakim/backend/internal/pipeline"
+	"github.com/hakim/backend/internal/safeurl"
 	"github.com/hakim/backend/internal/utils"
 	"github.com/hakim/backend/pkg/supabase"
 )
 
+// attachmentCheckTimeout bounds resolving the hosts of a submission's
+// attachment URLs
+const attachmentCheckTimeout = 5 * time.Second
+
 type ComplaintHandler struct {
 	client     *supabase.Client
 	classifier *ai.Classifier
+	detector   *dedup.Detector
+	pipeline   *pipeline.Classifier
+	urls       *safeurl.Policy
 }
 
-func NewComplaintHandler(client *supabase.Client, classifier *ai.Classifier) *ComplaintHandler {
+func NewComplaintHandler(client *supabase.Client, classifier *ai.Classifier, detector *dedup.Detector, queue *pipeline.Classifier, urls *safeurl.Policy) *ComplaintHandler {
 	return &ComplaintHandler{
 		client:     client,
 		classifier: classifier,
+		detector:   detector,
+		pipeline:   queue,
+		urls:       urls,
 	}
 }
 
@@ -41,35 +57,280 @@ func (h *ComplaintHandler) Create(c *fiber.Ctx) error {
 		return utils.JSONError(c, fiber.StatusBadRequest, "Invalid request body")
 	}
 
-	if req.Title == "" || req.Description == "" {
+	// A voice note can stand in for the written description
+	if req.Title == "" || (req.Description == "" && len(req.VoiceNotes) == 0) {
 		return utils.JSONError(c, fiber.StatusBadRequest, "Title and description are required")
 	}
 
-	// AI classification with image support
-	classification, err := h.classifier.ClassifyWithImages(req.Title, req.Description, req.Attachments)
+	// Attachment URLs are fetched by the server later, so they must point to
+	// our storage and not to internal addresses
+	if problems := h.checkAttachments(c.UserContext(), &req); len(problems) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Invalid attachment URL",
+			"details": problems,
+		})
+	}
+
+	// Only the cheap junk filter runs inline; full classification is queued.
+	// A voice note usually carries the details a short text lacks, so no
+	// questions are asked before it is transcribed.
+	screen := h.classifier.PreScreen
+	if len(req.VoiceNotes) > 0 {
+		screen = h.classifier.PreScreenJunk
+	}
+	screenErr := screen(req.Title, req.Description)
+
+	complaint, err := h.client.CreateComplaint(token, user.ID.String(), &req, nil)
 	if err != nil {
-		errStr := err.Error()
-		// Check if the complaint was rejected by AI
-		if strings.HasPrefix(errStr, "REJECTED:") {
-			rejectionReason := strings.TrimPrefix(errStr, "REJECTED: ")
-			slog.Info("Complaint rejected by AI", "reason", rejectionReason, "title", req.Title)
-			return utils.JSONError(c, fiber.StatusBadRequest, rejectionReason)
+		return utils.JSONInternalError(c, err)
+	}
+
+	// Rejected submissions are kept so the citizen can appeal
+	if rejection, rejected := ai.AsRejection(screenErr); rejected {
+		slog.Info("Complaint rejected by pre-screen", "complaint_id", complaint.ID, "reason", rejection.Reason)
+		if err := h.client.RejectComplaintSystem(complaint, rejection.Reason, rejection.Source, rejection.RuleIDs); err != nil {
+			return utils.JSONInternalError(c, err)
+		}
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":     rejection.Reason,
+			"complaint": complaint,
+		})
+	}
+
+	// Too little to go on: ask the citizen before classifying
+	if clarification, ok := ai.AsClarification(screenErr); ok {
+		questions, err := h.client.RequestClarification(complaint, clarification.Source, clarification.Questions, false)
+		if err != nil {
+			return utils.JSONInternalError(c, err)
 		}
-		slog.Warn("AI classification failed", "error", err)
-		// Continue without classification for other errors
+		return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+			"complaint": complaint,
+			"questions": questions,
+		})
 	}
 
-	// If no category provided, use AI suggestion
-	if req.CategoryID == uuid.Nil && classification != nil {
-		req.CategoryID = classification.CategoryID
+	if err := h.pipeline.Enqueue(complaint.ID.String()); err != nil {
+		slog.Error("Failed to queue classification", "complaint_id", complaint.ID, "error", err)
+		_ = h.client.SetClassificationStatus(complaint.ID.String(), supabase.ClassificationFailed)
+		complaint.ClassificationStatus = supabase.ClassificationFailed
 	}
 
-	complaint, err := h.client.CreateComplaint(token, user.ID.String(), &req, classification)
+	// Suggest linking to an existing report of the same incident
+	h.flagSimilarReport(complaint)
+
+	return c.Status(fiber.StatusCreated).JSON(complaint)
+}
+
+// Appeal asks staff to reconsider an automatic rejection
+func (h *ComplaintHandler) Appeal(c *fiber.Ctx) error {
+	user, err := utils.GetUser(c)
+	if err != nil {
+		return utils.JSONError(c, fiber.StatusUnauthorized, "Unauthorized")
+	}
+
+	token, err := utils.GetToken(c)
+	if err != nil {
+		return utils.JSONError(c, fiber.StatusUnauthorized, "Unauthorized")
+	}
+
+	var req models.AppealRequest
+	if err := c.BodyParser(&req); err != nil {
+		slog.Warn("Invalid request body", "error", err)
+		return utils.JSONError(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+	req.Statement = strings.TrimSpace(req.Statement)
+	if req.Statement == "" {
+		return utils.JSONError(c, fiber.StatusBadRequest, "statement is required")
+	}
+
+	id := c.Params("id")
+
+	appeal, err := h.client.AppealRejection(token, id, user.ID.String(), req.Statement)
+	if err != nil {
+		switch {
+		case errors.Is(err, supabase.ErrNotAppealable):
+			return utils.JSONError(c, fiber.StatusBadRequest, err.Error())
+		case errors.Is(err, supabase.ErrAlreadyAppealed):
+			return utils.JSONError(c, fiber.StatusConflict, err.Error())
+		}
+		slog.Warn("Appeal failed", "id", id, "error", err)
+		return utils.JSONError(c, fiber.StatusNotFound, "Complaint not found")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(appeal)
+}
+
+// GetClarifications returns the questions put to the citizen about a vague
+// complaint, with the answers given so far
+func (h *ComplaintHandler) GetClarifications(c *fiber.Ctx) error {
+	token, err := utils.GetToken(c)
+	if err != nil {
+		return utils.JSONError(c, fiber.StatusUnauthorized, "Unauthorized")
+	}
+
+	questions, err := h.client.GetClarifications(token, c.Params("id"))
 	if err != nil {
 		return utils.JSONInternalError(c, err)
 	}
 
-	return c.Status(fiber.StatusCreated).JSON(complaint)
+	return c.JSON(fiber.Map{"data": questions})
+}
+
+// AnswerClarifications takes the citizen's answers and queues the complaint
+// for classification again
+func (h *ComplaintHandler) AnswerClarifications(c *fiber.Ctx) error {
+	user, err := utils.GetUser(c)
+	if err != nil {
+		return utils.JSONError(c, fiber.StatusUnauthorized, "Unauthorized")
+	}
+
+	var req models.AnswerClarificationsRequest
+	if err := c.BodyParser(&req); err != nil {
+		slog.Warn("Invalid request body", "error", err)
+		return utils.JSONError(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+	if len(req.Answers) == 0 {
+		return utils.JSONError(c, fiber.StatusBadRequest, "answers are required")
+	}
+
+	id := c.Params("id")
+
+	complaint, err := h.client.AnswerClarifications(id, user.ID.String(), req.Answers)
+	if err != nil {
+		switch {
+		case errors.Is(err, supabase.ErrNoOpenQuestions):
+			return utils.JSONError(c, fiber.StatusConflict, err.Error())
+		case errors.Is(err, supabase.ErrNoAnswers):
+			return utils.JSONError(c, fiber.StatusBadRequest, err.Error())
+		}
+		slog.Warn("Answering clarifications failed", "id", id, "error", err)
+		return utils.JSONError(c, fiber.StatusNotFound, "Complaint not found")
+	}
+
+	if err := h.pipeline.Enqueue(id); err != nil {
+		slog.Error("Failed to queue classification", "complaint_id", id, "error", err)
+		_ = h.client.SetClassificationStatus(id, supabase.ClassificationFailed)
+		complaint.ClassificationStatus = supabase.ClassificationFailed
+	}
+
+	return c.JSON(complaint)
+}
+
+// GetDeflection returns the knowledge base answers an information request
+// was closed with
+func (h *ComplaintHandler) GetDeflection(c *fiber.Ctx) error {
+	token, err := utils.GetToken(c)
+	if err != nil {
+		return utils.JSONError(c, fiber.StatusUnauthorized, "Unauthorized")
+	}
+
+	deflection, err := h.client.GetComplaintDeflection(token, c.Params("id"))
+	if err != nil {
+		if errors.Is(err, supabase.ErrNotDeflected) {
+			return utils.JSONError(c, fiber.StatusNotFound, err.Error())
+		}
+		return utils.JSONInternalError(c, err)
+	}
+
+	return c.JSON(deflection)
+}
+
+// EscalateDeflection reopens an information request the answers didn't help
+// with and queues it to be handled as a complaint
+func (h *ComplaintHandler) EscalateDeflection(c *fiber.Ctx) error {
+	user, err := utils.GetUser(c)
+	if err != nil {
+		return utils.JSONError(c, fiber.StatusUnauthorized, "Unauthorized")
+	}
+
+	id := c.Params("id")
+
+	complaint, err := h.client.EscalateDeflection(id, user.ID.String())
+	if err != nil {
+		switch {
+		case errors.Is(err, supabase.ErrNotDeflected):
+			return utils.JSONError(c, fiber.StatusBadRequest, err.Error())
+		case errors.Is(err, supabase.ErrDeflectionEscalated):
+			return utils.JSONError(c, fiber.StatusConflict, err.Error())
+		}
+		slog.Warn("Escalating deflection failed", "id", id, "error", err)
+		return utils.JSONError(c, fiber.StatusNotFound, "Complaint not found")
+	}
+
+	if err := h.pipeline.Enqueue(id); err != nil {
+		slog.Error("Failed to queue classification", "complaint_id", id, "error", err)
+		_ = h.client.SetClassificationStatus(id, supabase.ClassificationFailed)
+		complaint.ClassificationStatus = supabase.ClassificationFailed
+	}
+
+	return c.JSON(complaint)
+}
+
+// GetAppeal returns the appeal against a complaint's rejection and its outcome
+func (h *ComplaintHandler) GetAppeal(c *fiber.Ctx) error {
+	token, err := utils.GetToken(c)
+	if err != nil {
+		return utils.JSONError(c, fiber.StatusUnauthorized, "Unauthorized")
+	}
+
+	appeal, err := h.client.GetComplaintAppeal(token, c.Params("id"))
+	if err != nil {
+		return utils.JSONInternalError(c, err)
+	}
+	if appeal == nil {
+		return utils.JSONError(c, fiber.StatusNotFound, "No appeal for this complaint")
+	}
+
+	return c.JSON(appeal)
+}
+
+// checkAttachments validates every attachment and voice note URL and
+// describes each one that is refused
+func (h *ComplaintHandler) checkAttachments(ctx context.Context, req *models.CreateComplaintRequest) []string {
+	ctx, cancel := context.WithTimeout(ctx, attachmentCheckTimeout)
+	defer cancel()
+
+	var problems []string
+	for i, attachment := range req.Attachments {
+		if err := h.urls.Validate(ctx, attachment); err != nil {
+			problems = append(problems, fmt.Sprintf("attachments[%d]: %v", i, err))
+		}
+	}
+	for i, note := range req.VoiceNotes {
+		if err := h.urls.Validate(ctx, note); err != nil {
+			problems = append(problems, fmt.Sprintf("voice_notes[%d]: %v", i, err))
+		}
+	}
+	return problems
+}
+
+// flagSimilarReport looks for existing reports of the same incident and
+// records the best match for staff. The citizen only learns that a similar
+// report exists and how to endorse it.
+func (h *ComplaintHandler) flagSimilarReport(complaint *models.Complaint) {
+	candidates, err := h.detector.FindCandidates(dedup.Submission{
+		ExcludeID:   complaint.ID,
+		CategoryID:  complaint.CategoryID,
+		Title:       complaint.Title,
+		Description: complaint.Description,
+		Latitude:    complaint.Latitude,
+		Longitude:   complaint.Longitude,
+	})
+	if err != nil {
+		slog.Warn("Duplicate detection failed", "complaint_id", complaint.ID, "error", err)
+		return
+	}
+	if len(candidates) == 0 {
+		return
+	}
+
+	best := candidates[0].ComplaintID
+	if err := h.client.SetSuggestedDuplicate(complaint.ID.String(), best.String()); err != nil {
+		slog.Warn("Failed to store suggested duplicate", "complaint_id", complaint.ID, "error", err)
+	} else {
+		complaint.SuggestedDuplicate = &best
+	}
+	complaint.SimilarReport = &models.SimilarReport{IncidentID: best}
 }
 
 func (h *ComplaintHandler) List(c *fiber.Ctx) error {
@@ -182,6 +443,62 @@ func (h *ComplaintHandler) SubmitFeedback(c *fiber.Ctx) error {
 	return c.Status(fiber.StatusCreated).JSON(feedback)
 }
 
+// Endorse adds the citizen as an affected party of an existing public incident
+func (h *ComplaintHandler) Endorse(c *fiber.Ctx) error {
+	user, err := utils.GetUser(c)
+	if err != nil {
+		return utils.JSONError(c, fiber.StatusUnauthorized, "Unauthorized")
+	}
+
+	token, err := utils.GetToken(c)
+	if err != nil {
+		return utils.JSONError(c, fiber.StatusUnauthorized, "Unauthorized")
+	}
+
+	id := c.Params("id")
+
+	result, err := h.client.EndorseIncident(token, id, user.ID.String())
+	if err != nil {
+		switch {
+		case errors.Is(err, supabase.ErrAlreadyEndorsed):
+			return utils.JSONError(c, fiber.StatusConflict, err.Error())
+		case errors.Is(err, supabase.ErrOwnComplaint):
+			return utils.JSONError(c, fiber.StatusBadRequest, err.Error())
+		case errors.Is(err, supabase.ErrIncidentNotFound):
+			return utils.JSONError(c, fiber.StatusNotFound, "Incident not found")
+		}
+		slog.Warn("Endorsement failed", "id", id, "error", err)
+		return utils.JSONError(c, fiber.StatusNotFound, "Incident not found or closed")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(result)
+}
+
+// RemoveEndorsement withdraws the citizen's endorsement of an incident
+func (h *ComplaintHandler) RemoveEndorsement(c *fiber.Ctx) error {
+	user, err := utils.GetUser(c)
+	if err != nil {
+		return utils.JSONError(c, fiber.StatusUnauthorized, "Unauthorized")
+	}
+
+	token, err := utils.GetToken(c)
+	if err != nil {
+		return utils.JSONError(c, fiber.StatusUnauthorized, "Unauthorized")
+	}
+
+	id := c.Params("id")
+
+	result, err := h.client.RemoveEndorsement(token, id, user.ID.String())
+	if err != nil {
+		if errors.Is(err, supabase.ErrIncidentNotFound) {
+			return utils.JSONError(c, fiber.StatusNotFound, "Incident not found")
+		}
+		return utils.JSONInternalError(c, err)
+	}
+
+	return c.JSON(result)
+}
+
 func (h *ComplaintHandler) GetStatusHistory(c *fiber.Ctx) error {
 	token, err := utils.GetToken(c)
 	if err != nil {
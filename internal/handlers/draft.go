@@ -0,0 +1,249 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/hakim/backend/internal/ai"
+	"github.com/hakim/backend/internal/models"
+	"github.com/hakim/backend/internal/utils"
+	"github.com/hakim/backend/pkg/supabase"
+)
+
+var errDraftForbidden = errors.New("only the complaint's department can draft for it")
+
+type DraftHandler struct {
+	client  *supabase.Client
+	drafter *ai.Drafter
+}
+
+func NewDraftHandler(client *supabase.Client, drafter *ai.Drafter) *DraftHandler {
+	return &DraftHandler{client: client, drafter: drafter}
+}
+
+// DraftReply writes a reply to the citizen for the employee to edit
+func (h *DraftHandler) DraftReply(c *fiber.Ctx) error {
+	return h.generate(c, models.DraftReply)
+}
+
+// ResolutionSummary writes the structured resolution record for closing the
+// complaint
+func (h *DraftHandler) ResolutionSummary(c *fiber.Ctx) error {
+	return h.generate(c, models.DraftResolutionSummary)
+}
+
+func (h *DraftHandler) generate(c *fiber.Ctx, kind models.DraftKind) error {
+	token, err := utils.GetToken(c)
+	if err != nil {
+		return utils.JSONError(c, fiber.StatusUnauthorized, "Unauthorized")
+	}
+
+	user, err := utils.GetUser(c)
+	if err != nil {
+		return utils.JSONError(c, fiber.StatusUnauthorized, "Unauthorized")
+	}
+
+	var req models.DraftRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			slog.Warn("Invalid request body", "error", err)
+			return utils.JSONError(c, fiber.StatusBadRequest, "Invalid request body")
+		}
+	}
+
+	id := c.Params("id")
+	complaint, err := h.client.GetComplaintAdmin(token, id)
+	if err != nil {
+		slog.Warn("Complaint not found (admin)", "id", id, "error", err)
+		return utils.JSONError(c, fiber.StatusNotFound, "Complaint not found")
+	}
+	if user.Role == string(models.RoleEmployee) &&
+		(user.DepartmentID == nil || *user.DepartmentID != complaint.DepartmentID) {
+		return utils.JSONError(c, fiber.StatusForbidden, errDraftForbidden.Error())
+	}
+
+	input := ai.DraftInput{Complaint: complaint, Instructions: req.Instructions}
+	if input.History, err = h.client.GetStatusHistory(token, id); err != nil {
+		slog.Warn("Failed to load status history for draft", "id", id, "error", err)
+	}
+
+	var draft *ai.Draft
+	if kind == models.DraftReply {
+		if input.Guidelines, err = h.client.GetReplyGuidelines(complaint.DepartmentID); err != nil {
+			slog.Warn("Failed to load reply guidelines", "department_id", complaint.DepartmentID, "error", err)
+		}
+		draft, err = h.drafter.DraftReply(input)
+	} else {
+		draft, err = h.drafter.SummarizeResolution(input)
+	}
+	if err != nil {
+		if errors.Is(err, ai.ErrDraftingUnavailable) {
+			return utils.JSONError(c, fiber.StatusServiceUnavailable, err.Error())
+		}
+		slog.Error("Failed to generate draft", "id", id, "kind", kind, "error", err)
+		return utils.JSONError(c, fiber.StatusBadGateway, "Failed to generate draft")
+	}
+
+	if err := h.client.RecordLLMUsage(id, complaint.DepartmentID, []supabase.LLMUsage{draft.Usage}); err != nil {
+		slog.Warn("Failed to record LLM usage", "id", id, "error", err)
+	}
+
+	saved, err := h.client.CreateDraft(token, &models.AIDraft{
+		ComplaintID:  complaint.ID,
+		Kind:         kind,
+		Instructions: strings.TrimSpace(req.Instructions),
+		DraftText:    draft.Text,
+		Summary:      draft.Summary,
+		Model:        draft.Model,
+		CreatedBy:    user.ID,
+	})
+	if err != nil {
+		return utils.JSONInternalError(c, err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(saved)
+}
+
+// Finalize records the text the employee sent. With a status the complaint
+// is moved to it and the text becomes the status note the citizen sees.
+func (h *DraftHandler) Finalize(c *fiber.Ctx) error {
+	token, err := utils.GetToken(c)
+	if err != nil {
+		return utils.JSONError(c, fiber.StatusUnauthorized, "Unauthorized")
+	}
+
+	user, err := utils.GetUser(c)
+	if err != nil {
+		return utils.JSONError(c, fiber.StatusUnauthorized, "Unauthorized")
+	}
+
+	var req models.FinalizeDraftRequest
+	if err := c.BodyParser(&req); err != nil {
+		slog.Warn("Invalid request body", "error", err)
+		return utils.JSONError(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+	req.FinalText = strings.TrimSpace(req.FinalText)
+	if req.FinalText == "" {
+		return utils.JSONError(c, fiber.StatusBadRequest, "final_text is required")
+	}
+	if req.Status != "" {
+		switch models.ComplaintStatus(req.Status) {
+		case models.StatusInReview, models.StatusAssigned, models.StatusInProgress,
+			models.StatusResolved, models.StatusClosed:
+		default:
+			return utils.JSONError(c, fiber.StatusBadRequest, "Invalid status")
+		}
+	}
+
+	draft, err := h.client.GetDraft(token, c.Params("id"))
+	if err != nil {
+		if errors.Is(err, supabase.ErrDraftNotFound) {
+			return utils.JSONError(c, fiber.StatusNotFound, err.Error())
+		}
+		return utils.JSONInternalError(c, err)
+	}
+
+	complaintID := draft.ComplaintID.String()
+	complaint, err := h.client.GetComplaintAdmin(token, complaintID)
+	if err != nil {
+		slog.Warn("Complaint not found (admin)", "id", complaintID, "error", err)
+		return utils.JSONError(c, fiber.StatusNotFound, "Complaint not found")
+	}
+	if user.Role == string(models.RoleEmployee) &&
+		(user.DepartmentID == nil || *user.DepartmentID != complaint.DepartmentID) {
+		return utils.JSONError(c, fiber.StatusForbidden, errDraftForbidden.Error())
+	}
+
+	// Finalizing first means a draft is never sent twice; it is reopened if
+	// the status change fails, so the employee can retry
+	finalized, err := h.client.FinalizeDraft(token, draft.ID.String(), req.FinalText, user.ID.String())
+	if err != nil {
+		if errors.Is(err, supabase.ErrDraftFinalized) {
+			return utils.JSONError(c, fiber.StatusConflict, err.Error())
+		}
+		return utils.JSONInternalError(c, err)
+	}
+
+	if req.Status == "" {
+		return c.JSON(fiber.Map{"draft": finalized})
+	}
+
+	updated, err := h.client.UpdateComplaintStatus(token, complaintID, req.Status, req.FinalText, user.ID.String())
+	if err != nil {
+		if reopenErr := h.client.ReopenDraft(token, finalized.ID.String()); reopenErr != nil {
+			slog.Error("Failed to reopen draft after status update failed", "draft_id", finalized.ID, "error", reopenErr)
+		}
+		return utils.JSONInternalError(c, err)
+	}
+
+	return c.JSON(fiber.Map{"draft": finalized, "complaint": updated})
+}
+
+// List returns drafts with their final text for quality review
+func (h *DraftHandler) List(c *fiber.Ctx) error {
+	token, err := utils.GetToken(c)
+	if err != nil {
+		return utils.JSONError(c, fiber.StatusUnauthorized, "Unauthorized")
+	}
+
+	page := c.QueryInt("page", 1)
+	limit := c.QueryInt("limit", 20)
+
+	drafts, err := h.client.GetDrafts(token, c.Query("kind"), c.Query("complaint_id"), page, limit)
+	if err != nil {
+		return utils.JSONInternalError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"data":  drafts,
+		"page":  page,
+		"limit": limit,
+	})
+}
+
+// GetGuidelines returns a department's tone guidelines for drafted replies
+func (h *DraftHandler) GetGuidelines(c *fiber.Ctx) error {
+	departmentID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.JSONError(c, fiber.StatusBadRequest, "Invalid department ID")
+	}
+
+	guidelines, err := h.client.GetReplyGuidelines(departmentID)
+	if err != nil {
+		return utils.JSONInternalError(c, err)
+	}
+
+	return c.JSON(fiber.Map{"department_id": departmentID, "guidelines": guidelines})
+}
+
+// SetGuidelines replaces a department's tone guidelines. Employees cannot
+// change them.
+func (h *DraftHandler) SetGuidelines(c *fiber.Ctx) error {
+	token, err := utils.GetToken(c)
+	if err != nil {
+		return utils.JSONError(c, fiber.StatusUnauthorized, "Unauthorized")
+	}
+
+	user, err := utils.GetUser(c)
+	if err != nil {
+		return utils.JSONError(c, fiber.StatusUnauthorized, "Unauthorized")
+	}
+	if user.Role == string(models.RoleEmployee) {
+		return utils.JSONError(c, fiber.StatusForbidden, "Only admins can change reply guidelines")
+	}
+
+	var req models.ReplyGuidelinesRequest
+	if err := c.BodyParser(&req); err != nil {
+		slog.Warn("Invalid request body", "error", err)
+		return utils.JSONError(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := h.client.SetReplyGuidelines(token, c.Params("id"), req.Guidelines); err != nil {
+		return utils.JSONInternalError(c, err)
+	}
+
+	return c.JSON(fiber.Map{"department_id": c.Params("id"), "guidelines": strings.TrimSpace(req.Guidelines)})
+}
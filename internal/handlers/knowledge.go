@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/url"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/hakim/backend/internal/knowledge"
+	"github.com/hakim/backend/internal/models"
+	"github.com/hakim/backend/internal/utils"
+	"github.com/hakim/backend/pkg/supabase"
+)
+
+type KnowledgeHandler struct {
+	client *supabase.Client
+}
+
+func NewKnowledgeHandler(client *supabase.Client) *KnowledgeHandler {
+	return &KnowledgeHandler{client: client}
+}
+
+// Search returns active entries, optionally for one department. With q the
+// entries are ranked against it and those sharing nothing are left out.
+func (h *KnowledgeHandler) Search(c *fiber.Ctx) error {
+	departmentID := c.Query("department_id")
+	if departmentID != "" {
+		if _, err := uuid.Parse(departmentID); err != nil {
+			return utils.JSONError(c, fiber.StatusBadRequest, "Invalid department ID")
+		}
+	}
+
+	entries, err := h.client.GetKnowledgeEntries("", departmentID, true)
+	if err != nil {
+		return utils.JSONInternalError(c, err)
+	}
+
+	if q := strings.TrimSpace(c.Query("q")); q != "" {
+		return c.JSON(fiber.Map{"data": knowledge.Rank(entries, q, uuid.Nil)})
+	}
+	return c.JSON(fiber.Map{"data": entries})
+}
+
+// List returns entries, active and inactive, optionally for one department
+func (h *KnowledgeHandler) List(c *fiber.Ctx) error {
+	token, err := utils.GetToken(c)
+	if err != nil {
+		return utils.JSONError(c, fiber.StatusUnauthorized, "Unauthorized")
+	}
+
+	departmentID := c.Query("department_id")
+	if departmentID != "" {
+		if _, err := uuid.Parse(departmentID); err != nil {
+			return utils.JSONError(c, fiber.StatusBadRequest, "Invalid department ID")
+		}
+	}
+
+	entries, err := h.client.GetKnowledgeEntries(token, departmentID, false)
+	if err != nil {
+		return utils.JSONInternalError(c, err)
+	}
+
+	return c.JSON(fiber.Map{"data": entries})
+}
+
+// Create adds an entry. Both languages are required so every citizen can be
+// answered.
+func (h *KnowledgeHandler) Create(c *fiber.Ctx) error {
+	token, err := utils.GetToken(c)
+	if err != nil {
+		return utils.JSONError(c, fiber.StatusUnauthorized, "Unauthorized")
+	}
+
+	user, err := utils.GetUser(c)
+	if err != nil {
+		return utils.JSONError(c, fiber.StatusUnauthorized, "Unauthorized")
+	}
+
+	var req models.CreateKnowledgeEntryRequest
+	if err := c.BodyParser(&req); err != nil {
+		slog.Warn("Invalid request body", "error", err)
+		return utils.JSONError(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	req.Question = strings.TrimSpace(req.Question)
+	req.QuestionAr = strings.TrimSpace(req.QuestionAr)
+	req.Answer = strings.TrimSpace(req.Answer)
+	req.AnswerAr = strings.TrimSpace(req.AnswerAr)
+	req.URL = strings.TrimSpace(req.URL)
+	if req.Question == "" || req.QuestionAr == "" || req.Answer == "" || req.AnswerAr == "" {
+		return utils.JSONError(c, fiber.StatusBadRequest, "question, question_ar, answer and answer_ar are required")
+	}
+	if req.URL != "" && !validLink(req.URL) {
+		return utils.JSONError(c, fiber.StatusBadRequest, "url must be an http or https link")
+	}
+
+	entry, err := h.client.CreateKnowledgeEntry(token, &req, user)
+	if err != nil {
+		if errors.Is(err, supabase.ErrKnowledgeForbidden) {
+			return utils.JSONError(c, fiber.StatusForbidden, err.Error())
+		}
+		return utils.JSONInternalError(c, err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(entry)
+}
+
+// Update changes an entry's text, link, keywords or active flag
+func (h *KnowledgeHandler) Update(c *fiber.Ctx) error {
+	token, err := utils.GetToken(c)
+	if err != nil {
+		return utils.JSONError(c, fiber.StatusUnauthorized, "Unauthorized")
+	}
+
+	user, err := utils.GetUser(c)
+	if err != nil {
+		return utils.JSONError(c, fiber.StatusUnauthorized, "Unauthorized")
+	}
+
+	var req models.UpdateKnowledgeEntryRequest
+	if err := c.BodyParser(&req); err != nil {
+		slog.Warn("Invalid request body", "error", err)
+		return utils.JSONError(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+	if req.CategoryID == nil && req.Question == nil && req.QuestionAr == nil && req.Answer == nil &&
+		req.AnswerAr == nil && req.URL == nil && req.Keywords == nil && req.IsActive == nil {
+		return utils.JSONError(c, fiber.StatusBadRequest, "No fields to update")
+	}
+	for _, field := range []*string{req.Question, req.QuestionAr, req.Answer, req.AnswerAr} {
+		if field != nil && strings.TrimSpace(*field) == "" {
+			return utils.JSONError(c, fiber.StatusBadRequest, "question and answer text cannot be empty")
+		}
+	}
+	if req.URL != nil && *req.URL != "" && !validLink(*req.URL) {
+		return utils.JSONError(c, fiber.StatusBadRequest, "url must be an http or https link")
+	}
+
+	entry, err := h.client.UpdateKnowledgeEntry(token, c.Params("id"), &req, user)
+	if err != nil {
+		switch {
+		case errors.Is(err, supabase.ErrKnowledgeEntryNotFound):
+			return utils.JSONError(c, fiber.StatusNotFound, err.Error())
+		case errors.Is(err, supabase.ErrKnowledgeForbidden):
+			return utils.JSONError(c, fiber.StatusForbidden, err.Error())
+		}
+		return utils.JSONInternalError(c, err)
+	}
+
+	return c.JSON(entry)
+}
+
+// Delete removes an entry
+func (h *KnowledgeHandler) Delete(c *fiber.Ctx) error {
+	token, err := utils.GetToken(c)
+	if err != nil {
+		return utils.JSONError(c, fiber.StatusUnauthorized, "Unauthorized")
+	}
+
+	user, err := utils.GetUser(c)
+	if err != nil {
+		return utils.JSONError(c, fiber.StatusUnauthorized, "Unauthorized")
+	}
+
+	if err := h.client.DeleteKnowledgeEntry(token, c.Params("id"), user); err != nil {
+		switch {
+		case errors.Is(err, supabase.ErrKnowledgeEntryNotFound):
+			return utils.JSONError(c, fiber.StatusNotFound, err.Error())
+		case errors.Is(err, supabase.ErrKnowledgeForbidden):
+			return utils.JSONError(c, fiber.StatusForbidden, err.Error())
+		}
+		return utils.JSONInternalError(c, err)
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// Stats reports how many complaints each department answered from the
+// knowledge base and how many of those were escalated anyway
+func (h *KnowledgeHandler) Stats(c *fiber.Ctx) error {
+	token, err := utils.GetToken(c)
+	if err != nil {
+		return utils.JSONError(c, fiber.StatusUnauthorized, "Unauthorized")
+	}
+
+	stats, err := h.client.GetDeflectionStats(token)
+	if err != nil {
+		return utils.JSONInternalError(c, err)
+	}
+
+	return c.JSON(stats)
+}
+
+// validLink reports whether s is an absolute http or https URL
+func validLink(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/hakim/backend/internal/models"
+	"github.com/hakim/backend/internal/utils"
+	"github.com/hakim/backend/pkg/supabase"
+)
+
+type ReviewHandler struct {
+	client *supabase.Client
+}
+
+func NewReviewHandler(client *supabase.Client) *ReviewHandler {
+	return &ReviewHandler{client: client}
+}
+
+// List returns the classification triage queue, pending items by default
+func (h *ReviewHandler) List(c *fiber.Ctx) error {
+	token, err := utils.GetToken(c)
+	if err != nil {
+		return utils.JSONError(c, fiber.StatusUnauthorized, "Unauthorized")
+	}
+
+	status := c.Query("status", string(models.ReviewPending))
+	departmentID := c.Query("department_id")
+	page := c.QueryInt("page", 1)
+	limit := c.QueryInt("limit", 20)
+
+	reviews, err := h.client.GetClassificationReviews(token, status, departmentID, page, limit)
+	if err != nil {
+		return utils.JSONInternalError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"data":  reviews,
+		"page":  page,
+		"limit": limit,
+	})
+}
+
+// Confirm accepts the predicted category and priority as correct
+func (h *ReviewHandler) Confirm(c *fiber.Ctx) error {
+	var req struct {
+		Note string `json:"note"`
+	}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			slog.Warn("Invalid request body", "error", err)
+			return utils.JSONError(c, fiber.StatusBadRequest, "Invalid request body")
+		}
+	}
+
+	return h.resolve(c, &models.ReviewCorrection{Note: req.Note})
+}
+
+// Correct reroutes the complaint to the category and priority chosen by staff
+func (h *ReviewHandler) Correct(c *fiber.Ctx) error {
+	var req models.ReviewCorrection
+	if err := c.BodyParser(&req); err != nil {
+		slog.Warn("Invalid request body", "error", err)
+		return utils.JSONError(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if req.CategoryID == nil && req.Priority == "" {
+		return utils.JSONError(c, fiber.StatusBadRequest, "category_id or priority is required")
+	}
+	if req.Priority != "" {
+		switch models.ComplaintPriority(req.Priority) {
+		case models.PriorityLow, models.PriorityMedium, models.PriorityHigh, models.PriorityCritical:
+		default:
+			return utils.JSONError(c, fiber.StatusBadRequest, "Invalid priority")
+		}
+	}
+	if req.CategoryID != nil {
+		category, err := h.client.GetCategory(req.CategoryID.String())
+		if err != nil {
+			return utils.JSONInternalError(c, err)
+		}
+		if category == nil {
+			return utils.JSONError(c, fiber.StatusBadRequest, "Invalid category_id")
+		}
+	}
+
+	return h.resolve(c, &req)
+}
+
+func (h *ReviewHandler) resolve(c *fiber.Ctx, correction *models.ReviewCorrection) error {
+	token, err := utils.GetToken(c)
+	if err != nil {
+		return utils.JSONError(c, fiber.StatusUnauthorized, "Unauthorized")
+	}
+
+	user, err := utils.GetUser(c)
+	if err != nil {
+		return utils.JSONError(c, fiber.StatusUnauthorized, "Unauthorized")
+	}
+
+	review, err := h.client.ResolveClassificationReview(token, c.Params("id"), correction, user)
+	if err != nil {
+		switch {
+		case errors.Is(err, supabase.ErrReviewForbidden):
+			return utils.JSONError(c, fiber.StatusForbidden, err.Error())
+		case errors.Is(err, supabase.ErrReviewNotPending):
+			return utils.JSONError(c, fiber.StatusConflict, err.Error())
+		case errors.Is(err, supabase.ErrCategoryNotFound):
+			return utils.JSONError(c, fiber.StatusBadRequest, "Invalid category_id")
+		case errors.Is(err, supabase.ErrCategoryRequired):
+			return utils.JSONError(c, fiber.StatusBadRequest, err.Error())
+		}
+		return utils.JSONInternalError(c, err)
+	}
+
+	return c.JSON(review)
+}
@@ -0,0 +1,230 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/hakim/backend/internal/models"
+	"github.com/hakim/backend/internal/rules"
+	"github.com/hakim/backend/internal/utils"
+	"github.com/hakim/backend/pkg/supabase"
+)
+
+type RuleHandler struct {
+	client *supabase.Client
+	engine *rules.Engine
+}
+
+func NewRuleHandler(client *supabase.Client, engine *rules.Engine) *RuleHandler {
+	return &RuleHandler{client: client, engine: engine}
+}
+
+// List returns classification rules, optionally for one rule set
+func (h *RuleHandler) List(c *fiber.Ctx) error {
+	token, err := utils.GetToken(c)
+	if err != nil {
+		return utils.JSONError(c, fiber.StatusUnauthorized, "Unauthorized")
+	}
+
+	ruleSet := c.Query("rule_set")
+	if ruleSet != "" && !validRuleSet(models.RuleSet(ruleSet)) {
+		return utils.JSONError(c, fiber.StatusBadRequest, "Invalid rule_set")
+	}
+
+	list, err := h.client.GetClassificationRules(token, ruleSet)
+	if err != nil {
+		return utils.JSONInternalError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"data":      list,
+		"loaded_at": h.engine.LoadedAt(),
+	})
+}
+
+// Create adds a rule and reloads the engine so it applies immediately
+func (h *RuleHandler) Create(c *fiber.Ctx) error {
+	token, err := utils.GetToken(c)
+	if err != nil {
+		return utils.JSONError(c, fiber.StatusUnauthorized, "Unauthorized")
+	}
+
+	user, err := utils.GetUser(c)
+	if err != nil {
+		return utils.JSONError(c, fiber.StatusUnauthorized, "Unauthorized")
+	}
+
+	var req models.CreateRuleRequest
+	if err := c.BodyParser(&req); err != nil {
+		slog.Warn("Invalid request body", "error", err)
+		return utils.JSONError(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	switch req.RuleSet {
+	case models.RuleSetCategory:
+		if req.CategoryID == nil || req.Priority != "" {
+			return utils.JSONError(c, fiber.StatusBadRequest, "category rules need category_id and no priority")
+		}
+	case models.RuleSetPriority:
+		if req.CategoryID != nil || !validRulePriority(req.Priority) {
+			return utils.JSONError(c, fiber.StatusBadRequest, "priority rules need priority critical, high or low and no category_id")
+		}
+	case models.RuleSetSpam, models.RuleSetOffensive:
+		if req.CategoryID != nil || req.Priority != "" {
+			return utils.JSONError(c, fiber.StatusBadRequest, "spam and offensive rules take no category_id or priority")
+		}
+	default:
+		return utils.JSONError(c, fiber.StatusBadRequest, "Invalid rule_set")
+	}
+	if req.Weight != nil && *req.Weight <= 0 {
+		return utils.JSONError(c, fiber.StatusBadRequest, "weight must be positive")
+	}
+
+	req.Pattern = strings.TrimSpace(req.Pattern)
+	if err := rules.Compile(models.ClassificationRule{RuleSet: req.RuleSet, Pattern: req.Pattern, IsRegex: req.IsRegex}); err != nil {
+		return utils.JSONError(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	rule, err := h.client.CreateClassificationRule(token, &req, user)
+	if err != nil {
+		if errors.Is(err, supabase.ErrRuleForbidden) {
+			return utils.JSONError(c, fiber.StatusForbidden, err.Error())
+		}
+		return utils.JSONInternalError(c, err)
+	}
+
+	h.reload()
+	return c.Status(fiber.StatusCreated).JSON(rule)
+}
+
+// Update changes a rule's pattern, weight or active flag
+func (h *RuleHandler) Update(c *fiber.Ctx) error {
+	token, err := utils.GetToken(c)
+	if err != nil {
+		return utils.JSONError(c, fiber.StatusUnauthorized, "Unauthorized")
+	}
+
+	user, err := utils.GetUser(c)
+	if err != nil {
+		return utils.JSONError(c, fiber.StatusUnauthorized, "Unauthorized")
+	}
+
+	var req models.UpdateRuleRequest
+	if err := c.BodyParser(&req); err != nil {
+		slog.Warn("Invalid request body", "error", err)
+		return utils.JSONError(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+	if req.Pattern == nil && req.IsRegex == nil && req.IsNegative == nil &&
+		req.Weight == nil && req.IsActive == nil && req.Description == nil {
+		return utils.JSONError(c, fiber.StatusBadRequest, "Nothing to update")
+	}
+	if req.Weight != nil && *req.Weight <= 0 {
+		return utils.JSONError(c, fiber.StatusBadRequest, "weight must be positive")
+	}
+
+	// The pattern must still compile with the regex flag it ends up with
+	if req.Pattern != nil || req.IsRegex != nil {
+		existing, err := h.client.GetClassificationRule(token, c.Params("id"))
+		if err != nil {
+			if errors.Is(err, supabase.ErrRuleNotFound) {
+				return utils.JSONError(c, fiber.StatusNotFound, err.Error())
+			}
+			return utils.JSONInternalError(c, err)
+		}
+		if req.Pattern != nil {
+			trimmed := strings.TrimSpace(*req.Pattern)
+			req.Pattern = &trimmed
+			existing.Pattern = trimmed
+		}
+		if req.IsRegex != nil {
+			existing.IsRegex = *req.IsRegex
+		}
+		if err := rules.Compile(*existing); err != nil {
+			return utils.JSONError(c, fiber.StatusBadRequest, err.Error())
+		}
+	}
+
+	rule, err := h.client.UpdateClassificationRule(token, c.Params("id"), &req, user)
+	if err != nil {
+		switch {
+		case errors.Is(err, supabase.ErrRuleForbidden):
+			return utils.JSONError(c, fiber.StatusForbidden, err.Error())
+		case errors.Is(err, supabase.ErrRuleNotFound):
+			return utils.JSONError(c, fiber.StatusNotFound, err.Error())
+		}
+		return utils.JSONInternalError(c, err)
+	}
+
+	h.reload()
+	return c.JSON(rule)
+}
+
+// Delete removes a rule
+func (h *RuleHandler) Delete(c *fiber.Ctx) error {
+	token, err := utils.GetToken(c)
+	if err != nil {
+		return utils.JSONError(c, fiber.StatusUnauthorized, "Unauthorized")
+	}
+
+	user, err := utils.GetUser(c)
+	if err != nil {
+		return utils.JSONError(c, fiber.StatusUnauthorized, "Unauthorized")
+	}
+
+	if err := h.client.DeleteClassificationRule(token, c.Params("id"), user); err != nil {
+		switch {
+		case errors.Is(err, supabase.ErrRuleForbidden):
+			return utils.JSONError(c, fiber.StatusForbidden, err.Error())
+		case errors.Is(err, supabase.ErrRuleNotFound):
+			return utils.JSONError(c, fiber.StatusNotFound, err.Error())
+		}
+		return utils.JSONInternalError(c, err)
+	}
+
+	h.reload()
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// DryRun shows which of the loaded rules fire for a text and the verdict
+// they produce, without classifying or saving anything
+func (h *RuleHandler) DryRun(c *fiber.Ctx) error {
+	var req struct {
+		Title       string `json:"title"`
+		Description string `json:"description"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		slog.Warn("Invalid request body", "error", err)
+		return utils.JSONError(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+	if strings.TrimSpace(req.Title+req.Description) == "" {
+		return utils.JSONError(c, fiber.StatusBadRequest, "title or description is required")
+	}
+
+	return c.JSON(h.engine.Evaluate(req.Title + " " + req.Description))
+}
+
+// reload applies a rule change on this instance right away; other instances
+// pick it up on their next periodic reload
+func (h *RuleHandler) reload() {
+	if err := h.engine.Reload(); err != nil {
+		slog.Warn("Failed to reload classification rules", "error", err)
+	}
+}
+
+func validRuleSet(ruleSet models.RuleSet) bool {
+	switch ruleSet {
+	case models.RuleSetCategory, models.RuleSetPriority, models.RuleSetSpam, models.RuleSetOffensive:
+		return true
+	}
+	return false
+}
+
+func validRulePriority(priority string) bool {
+	switch models.ComplaintPriority(priority) {
+	case models.PriorityCritical, models.PriorityHigh, models.PriorityLow:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/hakim/backend/internal/ai"
+	"github.com/hakim/backend/internal/models"
+	"github.com/hakim/backend/internal/utils"
+	"github.com/hakim/backend/pkg/supabase"
+)
+
+type UsageHandler struct {
+	client *supabase.Client
+	budget *ai.Budget
+}
+
+// NewUsageHandler creates the AI usage analytics handler; budget is nil when
+// no monthly budget is configured
+func NewUsageHandler(client *supabase.Client, budget *ai.Budget) *UsageHandler {
+	return &UsageHandler{client: client, budget: budget}
+}
+
+// GetAIUsage reports LLM tokens and cost per day, department and model,
+// with the month-to-date budget state
+func (h *UsageHandler) GetAIUsage(c *fiber.Ctx) error {
+	token, err := utils.GetToken(c)
+	if err != nil {
+		return utils.JSONError(c, fiber.StatusUnauthorized, "Unauthorized")
+	}
+
+	var since *time.Time
+	if days := c.QueryInt("days", 30); days > 0 {
+		from := time.Now().AddDate(0, 0, -days)
+		since = &from
+	}
+
+	report, err := h.client.GetAIUsage(token, since, c.Query("department_id"))
+	if err != nil {
+		return utils.JSONInternalError(c, err)
+	}
+
+	if h.budget != nil {
+		report.Budget = &models.AIBudgetInfo{
+			MonthlyLimitUSD: h.budget.Limit(),
+			SpentUSD:        h.budget.Spent(),
+			Mode:            string(h.budget.Mode()),
+		}
+	}
+
+	return c.JSON(report)
+}
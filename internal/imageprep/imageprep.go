@@ -0,0 +1,229 @@
+// Package imageprep fetches citizens' image attachments on the server and
+// prepares them for the LLM. Downloads are limited in size and type, the
+// image must decode, it is turned upright, scaled down and re-encoded as JPEG
+// (which drops EXIF and other metadata such as the GPS position) and is sent
+// inline as a base64 data URL, so the model never fetches citizen-supplied
+// URLs itself.
+package imageprep
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"io"
+	"mime"
+	"net/http"
+	"time"
+
+	"github.com/hakim/backend/internal/safeurl"
+
+	// Registered decoders for the accepted types
+	_ "image/gif"
+	_ "image/png"
+)
+
+var (
+	ErrTooLarge        = errors.New("image exceeds the size limit")
+	ErrUnsupportedType = errors.New("attachment is not a supported image type")
+	ErrUndecodable     = errors.New("attachment is not a decodable image")
+)
+
+// maxPixels rejects small files that decode to huge images
+const maxPixels = 50_000_000
+
+// jpegQuality is the quality images are re-encoded at
+const jpegQuality = 85
+
+// allowedTypes are the sniffed content types that are decoded
+var allowedTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+}
+
+type Options struct {
+	// MaxBytes is the largest download accepted
+	MaxBytes int64
+	// MaxDimension is the longest side after scaling; 0 keeps the size
+	MaxDimension int
+	// Timeout bounds each download
+	Timeout time.Duration
+	// Policy is the allowlist attachment URLs must match
+	Policy *safeurl.Policy
+	// Authorize adds credentials to requests for attachments in private
+	// storage. It is optional.
+	Authorize func(*http.Request)
+}
+
+type Preparer struct {
+	httpClient *http.Client
+	opts       Options
+}
+
+// Image is an attachment ready to be sent to the model
+type Image struct {
+	DataURL        string
+	Width          int
+	Height         int
+	OriginalWidth  int
+	OriginalHeight int
+}
+
+func New(opts Options) *Preparer {
+	return &Preparer{
+		httpClient: safeurl.NewHTTPClient(opts.Timeout),
+		opts:       opts,
+	}
+}
+
+// Prepare downloads an image and returns it scaled, stripped of metadata and
+// encoded as a data URL
+func (p *Preparer) Prepare(ctx context.Context, url string) (*Image, error) {
+	data, err := p.fetch(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+
+	// The sniffed type is trusted over what the server claims
+	contentType := http.DetectContentType(data)
+	if !allowedTypes[contentType] {
+		return nil, ErrUnsupportedType
+	}
+	// Read before re-encoding drops the EXIF data
+	rotation := 1
+	if contentType == "image/jpeg" {
+		rotation = orientation(data)
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUndecodable, err)
+	}
+	if config.Width*config.Height > maxPixels {
+		return nil, fmt.Errorf("%w: %dx%d pixels", ErrTooLarge, config.Width, config.Height)
+	}
+
+	decoded, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUndecodable, err)
+	}
+
+	scaled := fit(decoded, rotation, p.opts.MaxDimension)
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, scaled, &jpeg.Options{Quality: jpegQuality}); err != nil {
+		return nil, fmt.Errorf("failed to encode image: %w", err)
+	}
+
+	bounds := scaled.Bounds()
+	originalWidth, originalHeight := config.Width, config.Height
+	if rotation >= 5 {
+		originalWidth, originalHeight = originalHeight, originalWidth
+	}
+	return &Image{
+		DataURL:        "data:image/jpeg;base64," + base64.StdEncoding.EncodeToString(buf.Bytes()),
+		Width:          bounds.Dx(),
+		Height:         bounds.Dy(),
+		OriginalWidth:  originalWidth,
+		OriginalHeight: originalHeight,
+	}, nil
+}
+
+// fetch downloads at most MaxBytes, failing early on a declared size or type
+// that is out of bounds. URLs stored before they were validated on
+// submission are checked against the allowlist here too.
+func (p *Preparer) fetch(ctx context.Context, url string) ([]byte, error) {
+	if err := p.opts.Policy.Check(url); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid image URL: %w", err)
+	}
+	if p.opts.Authorize != nil {
+		p.opts.Authorize(req)
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download image: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("failed to download image: status %d", resp.StatusCode)
+	}
+	if resp.ContentLength > p.opts.MaxBytes {
+		return nil, fmt.Errorf("%w: %d bytes", ErrTooLarge, resp.ContentLength)
+	}
+	if contentType := resp.Header.Get("Content-Type"); contentType != "" {
+		mediaType, _, _ := mime.ParseMediaType(contentType)
+		if !allowedTypes[mediaType] && mediaType != "application/octet-stream" {
+			return nil, fmt.Errorf("%w: %s", ErrUnsupportedType, mediaType)
+		}
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, p.opts.MaxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to download image: %w", err)
+	}
+	if int64(len(data)) > p.opts.MaxBytes {
+		return nil, fmt.Errorf("%w: more than %d bytes", ErrTooLarge, p.opts.MaxBytes)
+	}
+	return data, nil
+}
+
+// fit turns src upright according to its EXIF orientation and scales it so
+// neither side exceeds maxDimension, averaging the source pixels each output
+// pixel covers. Transparent areas are flattened onto white, since JPEG has
+// no alpha channel.
+func fit(src image.Image, rotation, maxDimension int) *image.RGBA {
+	src = orient(src, rotation)
+	bounds := src.Bounds()
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+
+	w, h := srcW, srcH
+	if maxDimension > 0 && (w > maxDimension || h > maxDimension) {
+		if w >= h {
+			w, h = maxDimension, max(1, h*maxDimension/w)
+		} else {
+			w, h = max(1, w*maxDimension/h), maxDimension
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		y0 := bounds.Min.Y + y*srcH/h
+		y1 := max(y0+1, bounds.Min.Y+(y+1)*srcH/h)
+		for x := 0; x < w; x++ {
+			x0 := bounds.Min.X + x*srcW/w
+			x1 := max(x0+1, bounds.Min.X+(x+1)*srcW/w)
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r, g, b, a = r+uint64(cr), g+uint64(cg), b+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+
+			// Colors are alpha-premultiplied, so compositing over white adds
+			// the uncovered share of white to each channel
+			white := 0xffff - a/n
+			dst.SetRGBA(x, y, color.RGBA{
+				R: uint8((r/n + white) >> 8),
+				G: uint8((g/n + white) >> 8),
+				B: uint8((b/n + white) >> 8),
+				A: 0xff,
+			})
+		}
+	}
+	return dst
+}
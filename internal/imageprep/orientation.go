@@ -0,0 +1,134 @@
+package imageprep
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+)
+
+// exifOrientationTag is the TIFF tag holding how the camera was held
+const exifOrientationTag = 0x0112
+
+// orientation returns the EXIF Orientation of a JPEG, 1 to 8, or 1 when the
+// image has none. Phones store photos as the sensor saw them and record the
+// rotation here instead, so re-encoding without applying it (which also
+// drops the tag) leaves the photo sideways or upside down.
+func orientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+
+	for pos := 2; pos+4 <= len(data); {
+		if data[pos] != 0xFF {
+			return 1
+		}
+		marker := data[pos+1]
+		// Image data starts at the first scan; metadata comes before it
+		if marker == 0xDA || marker == 0xD9 {
+			return 1
+		}
+		length := int(binary.BigEndian.Uint16(data[pos+2:]))
+		if length < 2 || pos+2+length > len(data) {
+			return 1
+		}
+		segment := data[pos+4 : pos+2+length]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return tiffOrientation(segment[6:])
+		}
+		pos += 2 + length
+	}
+	return 1
+}
+
+// tiffOrientation reads the Orientation tag from the first IFD of a TIFF
+// structure
+func tiffOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return 1
+	}
+	entries := int(order.Uint16(tiff[ifd:]))
+	for i := 0; i < entries; i++ {
+		entry := ifd + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:]) != exifOrientationTag {
+			continue
+		}
+		value := int(order.Uint16(tiff[entry+8:]))
+		if value < 1 || value > 8 {
+			return 1
+		}
+		return value
+	}
+	return 1
+}
+
+// oriented presents an image as it should be displayed given its EXIF
+// orientation
+type oriented struct {
+	src         image.Image
+	orientation int
+}
+
+// orient wraps src so that reading it applies the orientation
+func orient(src image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return src
+	}
+	return &oriented{src: src, orientation: orientation}
+}
+
+func (o *oriented) ColorModel() color.Model {
+	return o.src.ColorModel()
+}
+
+func (o *oriented) Bounds() image.Rectangle {
+	b := o.src.Bounds()
+	// Orientations 5 to 8 turn the image a quarter
+	if o.orientation >= 5 {
+		return image.Rect(0, 0, b.Dy(), b.Dx())
+	}
+	return image.Rect(0, 0, b.Dx(), b.Dy())
+}
+
+func (o *oriented) At(x, y int) color.Color {
+	b := o.src.Bounds()
+	w, h := b.Dx(), b.Dy()
+
+	var sx, sy int
+	switch o.orientation {
+	case 2: // mirrored
+		sx, sy = w-1-x, y
+	case 3: // upside down
+		sx, sy = w-1-x, h-1-y
+	case 4: // mirrored upside down
+		sx, sy = x, h-1-y
+	case 5: // mirrored, turned counterclockwise
+		sx, sy = y, x
+	case 6: // turned counterclockwise, shown turned clockwise
+		sx, sy = y, h-1-x
+	case 7: // mirrored, turned clockwise
+		sx, sy = w-1-y, h-1-x
+	case 8: // turned clockwise, shown turned counterclockwise
+		sx, sy = w-1-y, x
+	default:
+		sx, sy = x, y
+	}
+	return o.src.At(b.Min.X+sx, b.Min.Y+sy)
+}
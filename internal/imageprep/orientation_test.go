@@ -0,0 +1,109 @@
+package imageprep
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+)
+
+// exifJPEG returns a small JPEG carrying an EXIF Orientation tag, or none
+// when orientation is 0
+func exifJPEG(t *testing.T, orientation uint16, order binary.ByteOrder) []byte {
+	t.Helper()
+
+	var encoded bytes.Buffer
+	if err := jpeg.Encode(&encoded, image.NewRGBA(image.Rect(0, 0, 4, 2)), nil); err != nil {
+		t.Fatalf("jpeg.Encode: %v", err)
+	}
+	if orientation == 0 {
+		return encoded.Bytes()
+	}
+
+	tiff := make([]byte, 8+2+12+4)
+	if order == binary.LittleEndian {
+		copy(tiff, "II")
+	} else {
+		copy(tiff, "MM")
+	}
+	order.PutUint16(tiff[2:], 42)
+	order.PutUint32(tiff[4:], 8)
+	order.PutUint16(tiff[8:], 1)
+	order.PutUint16(tiff[10:], exifOrientationTag)
+	order.PutUint16(tiff[12:], 3) // SHORT
+	order.PutUint32(tiff[14:], 1)
+	order.PutUint16(tiff[18:], orientation)
+
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	segment := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	segment = append(segment, payload...)
+
+	data := encoded.Bytes()
+	out := append([]byte{}, data[:2]...)
+	out = append(out, segment...)
+	return append(out, data[2:]...)
+}
+
+func TestOrientation(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want int
+	}{
+		{"no exif", exifJPEG(t, 0, binary.BigEndian), 1},
+		{"upright", exifJPEG(t, 1, binary.BigEndian), 1},
+		{"rotated, big endian", exifJPEG(t, 6, binary.BigEndian), 6},
+		{"rotated, little endian", exifJPEG(t, 8, binary.LittleEndian), 8},
+		{"upside down", exifJPEG(t, 3, binary.LittleEndian), 3},
+		{"out of range", exifJPEG(t, 9, binary.BigEndian), 1},
+		{"not a jpeg", []byte("\x89PNG\r\n\x1a\n"), 1},
+		{"truncated", []byte{0xFF, 0xD8, 0xFF, 0xE1, 0x10}, 1},
+		{"empty", nil, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := orientation(tt.data); got != tt.want {
+				t.Errorf("orientation() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFitAppliesOrientation(t *testing.T) {
+	red := color.RGBA{R: 0xff, A: 0xff}
+	blue := color.RGBA{B: 0xff, A: 0xff}
+
+	// A 2x1 image, red on the left and blue on the right
+	src := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	src.Set(0, 0, red)
+	src.Set(1, 0, blue)
+
+	tests := []struct {
+		orientation int
+		width       int
+		height      int
+		first       color.RGBA // top-left pixel after orienting
+	}{
+		{1, 2, 1, red},
+		{2, 2, 1, blue},
+		{3, 2, 1, blue},
+		{6, 1, 2, red},
+		{8, 1, 2, blue},
+	}
+
+	for _, tt := range tests {
+		got := fit(src, tt.orientation, 0)
+		bounds := got.Bounds()
+		if bounds.Dx() != tt.width || bounds.Dy() != tt.height {
+			t.Errorf("orientation %d: size %dx%d, want %dx%d", tt.orientation, bounds.Dx(), bounds.Dy(), tt.width, tt.height)
+			continue
+		}
+		if first := got.RGBAAt(0, 0); first != tt.first {
+			t.Errorf("orientation %d: top-left pixel %v, want %v", tt.orientation, first, tt.first)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+// Package knowledge finds knowledge base answers for citizens' information
+// requests, so they can be answered straight away instead of being handled
+// as complaints.
+package knowledge
+
+import (
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/hakim/backend/internal/arabic"
+	"github.com/hakim/backend/internal/config"
+	"github.com/hakim/backend/internal/models"
+	"github.com/hakim/backend/pkg/supabase"
+)
+
+// keywordWeight is what each keyword found in the text adds to an entry's score
+const keywordWeight = 0.2
+
+type Matcher struct {
+	client *supabase.Client
+}
+
+// Query is the information request to find answers for
+type Query struct {
+	DepartmentID uuid.UUID
+	CategoryID   uuid.UUID
+	Text         string
+}
+
+func NewMatcher(client *supabase.Client) *Matcher {
+	return &Matcher{client: client}
+}
+
+// Match returns the active entries of the department, and those for every
+// department, that answer the request well enough, best first
+func (m *Matcher) Match(q Query) ([]models.KnowledgeMatch, error) {
+	departmentID := ""
+	if q.DepartmentID != uuid.Nil {
+		departmentID = q.DepartmentID.String()
+	}
+
+	entries, err := m.client.GetKnowledgeEntries("", departmentID, true)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := config.AppConfig
+	matches := make([]models.KnowledgeMatch, 0)
+	for _, match := range Rank(entries, q.Text, q.CategoryID) {
+		if match.Score < cfg.KnowledgeMinScore || len(matches) == cfg.KnowledgeMaxAnswers {
+			break
+		}
+		matches = append(matches, match)
+	}
+
+	return matches, nil
+}
+
+// Rank scores entries against text, best first, dropping entries that share
+// nothing with it. An entry tied to a category is skipped for other
+// categories when categoryID is set.
+//
+// The score is the share of the entry's question words found in the text, in
+// whichever language matches better, plus keywordWeight per keyword found,
+// capped at 1.
+func Rank(entries []models.KnowledgeEntry, text string, categoryID uuid.UUID) []models.KnowledgeMatch {
+	prepared := arabic.NewText(text)
+	tokens := tokenize(text)
+
+	matches := make([]models.KnowledgeMatch, 0)
+	for _, entry := range entries {
+		if categoryID != uuid.Nil && entry.CategoryID != nil && *entry.CategoryID != categoryID {
+			continue
+		}
+
+		score := max(coverage(tokenize(entry.QuestionAr), tokens), coverage(tokenize(entry.Question), tokens))
+		for _, keyword := range entry.Keywords {
+			if prepared.Contains(keyword) {
+				score += keywordWeight
+			}
+		}
+		if score <= 0 {
+			continue
+		}
+
+		matches = append(matches, models.KnowledgeMatch{KnowledgeEntry: entry, Score: min(score, 1)})
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Score > matches[j].Score
+	})
+	return matches
+}
+
+// tokenize normalizes and stems text into a set of words, dropping
+// single-letter tokens
+func tokenize(text string) map[string]struct{} {
+	words := arabic.Tokens(text)
+
+	set := make(map[string]struct{}, len(words))
+	for _, word := range words {
+		if arabic.RuneLen(word) < 2 {
+			continue
+		}
+		set[word] = struct{}{}
+	}
+	return set
+}
+
+// coverage returns the share of the words of a that also appear in b
+func coverage(a, b map[string]struct{}) float64 {
+	if len(a) == 0 {
+		return 0
+	}
+
+	found := 0
+	for word := range a {
+		if _, ok := b[word]; ok {
+			found++
+		}
+	}
+	return float64(found) / float64(len(a))
+}
@@ -26,3 +26,44 @@ type DailyCount struct {
 	Date  string `json:"date"`
 	Count int    `json:"count"`
 }
+
+// AIUsage is LLM spend for one day, department and model
+type AIUsage struct {
+	Date             string  `json:"date"`
+	DepartmentID     string  `json:"department_id,omitempty"`
+	DepartmentName   string  `json:"department_name,omitempty"`
+	Model            string  `json:"model"`
+	Calls            int     `json:"calls"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	CostUSD          float64 `json:"cost_usd"`
+}
+
+// AIUsageReport is the LLM usage breakdown with totals and budget state
+type AIUsageReport struct {
+	Usage            []AIUsage     `json:"usage"`
+	Calls            int           `json:"calls"`
+	PromptTokens     int           `json:"prompt_tokens"`
+	CompletionTokens int           `json:"completion_tokens"`
+	CostUSD          float64       `json:"cost_usd"`
+	Budget           *AIBudgetInfo `json:"budget,omitempty"`
+}
+
+// AIBudgetInfo is the month-to-date spend against the configured budget
+type AIBudgetInfo struct {
+	MonthlyLimitUSD float64 `json:"monthly_limit_usd"`
+	SpentUSD        float64 `json:"spent_usd"`
+	Mode            string  `json:"mode"`
+}
+
+// CategoryDisagreement is how often the AI agreed with citizens who chose a
+// category
+type CategoryDisagreement struct {
+	CategoryID       string  `json:"category_id"`
+	CategoryName     string  `json:"category_name"`
+	Complaints       int     `json:"complaints"`
+	Agreed           int     `json:"agreed"`
+	CitizenKept      int     `json:"citizen_kept"`
+	Disputed         int     `json:"disputed"`
+	DisagreementRate float64 `json:"disagreement_rate"`
+}
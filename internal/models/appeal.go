@@ -0,0 +1,67 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Where an automatic rejection came from
+const (
+	RejectionSourcePreScreen = "pre_screen"
+	RejectionSourceLLM       = "llm"
+)
+
+type AppealStatus string
+
+const (
+	AppealPending    AppealStatus = "pending"
+	AppealUpheld     AppealStatus = "upheld"
+	AppealOverturned AppealStatus = "overturned"
+)
+
+// RejectionAppeal is a citizen's request to reconsider an automatic rejection
+type RejectionAppeal struct {
+	ID               uuid.UUID    `json:"id"`
+	ComplaintID      uuid.UUID    `json:"complaint_id"`
+	UserID           uuid.UUID    `json:"user_id"`
+	Statement        string       `json:"statement"`
+	Status           AppealStatus `json:"status"`
+	RejectionReason  string       `json:"rejection_reason,omitempty"`
+	RejectionSource  string       `json:"rejection_source,omitempty"`
+	RejectionRuleIDs []uuid.UUID  `json:"rejection_rule_ids,omitempty"`
+	ReviewedBy       *uuid.UUID   `json:"reviewed_by,omitempty"`
+	ReviewNote       string       `json:"review_note,omitempty"`
+	ReviewedAt       *time.Time   `json:"reviewed_at,omitempty"`
+	CreatedAt        time.Time    `json:"created_at"`
+
+	// Relations
+	Complaint *Complaint `json:"complaint,omitempty"`
+}
+
+type AppealRequest struct {
+	Statement string `json:"statement" validate:"required"`
+}
+
+// AppealDecision is a moderator's ruling on an appeal
+type AppealDecision struct {
+	Note string `json:"note,omitempty"`
+}
+
+// RejectionSourceStat summarizes appeals against one rejection source
+type RejectionSourceStat struct {
+	Source     string  `json:"source"`
+	Rejections int     `json:"rejections"`
+	Appeals    int     `json:"appeals"`
+	Upheld     int     `json:"upheld"`
+	Overturned int     `json:"overturned"`
+	AppealRate float64 `json:"appeal_rate"`
+	// OverturnRate is overturned over decided appeals
+	OverturnRate float64 `json:"overturn_rate"`
+}
+
+// AppealStats is the moderation overview used to tune the filters
+type AppealStats struct {
+	Sources []RejectionSourceStat `json:"sources"`
+	Rules   []RuleOverrideStat    `json:"rules"`
+}
@@ -0,0 +1,84 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type BulkAction string
+
+const (
+	BulkActionStatus   BulkAction = "status"
+	BulkActionAssign   BulkAction = "assign"
+	BulkActionPriority BulkAction = "priority"
+	BulkActionTag      BulkAction = "tag"
+	BulkActionTransfer BulkAction = "transfer"
+)
+
+type BulkJobStatus string
+
+const (
+	BulkJobQueued    BulkJobStatus = "queued"
+	BulkJobRunning   BulkJobStatus = "running"
+	BulkJobCompleted BulkJobStatus = "completed"
+	BulkJobFailed    BulkJobStatus = "failed"
+)
+
+// ComplaintFilter selects complaints for a bulk operation
+type ComplaintFilter struct {
+	Status       string `json:"status,omitempty"`
+	Priority     string `json:"priority,omitempty"`
+	DepartmentID string `json:"department_id,omitempty"`
+	CategoryID   string `json:"category_id,omitempty"`
+	CreatedFrom  string `json:"created_from,omitempty"`
+	CreatedTo    string `json:"created_to,omitempty"`
+}
+
+// IsEmpty reports whether the filter would match every complaint
+func (f *ComplaintFilter) IsEmpty() bool {
+	return f.Status == "" && f.Priority == "" && f.DepartmentID == "" &&
+		f.CategoryID == "" && f.CreatedFrom == "" && f.CreatedTo == ""
+}
+
+type BulkOperationRequest struct {
+	IDs        []uuid.UUID      `json:"ids,omitempty"`
+	Filter     *ComplaintFilter `json:"filter,omitempty"`
+	Action     BulkAction       `json:"action" validate:"required"`
+	Status     string           `json:"status,omitempty"`
+	Note       string           `json:"note,omitempty"`
+	AssigneeID string           `json:"assignee_id,omitempty"`
+	Priority   string           `json:"priority,omitempty"`
+	Tag        string           `json:"tag,omitempty"`
+	Transfer   *TransferRequest `json:"transfer,omitempty"`
+}
+
+// BulkItemResult is the outcome for a single complaint in a bulk operation
+type BulkItemResult struct {
+	ComplaintID string `json:"complaint_id"`
+	Success     bool   `json:"success"`
+	Error       string `json:"error,omitempty"`
+}
+
+type BulkResult struct {
+	Total     int              `json:"total"`
+	Succeeded int              `json:"succeeded"`
+	Failed    int              `json:"failed"`
+	Results   []BulkItemResult `json:"results"`
+}
+
+// BulkJob tracks a large bulk operation running in the background
+type BulkJob struct {
+	ID          uuid.UUID        `json:"id"`
+	Action      BulkAction       `json:"action"`
+	Status      BulkJobStatus    `json:"status"`
+	Total       int              `json:"total"`
+	Processed   int              `json:"processed"`
+	Succeeded   int              `json:"succeeded"`
+	Failed      int              `json:"failed"`
+	Results     []BulkItemResult `json:"results,omitempty"`
+	Error       string           `json:"error,omitempty"`
+	CreatedBy   uuid.UUID        `json:"created_by"`
+	CreatedAt   time.Time        `json:"created_at"`
+	CompletedAt *time.Time       `json:"completed_at,omitempty"`
+}
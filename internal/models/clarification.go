@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Where clarifying questions came from
+const (
+	ClarificationSourcePreScreen = "pre_screen"
+	ClarificationSourceLLM       = "llm"
+)
+
+// ClarificationQuestion is a follow-up question put to the citizen when a
+// complaint is too vague to classify
+type ClarificationQuestion struct {
+	ID          uuid.UUID  `json:"id"`
+	ComplaintID uuid.UUID  `json:"complaint_id"`
+	Round       int        `json:"round"`
+	Question    string     `json:"question"`
+	Answer      string     `json:"answer,omitempty"`
+	Source      string     `json:"source"`
+	AnsweredAt  *time.Time `json:"answered_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+type ClarificationAnswer struct {
+	QuestionID uuid.UUID `json:"question_id"`
+	Answer     string    `json:"answer"`
+}
+
+type AnswerClarificationsRequest struct {
+	Answers []ClarificationAnswer `json:"answers" validate:"required"`
+}
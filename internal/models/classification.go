@@ -0,0 +1,53 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AIClassificationRecord is the full output of one classification run,
+// shown to staff on complaint detail
+type AIClassificationRecord struct {
+	ID            uuid.UUID  `json:"id"`
+	ComplaintID   uuid.UUID  `json:"complaint_id"`
+	Source        string     `json:"source"`
+	Model         string     `json:"model,omitempty"`
+	CategoryID    *uuid.UUID `json:"category_id,omitempty"`
+	CategoryName  string     `json:"category_name,omitempty"`
+	Priority      string     `json:"priority,omitempty"`
+	Confidence    float64    `json:"confidence"`
+	Summary       string     `json:"summary,omitempty"`
+	Sentiment     string     `json:"sentiment,omitempty"`
+	ImageAnalysis string     `json:"image_analysis,omitempty"`
+	Tags          []string   `json:"tags,omitempty"`
+	LatencyMs     int64      `json:"latency_ms"`
+	RawResponse   string     `json:"raw_response,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	// ManipulationSignals lists why the result may have been steered by
+	// instructions hidden in the complaint text
+	ManipulationSignals []string `json:"manipulation_signals,omitempty"`
+	// Cached is set when the LLM verdict was reused for identical content
+	Cached bool `json:"cached,omitempty"`
+	// VoiceTranscriptUsed is set when voice note transcripts were classified
+	// along with the written description
+	VoiceTranscriptUsed bool `json:"voice_transcript_used,omitempty"`
+
+	SecondOpinion *ClassificationSecondOpinion `json:"second_opinion,omitempty"`
+	Redaction     *ClassificationRedaction     `json:"redaction,omitempty"`
+}
+
+// ClassificationRedaction lists the kinds of personal data removed before
+// the LLM call and how many of each were found
+type ClassificationRedaction struct {
+	Kinds  []string       `json:"kinds"`
+	Counts map[string]int `json:"counts"`
+}
+
+// ClassificationSecondOpinion is the local model's category for an LLM run
+type ClassificationSecondOpinion struct {
+	CategoryID *uuid.UUID `json:"category_id,omitempty"`
+	Confidence float64    `json:"confidence"`
+	Model      string     `json:"model,omitempty"`
+	Disagrees  bool       `json:"disagrees"`
+}
@@ -27,33 +27,98 @@ const (
 	PriorityCritical ComplaintPriority = "critical"
 )
 
+// How the AI category was reconciled with the citizen's choice
+const (
+	CategoryAIOnly      = "ai_only"      // the citizen chose no category
+	CategoryAgreed      = "agreed"       // both chose the same category
+	CategoryCitizenKept = "citizen_kept" // the AI disagreed without enough confidence
+	CategoryDisputed    = "disputed"     // the AI confidently disagreed; sent to triage
+)
+
 type Complaint struct {
-	ID                 uuid.UUID         `json:"id"`
-	TrackingNumber     string            `json:"tracking_number"`
-	UserID             uuid.UUID         `json:"user_id"`
-	CategoryID         uuid.UUID         `json:"category_id"`
-	DepartmentID       uuid.UUID         `json:"department_id"`
-	AssignedTo         *uuid.UUID        `json:"assigned_to,omitempty"`
-	Title              string            `json:"title"`
-	Description        string            `json:"description"`
-	Status             ComplaintStatus   `json:"status"`
-	Priority           ComplaintPriority `json:"priority"`
-	Latitude           *float64          `json:"latitude,omitempty"`
-	Longitude          *float64          `json:"longitude,omitempty"`
-	Address            string            `json:"address,omitempty"`
-	AISummary          string            `json:"ai_summary,omitempty"`
-	AIClassification   string            `json:"ai_classification,omitempty"`
-	AIConfidence       float64           `json:"ai_confidence"`
-	ExpectedResolution *time.Time        `json:"expected_resolution,omitempty"`
-	ResolvedAt         *time.Time        `json:"resolved_at,omitempty"`
-	CreatedAt          time.Time         `json:"created_at"`
-	UpdatedAt          time.Time         `json:"updated_at"`
+	ID                   uuid.UUID         `json:"id"`
+	TrackingNumber       string            `json:"tracking_number"`
+	UserID               uuid.UUID         `json:"user_id"`
+	CategoryID           uuid.UUID         `json:"category_id"`
+	DepartmentID         uuid.UUID         `json:"department_id"`
+	AssignedTo           *uuid.UUID        `json:"assigned_to,omitempty"`
+	Title                string            `json:"title"`
+	Description          string            `json:"description"`
+	Status               ComplaintStatus   `json:"status"`
+	Priority             ComplaintPriority `json:"priority"`
+	Latitude             *float64          `json:"latitude,omitempty"`
+	Longitude            *float64          `json:"longitude,omitempty"`
+	Address              string            `json:"address,omitempty"`
+	AISummary            string            `json:"ai_summary,omitempty"`
+	AIClassification     string            `json:"ai_classification,omitempty"`
+	AIConfidence         float64           `json:"ai_confidence"`
+	AIPriority           string            `json:"ai_priority,omitempty"`
+	AISentiment          string            `json:"ai_sentiment,omitempty"`
+	AITags               []string          `json:"ai_tags,omitempty"`
+	AISource             string            `json:"ai_source,omitempty"`
+	ClassificationStatus string            `json:"classification_status,omitempty"`
+	// The citizen's own category choice and the AI's, kept apart from the
+	// category the complaint is routed to
+	CitizenCategoryID      *uuid.UUID  `json:"citizen_category_id,omitempty"`
+	AICategoryID           *uuid.UUID  `json:"ai_category_id,omitempty"`
+	CategoryReconciliation string      `json:"category_reconciliation,omitempty"`
+	RejectionReason        string      `json:"rejection_reason,omitempty"`
+	RejectionSource        string      `json:"rejection_source,omitempty"`
+	RejectionRuleIDs       []uuid.UUID `json:"rejection_rule_ids,omitempty"`
+	ScreeningOverridden    bool        `json:"screening_overridden,omitempty"`
+	DeflectionDeclined     bool        `json:"deflection_declined,omitempty"`
+	SuggestedDuplicate     *uuid.UUID  `json:"suggested_duplicate_of,omitempty"`
+	MergedInto             *uuid.UUID  `json:"merged_into,omitempty"`
+	EndorsementCount       int         `json:"endorsement_count"`
+	ExpectedResolution     *time.Time  `json:"expected_resolution,omitempty"`
+	ResolvedAt             *time.Time  `json:"resolved_at,omitempty"`
+	CreatedAt              time.Time   `json:"created_at"`
+	UpdatedAt              time.Time   `json:"updated_at"`
 
 	// Relations
 	Category   *Category   `json:"category,omitempty"`
 	Department *Department `json:"department,omitempty"`
 	User       *Profile    `json:"user,omitempty"`
 	Assignee   *Profile    `json:"assignee,omitempty"`
+
+	// Set on creation when a similar report already exists nearby
+	SimilarReport *SimilarReport `json:"similar_report,omitempty"`
+
+	// Full classification output (staff only)
+	AIDetails *AIClassificationRecord `json:"ai_details,omitempty"`
+}
+
+// SimilarReport tells a citizen that what they reported looks like an
+// incident someone else already reported nearby. Only the ID needed to
+// endorse it is shared; the other report's details are for staff.
+type SimilarReport struct {
+	IncidentID uuid.UUID `json:"incident_id"`
+}
+
+// DuplicateCandidate is an existing complaint that looks like the same
+// incident. It carries the other complaint's details and is shown to staff
+// only.
+type DuplicateCandidate struct {
+	ComplaintID    uuid.UUID       `json:"complaint_id"`
+	TrackingNumber string          `json:"tracking_number"`
+	Title          string          `json:"title"`
+	Status         ComplaintStatus `json:"status"`
+	DistanceMeters *float64        `json:"distance_meters,omitempty"`
+	Similarity     float64         `json:"similarity"`
+	Score          float64         `json:"score"`
+	CreatedAt      time.Time       `json:"created_at"`
+}
+
+type MergeComplaintsRequest struct {
+	DuplicateIDs []uuid.UUID `json:"duplicate_ids" validate:"required"`
+	Note         string      `json:"note,omitempty"`
+}
+
+// MergeResult reports which duplicates were linked to the master complaint
+type MergeResult struct {
+	Master *Complaint  `json:"master"`
+	Merged []uuid.UUID `json:"merged"`
+	Failed []string    `json:"failed,omitempty"`
 }
 
 type CreateComplaintRequest struct {
@@ -64,6 +129,8 @@ type CreateComplaintRequest struct {
 	Longitude   *float64  `json:"longitude,omitempty"`
 	Address     string    `json:"address,omitempty"`
 	Attachments []string  `json:"attachments,omitempty"`
+	// VoiceNotes are recorded audio attachments, transcribed in the background
+	VoiceNotes []string `json:"voice_notes,omitempty"`
 }
 
 type UpdateComplaintRequest struct {
@@ -84,15 +151,40 @@ type StatusHistory struct {
 }
 
 type ComplaintAttachment struct {
-	ID          uuid.UUID `json:"id"`
-	ComplaintID uuid.UUID `json:"complaint_id"`
-	FileName    string    `json:"file_name"`
-	FileURL     string    `json:"file_url"`
-	FileType    string    `json:"file_type"`
-	FileSize    int64     `json:"file_size"`
-	CreatedAt   time.Time `json:"created_at"`
+	ID            uuid.UUID `json:"id"`
+	ComplaintID   uuid.UUID `json:"complaint_id"`
+	FileName      string    `json:"file_name"`
+	FileURL       string    `json:"file_url"`
+	FileType      string    `json:"file_type"`
+	FileSize      int64     `json:"file_size"`
+	MimeType      string    `json:"mime_type,omitempty"`
+	AILabels      []string  `json:"ai_labels,omitempty"`
+	AIDescription string    `json:"ai_description,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+
+	// Voice notes only
+	Transcript                string     `json:"transcript,omitempty"`
+	TranscriptLanguage        string     `json:"transcript_language,omitempty"`
+	TranscriptDurationSeconds *float64   `json:"transcript_duration_seconds,omitempty"`
+	Transcriber               string     `json:"transcriber,omitempty"`
+	TranscriptionStatus       string     `json:"transcription_status,omitempty"`
+	TranscriptionError        string     `json:"transcription_error,omitempty"`
+	TranscribedAt             *time.Time `json:"transcribed_at,omitempty"`
 }
 
+// Attachment file types
+const (
+	AttachmentImage    = "image"
+	AttachmentVoice    = "voice"
+	AttachmentDocument = "document"
+)
+
+// Outcomes of transcribing a voice note
+const (
+	TranscriptionCompleted = "completed"
+	TranscriptionFailed    = "failed"
+)
+
 type Feedback struct {
 	ID          uuid.UUID `json:"id"`
 	ComplaintID uuid.UUID `json:"complaint_id"`
@@ -101,3 +193,18 @@ type Feedback struct {
 	Comment     string    `json:"comment,omitempty"`
 	CreatedAt   time.Time `json:"created_at"`
 }
+
+// Endorsement records a citizen saying "me too" on an existing incident
+type Endorsement struct {
+	ID          uuid.UUID `json:"id"`
+	ComplaintID uuid.UUID `json:"complaint_id"`
+	UserID      uuid.UUID `json:"user_id"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type EndorsementResult struct {
+	ComplaintID      uuid.UUID         `json:"complaint_id"`
+	Endorsed         bool              `json:"endorsed"`
+	EndorsementCount int               `json:"endorsement_count"`
+	Priority         ComplaintPriority `json:"priority"`
+}
@@ -0,0 +1,62 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DraftKind is what an AI draft was written for
+type DraftKind string
+
+const (
+	// DraftReply is an update to the citizen about their complaint
+	DraftReply DraftKind = "reply"
+	// DraftResolutionSummary is the record written when a complaint is closed
+	DraftResolutionSummary DraftKind = "resolution_summary"
+)
+
+// ResolutionSummary is the structured account of how a complaint was resolved
+type ResolutionSummary struct {
+	Problem        string   `json:"problem"`
+	ActionsTaken   []string `json:"actions_taken"`
+	Outcome        string   `json:"outcome"`
+	RootCause      string   `json:"root_cause"`
+	FollowUp       string   `json:"follow_up"`
+	CitizenMessage string   `json:"citizen_message"`
+}
+
+// AIDraft is a text generated for an employee, kept with what they finally
+// sent so the drafts can be reviewed for quality
+type AIDraft struct {
+	ID           uuid.UUID          `json:"id"`
+	ComplaintID  uuid.UUID          `json:"complaint_id"`
+	Kind         DraftKind          `json:"kind"`
+	Instructions string             `json:"instructions,omitempty"`
+	DraftText    string             `json:"draft_text"`
+	Summary      *ResolutionSummary `json:"summary,omitempty"`
+	Model        string             `json:"model,omitempty"`
+	FinalText    string             `json:"final_text,omitempty"`
+	Edited       bool               `json:"edited"`
+	CreatedBy    uuid.UUID          `json:"created_by"`
+	FinalizedBy  *uuid.UUID         `json:"finalized_by,omitempty"`
+	FinalizedAt  *time.Time         `json:"finalized_at,omitempty"`
+	CreatedAt    time.Time          `json:"created_at"`
+}
+
+// DraftRequest carries the employee's notes: what a reply should say, or
+// what was done to resolve the complaint
+type DraftRequest struct {
+	Instructions string `json:"instructions,omitempty"`
+}
+
+// FinalizeDraftRequest records the text the employee actually sent. When
+// Status is set the complaint moves to it with the text as the status note.
+type FinalizeDraftRequest struct {
+	FinalText string `json:"final_text" validate:"required"`
+	Status    string `json:"status,omitempty"`
+}
+
+type ReplyGuidelinesRequest struct {
+	Guidelines string `json:"guidelines"`
+}
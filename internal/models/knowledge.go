@@ -0,0 +1,78 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// KnowledgeEntry is a managed answer to a common information request. An
+// entry without a department applies to every department.
+type KnowledgeEntry struct {
+	ID           uuid.UUID  `json:"id"`
+	DepartmentID *uuid.UUID `json:"department_id,omitempty"`
+	CategoryID   *uuid.UUID `json:"category_id,omitempty"`
+	Question     string     `json:"question"`
+	QuestionAr   string     `json:"question_ar"`
+	Answer       string     `json:"answer"`
+	AnswerAr     string     `json:"answer_ar"`
+	URL          string     `json:"url,omitempty"`
+	Keywords     []string   `json:"keywords,omitempty"`
+	IsActive     bool       `json:"is_active"`
+	CreatedBy    *uuid.UUID `json:"created_by,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+type CreateKnowledgeEntryRequest struct {
+	DepartmentID *uuid.UUID `json:"department_id,omitempty"`
+	CategoryID   *uuid.UUID `json:"category_id,omitempty"`
+	Question     string     `json:"question" validate:"required"`
+	QuestionAr   string     `json:"question_ar" validate:"required"`
+	Answer       string     `json:"answer" validate:"required"`
+	AnswerAr     string     `json:"answer_ar" validate:"required"`
+	URL          string     `json:"url,omitempty"`
+	Keywords     []string   `json:"keywords,omitempty"`
+}
+
+type UpdateKnowledgeEntryRequest struct {
+	CategoryID *uuid.UUID `json:"category_id,omitempty"`
+	Question   *string    `json:"question,omitempty"`
+	QuestionAr *string    `json:"question_ar,omitempty"`
+	Answer     *string    `json:"answer,omitempty"`
+	AnswerAr   *string    `json:"answer_ar,omitempty"`
+	URL        *string    `json:"url,omitempty"`
+	Keywords   []string   `json:"keywords,omitempty"`
+	IsActive   *bool      `json:"is_active,omitempty"`
+}
+
+// KnowledgeMatch is an entry that answers a complaint, with how well it matched
+type KnowledgeMatch struct {
+	KnowledgeEntry
+	Score float64 `json:"score"`
+}
+
+// ComplaintDeflection records an information request answered from the
+// knowledge base instead of being handled as a complaint
+type ComplaintDeflection struct {
+	ID           uuid.UUID        `json:"id"`
+	ComplaintID  uuid.UUID        `json:"complaint_id"`
+	DepartmentID *uuid.UUID       `json:"department_id,omitempty"`
+	EntryIDs     []uuid.UUID      `json:"entry_ids"`
+	Entries      []KnowledgeEntry `json:"entries,omitempty"`
+	Escalated    bool             `json:"escalated"`
+	EscalatedAt  *time.Time       `json:"escalated_at,omitempty"`
+	CreatedAt    time.Time        `json:"created_at"`
+}
+
+// DeflectionStat is how many of a department's complaints were answered from
+// the knowledge base, and how many of those the citizen escalated anyway
+type DeflectionStat struct {
+	DepartmentID   string  `json:"department_id"`
+	DepartmentName string  `json:"department_name"`
+	Complaints     int     `json:"complaints"`
+	Deflected      int     `json:"deflected"`
+	Escalated      int     `json:"escalated"`
+	DeflectionRate float64 `json:"deflection_rate"`
+	EscalationRate float64 `json:"escalation_rate"`
+}
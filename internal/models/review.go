@@ -0,0 +1,53 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type ReviewStatus string
+
+const (
+	ReviewPending   ReviewStatus = "pending"
+	ReviewConfirmed ReviewStatus = "confirmed"
+	ReviewCorrected ReviewStatus = "corrected"
+)
+
+// Reasons a classification is sent for human review
+const (
+	ReviewReasonLowConfidence = "low_confidence"
+	ReviewReasonFallback      = "fallback"
+	ReviewReasonDisagreement  = "model_disagreement"
+	ReviewReasonManipulation  = "suspected_manipulation"
+	ReviewReasonCategory      = "category_disagreement"
+)
+
+// ClassificationReview is a classification waiting for staff to confirm or correct it
+type ClassificationReview struct {
+	ID                  uuid.UUID    `json:"id"`
+	ComplaintID         uuid.UUID    `json:"complaint_id"`
+	Reason              string       `json:"reason"`
+	Status              ReviewStatus `json:"status"`
+	PredictedCategoryID *uuid.UUID   `json:"predicted_category_id,omitempty"`
+	PredictedPriority   string       `json:"predicted_priority,omitempty"`
+	PredictedConfidence float64      `json:"predicted_confidence"`
+	PredictedSource     string       `json:"predicted_source,omitempty"`
+	FinalCategoryID     *uuid.UUID   `json:"final_category_id,omitempty"`
+	FinalPriority       string       `json:"final_priority,omitempty"`
+	ReviewedBy          *uuid.UUID   `json:"reviewed_by,omitempty"`
+	ReviewNote          string       `json:"review_note,omitempty"`
+	ReviewedAt          *time.Time   `json:"reviewed_at,omitempty"`
+	CreatedAt           time.Time    `json:"created_at"`
+
+	// Relations
+	Complaint *Complaint `json:"complaint,omitempty"`
+}
+
+// ReviewCorrection is a staff decision on a review. Empty fields keep the
+// predicted value.
+type ReviewCorrection struct {
+	CategoryID *uuid.UUID `json:"category_id,omitempty"`
+	Priority   string     `json:"priority,omitempty"`
+	Note       string     `json:"note,omitempty"`
+}
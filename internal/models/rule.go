@@ -0,0 +1,93 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RuleSet groups classification rules by what they decide
+type RuleSet string
+
+const (
+	RuleSetCategory  RuleSet = "category"
+	RuleSetPriority  RuleSet = "priority"
+	RuleSetSpam      RuleSet = "spam"
+	RuleSetOffensive RuleSet = "offensive"
+)
+
+// ClassificationRule is an admin-managed keyword or regex used by the keyword
+// classifier and the junk filter. A negative rule that fires rules out its
+// target category or priority instead of voting for it.
+type ClassificationRule struct {
+	ID          uuid.UUID  `json:"id"`
+	RuleSet     RuleSet    `json:"rule_set"`
+	CategoryID  *uuid.UUID `json:"category_id,omitempty"`
+	Priority    string     `json:"priority,omitempty"`
+	Pattern     string     `json:"pattern"`
+	IsRegex     bool       `json:"is_regex"`
+	IsNegative  bool       `json:"is_negative"`
+	Weight      float64    `json:"weight"`
+	IsActive    bool       `json:"is_active"`
+	Description string     `json:"description,omitempty"`
+	CreatedBy   *uuid.UUID `json:"created_by,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+type CreateRuleRequest struct {
+	RuleSet     RuleSet    `json:"rule_set" validate:"required"`
+	CategoryID  *uuid.UUID `json:"category_id,omitempty"`
+	Priority    string     `json:"priority,omitempty"`
+	Pattern     string     `json:"pattern" validate:"required"`
+	IsRegex     bool       `json:"is_regex"`
+	IsNegative  bool       `json:"is_negative"`
+	Weight      *float64   `json:"weight,omitempty"`
+	Description string     `json:"description,omitempty"`
+}
+
+type UpdateRuleRequest struct {
+	Pattern     *string  `json:"pattern,omitempty"`
+	IsRegex     *bool    `json:"is_regex,omitempty"`
+	IsNegative  *bool    `json:"is_negative,omitempty"`
+	Weight      *float64 `json:"weight,omitempty"`
+	IsActive    *bool    `json:"is_active,omitempty"`
+	Description *string  `json:"description,omitempty"`
+}
+
+// RuleMatch is one rule that fired on a text
+type RuleMatch struct {
+	RuleID     *uuid.UUID `json:"rule_id,omitempty"` // nil for built-in defaults
+	RuleSet    RuleSet    `json:"rule_set"`
+	CategoryID *uuid.UUID `json:"category_id,omitempty"`
+	Priority   string     `json:"priority,omitempty"`
+	Pattern    string     `json:"pattern"`
+	IsNegative bool       `json:"is_negative"`
+	Weight     float64    `json:"weight"`
+	// Demoted rules are overturned so often on appeal that they no longer
+	// reject complaints on their own
+	Demoted bool `json:"demoted,omitempty"`
+}
+
+// RuleOverrideStat is how often rejections caused by a screening rule were
+// appealed and overturned
+type RuleOverrideStat struct {
+	RuleID     uuid.UUID `json:"rule_id"`
+	Pattern    string    `json:"pattern,omitempty"`
+	RuleSet    RuleSet   `json:"rule_set,omitempty"`
+	Rejections int       `json:"rejections"`
+	Appeals    int       `json:"appeals"`
+	Upheld     int       `json:"upheld"`
+	Overturned int       `json:"overturned"`
+	Demoted    bool      `json:"demoted"`
+}
+
+// RuleEvaluation is the combined verdict of all rules on a text
+type RuleEvaluation struct {
+	Matches          []RuleMatch        `json:"matches"`
+	CategoryScores   map[string]float64 `json:"category_scores"`
+	VetoedCategories []string           `json:"vetoed_categories"`
+	Priority         string             `json:"priority,omitempty"`
+	Spam             bool               `json:"spam"`
+	Offensive        bool               `json:"offensive"`
+}
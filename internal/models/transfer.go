@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type TransferStatus string
+
+const (
+	TransferPending   TransferStatus = "pending"
+	TransferCompleted TransferStatus = "completed"
+	TransferRejected  TransferStatus = "rejected"
+)
+
+// ComplaintTransfer records a complaint moving between departments
+type ComplaintTransfer struct {
+	ID               uuid.UUID      `json:"id"`
+	ComplaintID      uuid.UUID      `json:"complaint_id"`
+	FromDepartmentID *uuid.UUID     `json:"from_department_id,omitempty"`
+	ToDepartmentID   uuid.UUID      `json:"to_department_id"`
+	ToCategoryID     *uuid.UUID     `json:"to_category_id,omitempty"`
+	Reason           string         `json:"reason"`
+	Status           TransferStatus `json:"status"`
+	RequestedBy      *uuid.UUID     `json:"requested_by,omitempty"`
+	RespondedBy      *uuid.UUID     `json:"responded_by,omitempty"`
+	ResponseNote     string         `json:"response_note,omitempty"`
+	RespondedAt      *time.Time     `json:"responded_at,omitempty"`
+	CreatedAt        time.Time      `json:"created_at"`
+}
+
+type TransferRequest struct {
+	ToDepartmentID    uuid.UUID  `json:"to_department_id" validate:"required"`
+	ToCategoryID      *uuid.UUID `json:"to_category_id,omitempty"`
+	Reason            string     `json:"reason" validate:"required"`
+	RequireAcceptance *bool      `json:"require_acceptance,omitempty"`
+}
+
+// TransferPairCount counts transfers between two departments
+type TransferPairCount struct {
+	FromDepartmentID   string `json:"from_department_id"`
+	FromDepartmentName string `json:"from_department_name"`
+	ToDepartmentID     string `json:"to_department_id"`
+	ToDepartmentName   string `json:"to_department_name"`
+	Total              int    `json:"total"`
+	Completed          int    `json:"completed"`
+	Rejected           int    `json:"rejected"`
+	Pending            int    `json:"pending"`
+}
@@ -0,0 +1,460 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hakim/backend/internal/ai"
+	"github.com/hakim/backend/internal/arabic"
+	"github.com/hakim/backend/internal/config"
+	"github.com/hakim/backend/internal/dedup"
+	"github.com/hakim/backend/internal/imageprep"
+	"github.com/hakim/backend/internal/knowledge"
+	"github.com/hakim/backend/internal/models"
+	"github.com/hakim/backend/internal/transcribe"
+	"github.com/hakim/backend/pkg/supabase"
+)
+
+// jobTimeout bounds the downloads and transcriptions of one classification
+// job. Together with the model calls it must stay well inside the 20 minute
+// lock window of claim_classification_jobs, after which another worker may
+// claim the job.
+const jobTimeout = 8 * time.Minute
+
+// retryBackoff is the delay before a failed job is retried, multiplied by the attempt number
+const retryBackoff = 30 * time.Second
+
+// Classifier runs queued complaint classifications on a pool of workers.
+// The queue lives in the database, so jobs survive restarts and can be shared
+// between server instances.
+type Classifier struct {
+	client     *supabase.Client
+	classifier *ai.Classifier
+	detector   *dedup.Detector
+	matcher    *knowledge.Matcher
+	images     *imageprep.Preparer
+	voice      *transcribe.Service
+	workerID   string
+	wg         sync.WaitGroup
+}
+
+// NewClassifier builds the pipeline. voice may be nil, in which case voice
+// notes are not transcribed.
+func NewClassifier(client *supabase.Client, classifier *ai.Classifier, detector *dedup.Detector, matcher *knowledge.Matcher, images *imageprep.Preparer, voice *transcribe.Service) *Classifier {
+	host, _ := os.Hostname()
+	return &Classifier{
+		client:     client,
+		classifier: classifier,
+		detector:   detector,
+		matcher:    matcher,
+		images:     images,
+		voice:      voice,
+		workerID:   fmt.Sprintf("%s-%d", host, os.Getpid()),
+	}
+}
+
+// Enqueue schedules a saved complaint for classification
+func (p *Classifier) Enqueue(complaintID string) error {
+	return p.client.EnqueueClassification(complaintID)
+}
+
+// Start launches the worker pool. Workers stop when ctx is cancelled; Wait
+// blocks until in-flight jobs finish.
+func (p *Classifier) Start(ctx context.Context) {
+	workers := config.AppConfig.ClassificationWorkers
+	if workers < 1 {
+		workers = 1
+	}
+	interval := time.Duration(config.AppConfig.ClassificationPollSeconds) * time.Second
+	if interval <= 0 {
+		interval = 2 * time.Second
+	}
+
+	for i := 0; i < workers; i++ {
+		p.wg.Add(1)
+		go p.work(ctx, fmt.Sprintf("%s/%d", p.workerID, i), interval)
+	}
+
+	slog.Info("Classification workers started", "workers", workers, "poll_interval", interval)
+}
+
+// Wait blocks until all workers have exited
+func (p *Classifier) Wait() {
+	p.wg.Wait()
+}
+
+func (p *Classifier) work(ctx context.Context, workerID string, interval time.Duration) {
+	defer p.wg.Done()
+
+	for {
+		jobs, err := p.client.ClaimClassificationJobs(workerID, 1)
+		if err != nil {
+			slog.Warn("Failed to claim classification jobs", "worker", workerID, "error", err)
+		}
+
+		if len(jobs) > 0 {
+			p.process(ctx, &jobs[0])
+			// Look for more work straight away while the queue is non-empty
+			if ctx.Err() != nil {
+				return
+			}
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+func (p *Classifier) process(ctx context.Context, job *supabase.ClassificationJob) {
+	start := time.Now()
+
+	// A job already running finishes at shutdown instead of being cut short,
+	// bounded by its own timeout
+	jobCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), jobTimeout)
+	defer cancel()
+
+	if err := p.run(jobCtx, job); err != nil {
+		p.fail(job, err)
+		return
+	}
+
+	if err := p.client.CompleteClassificationJob(job.ID); err != nil {
+		slog.Warn("Failed to mark classification job done", "job_id", job.ID, "error", err)
+	}
+
+	slog.Info("Complaint classified", "complaint_id", job.ComplaintID,
+		"attempt", job.Attempts, "duration", time.Since(start))
+}
+
+// run classifies one complaint and applies the result. Results are pushed to
+// the citizen through the complaint row and a notification, both of which
+// clients receive over Supabase Realtime.
+func (p *Classifier) run(ctx context.Context, job *supabase.ClassificationJob) error {
+	complaint, err := p.client.GetComplaintSystem(job.ComplaintID)
+	if err != nil {
+		return err
+	}
+	if complaint.ClassificationStatus == supabase.ClassificationCompleted {
+		return nil
+	}
+
+	_ = p.client.SetClassificationStatus(job.ComplaintID, supabase.ClassificationProcessing)
+
+	attachments, err := p.client.GetImageAttachments(job.ComplaintID)
+	if err != nil {
+		slog.Warn("Failed to load attachments for classification", "complaint_id", job.ComplaintID, "error", err)
+	}
+	sent, imageURLs := p.prepareImages(ctx, job.ComplaintID, attachments)
+	// Images skipped for lack of time are not a reason to classify without
+	// them; the job is retried instead
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// A short description is classified together with what the citizen said
+	description, transcribed, err := p.describe(ctx, complaint)
+	if err != nil {
+		return err
+	}
+
+	// A complaint reinstated on appeal is not screened again, and one whose
+	// citizen has answered every round of questions is not asked more
+	classify := p.classifier.ClassifyWithImages
+	if complaint.ScreeningOverridden {
+		classify = p.classifier.ClassifyAccepted
+	} else {
+		rounds, err := p.client.GetClarificationRounds(job.ComplaintID)
+		if err != nil {
+			return err
+		}
+		if rounds >= config.AppConfig.ClarificationMaxRounds {
+			classify = p.classifier.ClassifyWithoutQuestions
+		}
+	}
+
+	classification, err := classify(complaint.Title, description, imageURLs)
+	if err != nil {
+		if rejection, rejected := ai.AsRejection(err); rejected {
+			slog.Info("Complaint rejected by AI", "complaint_id", job.ComplaintID, "reason", rejection.Reason)
+			p.recordUsage(job.ComplaintID, uuid.Nil, rejection.Usage)
+			return p.client.RejectComplaintSystem(complaint, rejection.Reason, rejection.Source, rejection.RuleIDs)
+		}
+		if clarification, ok := ai.AsClarification(err); ok {
+			slog.Info("Complaint needs clarification", "complaint_id", job.ComplaintID, "questions", len(clarification.Questions))
+			p.recordUsage(job.ComplaintID, uuid.Nil, clarification.Usage)
+			_, err := p.client.RequestClarification(complaint, clarification.Source, clarification.Questions, true)
+			return err
+		}
+		return err
+	}
+
+	decision, err := p.reconcileCategory(complaint, classification)
+	if err != nil {
+		return err
+	}
+	p.recordUsage(job.ComplaintID, decision.DepartmentID, classification.Usage)
+	classification.VoiceTranscriptUsed = transcribed
+
+	classified, err := p.client.ApplyClassification(job.ComplaintID, classification, decision)
+	if err != nil {
+		return err
+	}
+	p.saveImageAnalysis(job.ComplaintID, sent, classification.Images)
+
+	// An information request the knowledge base can answer is closed with
+	// the answers, unless the citizen already escalated it
+	if classification.InformationRequest && !complaint.DeflectionDeclined && p.deflect(classified) {
+		return nil
+	}
+
+	if reason := reviewReason(classification, decision); reason != "" {
+		if err := p.client.QueueClassificationReview(job.ComplaintID, classification, reason); err != nil {
+			slog.Warn("Failed to queue classification review", "complaint_id", job.ComplaintID, "error", err)
+		}
+	}
+
+	// The category may have changed, so look for duplicates again
+	if classified.SuggestedDuplicate == nil {
+		p.suggestDuplicate(classified.ID.String(), dedup.Submission{
+			ExcludeID:   classified.ID,
+			CategoryID:  classified.CategoryID,
+			Title:       classified.Title,
+			Description: classified.Description,
+			Latitude:    classified.Latitude,
+			Longitude:   classified.Longitude,
+		})
+	}
+
+	p.client.NotifyClassified(classified)
+
+	return nil
+}
+
+// reviewReason returns why a classification needs human review, or "" if it does not
+func reviewReason(result *supabase.ClassificationResult, decision *supabase.CategoryDecision) string {
+	switch {
+	case len(result.ManipulationSignals) > 0:
+		return models.ReviewReasonManipulation
+	case result.Fallback:
+		return models.ReviewReasonFallback
+	case decision.Reconciliation == models.CategoryDisputed:
+		return models.ReviewReasonCategory
+	case result.SecondOpinion != nil && result.SecondOpinion.Disagrees:
+		return models.ReviewReasonDisagreement
+	case result.Confidence < config.AppConfig.ReviewConfidenceThreshold:
+		return models.ReviewReasonLowConfidence
+	}
+	return ""
+}
+
+// reconcileCategory decides between the AI category and the one the citizen
+// chose. Without a citizen choice, or when both agree, the AI category is
+// used. Otherwise the citizen's choice stands, and an AI confident enough to
+// contradict it sends the complaint to triage rather than rerouting it.
+func (p *Classifier) reconcileCategory(complaint *models.Complaint, result *supabase.ClassificationResult) (*supabase.CategoryDecision, error) {
+	decision := &supabase.CategoryDecision{
+		CategoryID:     result.CategoryID,
+		DepartmentID:   result.DepartmentID,
+		Reconciliation: models.CategoryAIOnly,
+	}
+
+	citizenID := complaint.CitizenCategoryID
+	switch {
+	case citizenID == nil:
+		return decision, nil
+	case *citizenID == result.CategoryID:
+		decision.Reconciliation = models.CategoryAgreed
+		return decision, nil
+	}
+
+	citizen, err := p.client.GetCategory(citizenID.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load citizen category: %w", err)
+	}
+	if citizen == nil {
+		// Deactivated since the complaint was filed
+		return decision, nil
+	}
+
+	decision.CategoryID = citizen.ID
+	decision.DepartmentID = citizen.DepartmentID
+	decision.Reconciliation = models.CategoryCitizenKept
+	if result.CategoryID != uuid.Nil && result.Confidence >= config.AppConfig.CitizenCategoryDisputeConfidence {
+		decision.Reconciliation = models.CategoryDisputed
+	}
+	return decision, nil
+}
+
+// prepareImages downloads and prepares the image attachments for the model,
+// returning the attachments that were usable and their data URLs. Images
+// that are too large, of another type or broken are left out.
+func (p *Classifier) prepareImages(ctx context.Context, complaintID string, attachments []models.ComplaintAttachment) ([]models.ComplaintAttachment, []string) {
+	sent := make([]models.ComplaintAttachment, 0, len(attachments))
+	dataURLs := make([]string, 0, len(attachments))
+	for _, attachment := range attachments {
+		img, err := p.images.Prepare(ctx, attachment.FileURL)
+		if err != nil {
+			slog.Warn("Skipping image attachment", "complaint_id", complaintID, "attachment_id", attachment.ID, "error", err)
+			continue
+		}
+		sent = append(sent, attachment)
+		dataURLs = append(dataURLs, img.DataURL)
+	}
+	return sent, dataURLs
+}
+
+// saveImageAnalysis writes the model's findings back to each attachment. A
+// result without per-image findings, such as an offline one, is skipped.
+func (p *Classifier) saveImageAnalysis(complaintID string, sent []models.ComplaintAttachment, analyses []supabase.AttachmentAnalysis) {
+	if len(analyses) != len(sent) {
+		return
+	}
+	for i, attachment := range sent {
+		if err := p.client.SetAttachmentAnalysis(attachment.ID.String(), analyses[i]); err != nil {
+			slog.Warn("Failed to store image analysis", "complaint_id", complaintID, "attachment_id", attachment.ID, "error", err)
+		}
+	}
+}
+
+// describe returns the description to classify and whether voice note
+// transcripts were added to it. Voice notes are always transcribed so staff
+// can read them, but only a short description is classified with them.
+// Transcripts stored by an earlier attempt are reused. An error means the
+// job ran out of time before every note was transcribed.
+func (p *Classifier) describe(ctx context.Context, complaint *models.Complaint) (string, bool, error) {
+	description := complaint.Description
+	if p.voice == nil {
+		return description, false, nil
+	}
+
+	complaintID := complaint.ID.String()
+	notes, err := p.client.GetVoiceAttachments(complaintID)
+	if err != nil {
+		slog.Warn("Failed to load voice notes", "complaint_id", complaintID, "error", err)
+		return description, false, nil
+	}
+
+	var transcripts []string
+	for _, note := range notes {
+		if note.TranscriptionStatus != models.TranscriptionCompleted {
+			note.Transcript = p.transcribe(ctx, complaintID, note)
+		}
+		if err := ctx.Err(); err != nil {
+			return "", false, err
+		}
+		if text := strings.TrimSpace(note.Transcript); text != "" {
+			transcripts = append(transcripts, text)
+		}
+	}
+	if len(transcripts) == 0 || arabic.RuneLen(strings.TrimSpace(description)) >= config.AppConfig.VoiceTranscriptMinDescription {
+		return description, false, nil
+	}
+
+	return strings.TrimSpace(description + "\n\nنص الرسالة الصوتية:\n" + strings.Join(transcripts, "\n\n")), true, nil
+}
+
+// transcribe converts one voice note to text and stores the outcome on the
+// attachment. A failed note is retried the next time the complaint is
+// classified.
+func (p *Classifier) transcribe(ctx context.Context, complaintID string, note models.ComplaintAttachment) string {
+	transcription := supabase.Transcription{Transcriber: p.voice.Name()}
+
+	transcript, err := p.voice.Transcribe(ctx, note.FileURL, note.FileName)
+	if err != nil && ctx.Err() != nil {
+		// The job ran out of time; the note is not at fault and is
+		// transcribed again when the job is retried
+		return ""
+	}
+	if err != nil {
+		slog.Warn("Failed to transcribe voice note", "complaint_id", complaintID, "attachment_id", note.ID, "error", err)
+		transcription.Err = err
+	} else {
+		transcription.Text = transcript.Text
+		transcription.Language = transcript.Language
+		transcription.DurationSeconds = transcript.Duration.Seconds()
+	}
+
+	if err := p.client.SetAttachmentTranscript(note.ID.String(), transcription); err != nil {
+		slog.Warn("Failed to store transcript", "complaint_id", complaintID, "attachment_id", note.ID, "error", err)
+	}
+	return transcription.Text
+}
+
+// deflect answers an information request from the knowledge base and reports
+// whether it did. Without a good enough answer the complaint is handled as
+// usual.
+func (p *Classifier) deflect(complaint *models.Complaint) bool {
+	matches, err := p.matcher.Match(knowledge.Query{
+		DepartmentID: complaint.DepartmentID,
+		CategoryID:   complaint.CategoryID,
+		Text:         complaint.Title + " " + complaint.Description,
+	})
+	if err != nil {
+		slog.Warn("Knowledge base lookup failed", "complaint_id", complaint.ID, "error", err)
+		return false
+	}
+	if len(matches) == 0 {
+		return false
+	}
+
+	if _, err := p.client.DeflectComplaint(complaint, matches); err != nil {
+		slog.Warn("Failed to deflect complaint", "complaint_id", complaint.ID, "error", err)
+		return false
+	}
+
+	slog.Info("Information request answered from knowledge base", "complaint_id", complaint.ID, "answers", len(matches))
+	return true
+}
+
+// recordUsage stores the LLM calls made for a complaint; accounting failures
+// never fail the classification
+func (p *Classifier) recordUsage(complaintID string, departmentID uuid.UUID, usage []supabase.LLMUsage) {
+	if err := p.client.RecordLLMUsage(complaintID, departmentID, usage); err != nil {
+		slog.Warn("Failed to record LLM usage", "complaint_id", complaintID, "error", err)
+	}
+}
+
+func (p *Classifier) suggestDuplicate(complaintID string, submission dedup.Submission) {
+	candidates, err := p.detector.FindCandidates(submission)
+	if err != nil {
+		slog.Warn("Duplicate detection failed", "complaint_id", complaintID, "error", err)
+		return
+	}
+	if len(candidates) == 0 {
+		return
+	}
+
+	if err := p.client.SetSuggestedDuplicate(complaintID, candidates[0].ComplaintID.String()); err != nil {
+		slog.Warn("Failed to store suggested duplicate", "complaint_id", complaintID, "error", err)
+	}
+}
+
+// fail requeues the job with backoff, or gives up after the configured attempts
+func (p *Classifier) fail(job *supabase.ClassificationJob, jobErr error) {
+	var retryAt *time.Time
+	if job.Attempts < config.AppConfig.ClassificationMaxAttempts {
+		t := time.Now().Add(time.Duration(job.Attempts) * retryBackoff)
+		retryAt = &t
+		slog.Warn("Classification failed, will retry", "complaint_id", job.ComplaintID,
+			"attempt", job.Attempts, "retry_at", t, "error", jobErr)
+		_ = p.client.SetClassificationStatus(job.ComplaintID, supabase.ClassificationPending)
+	} else {
+		slog.Error("Classification failed permanently", "complaint_id", job.ComplaintID,
+			"attempts", job.Attempts, "error", jobErr)
+		_ = p.client.SetClassificationStatus(job.ComplaintID, supabase.ClassificationFailed)
+	}
+
+	if err := p.client.FailClassificationJob(job.ID, jobErr.Error(), retryAt); err != nil {
+		slog.Warn("Failed to update classification job", "job_id", job.ID, "error", err)
+	}
+}
@@ -0,0 +1,243 @@
+// Package redact replaces personal data in complaint text with placeholders
+// before it is sent to an external LLM. The patterns target Jordanian
+// formats: national numbers, +962 and 07x phone numbers, vehicle plates and
+// JO IBANs, plus email addresses and self-introduced names.
+package redact
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Kind is one type of personal data
+type Kind string
+
+const (
+	KindEmail      Kind = "email"
+	KindIBAN       Kind = "iban"
+	KindPhone      Kind = "phone"
+	KindNationalID Kind = "national_id"
+	KindPlate      Kind = "plate"
+	KindName       Kind = "name"
+)
+
+type pattern struct {
+	kind        Kind
+	re          *regexp.Regexp
+	placeholder string
+	// isolated rejects matches that are part of a longer number or word
+	isolated bool
+	// accept filters out matches that only look like personal data
+	accept func(match string) bool
+}
+
+// patterns run in order on the text left by the previous ones, so formats
+// that contain long digit runs (IBANs, phones) are removed before the bare
+// 10-digit national number is looked for. When a pattern has a capture
+// group only the group is replaced.
+var patterns = []pattern{
+	{
+		kind:        KindEmail,
+		re:          regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`),
+		placeholder: "[EMAIL]",
+	},
+	{
+		kind:        KindIBAN,
+		re:          regexp.MustCompile(`(?i)\b[A-Z]{2}[0-9]{2}(?: ?[A-Z0-9]{4}){3,7}(?: ?[A-Z0-9]{1,4})?\b`),
+		placeholder: "[IBAN]",
+	},
+	{
+		// Mobiles are 07[789] plus 7 digits, landlines 0[2356] plus 7 digits;
+		// the leading 0 becomes +962 or 00962 internationally
+		kind:        KindPhone,
+		re:          regexp.MustCompile(`(?:(?:\+|00) ?962[ -]?|0)(?:7[789]|[2356])(?:[ -]?[0-9]){7}`),
+		placeholder: "[PHONE]",
+		isolated:    true,
+	},
+	{
+		kind:        KindNationalID,
+		re:          regexp.MustCompile(`[0-9]{10}`),
+		placeholder: "[NATIONAL_ID]",
+		isolated:    true,
+	},
+	{
+		// A plate mentioned by name can be written without the dash
+		kind:        KindPlate,
+		re:          regexp.MustCompile(`(?:لوح[ةه]|نمر[ةه]|رقم السيار[ةه]|(?i:plate))[^0-9]{0,15}?([0-9]{1,2} ?[-/]? ?[0-9]{3,5})`),
+		placeholder: "[PLATE]",
+		isolated:    true,
+	},
+	{
+		// Jordanian plates are a 1-2 digit code and a 3-5 digit number
+		kind:        KindPlate,
+		re:          regexp.MustCompile(`[0-9]{1,2} ?- ?[0-9]{3,5}`),
+		placeholder: "[PLATE]",
+		isolated:    true,
+		accept:      notDate,
+	},
+	{
+		kind:        KindName,
+		re:          regexp.MustCompile(`(?:اسمي|الاسم) ?:? ?(\p{Arabic}+(?: \p{Arabic}+){0,2})`),
+		placeholder: "[NAME]",
+	},
+}
+
+// yearPattern matches 4-digit years, so "3-2024" is not taken for a plate
+var yearPattern = regexp.MustCompile(`- ?(19|20)[0-9]{2}$`)
+
+func notDate(match string) bool {
+	return !yearPattern.MatchString(match)
+}
+
+// Kinds lists every kind of personal data the package can redact
+func Kinds() []Kind {
+	return []Kind{KindEmail, KindIBAN, KindPhone, KindNationalID, KindPlate, KindName}
+}
+
+// Report counts what was redacted from one text, by kind. Values are never
+// kept, so the report is safe to store and log.
+type Report map[Kind]int
+
+// Merge adds the counts of another report
+func (r Report) Merge(other Report) {
+	for kind, count := range other {
+		r[kind] += count
+	}
+}
+
+// Counts returns the report keyed by plain strings for storage
+func (r Report) Counts() map[string]int {
+	counts := make(map[string]int, len(r))
+	for kind, count := range r {
+		counts[string(kind)] = count
+	}
+	return counts
+}
+
+// Redactor applies the patterns of the enabled kinds
+type Redactor struct {
+	kinds    []Kind
+	patterns []pattern
+}
+
+// New creates a redactor for the given kinds; an empty list enables all
+func New(kinds []string) (*Redactor, error) {
+	enabled := make(map[Kind]bool)
+	for _, name := range kinds {
+		kind := Kind(strings.ToLower(strings.TrimSpace(name)))
+		if !known(kind) {
+			return nil, fmt.Errorf("unknown redaction kind: %q", name)
+		}
+		enabled[kind] = true
+	}
+
+	r := &Redactor{}
+	for _, kind := range Kinds() {
+		if len(enabled) == 0 || enabled[kind] {
+			r.kinds = append(r.kinds, kind)
+		}
+	}
+	for _, p := range patterns {
+		if len(enabled) == 0 || enabled[p.kind] {
+			r.patterns = append(r.patterns, p)
+		}
+	}
+	return r, nil
+}
+
+func known(kind Kind) bool {
+	for _, k := range Kinds() {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// Kinds returns the kinds this redactor removes
+func (r *Redactor) Kinds() []Kind {
+	return r.kinds
+}
+
+// KindNames returns the enabled kinds as plain strings for storage
+func (r *Redactor) KindNames() []string {
+	names := make([]string, len(r.kinds))
+	for i, kind := range r.kinds {
+		names[i] = string(kind)
+	}
+	return names
+}
+
+// Redact returns the text with personal data replaced by placeholders such
+// as [PHONE]. Arabic-Indic digits are converted to ASCII first so both
+// spellings of a number are caught.
+func (r *Redactor) Redact(text string) (string, Report) {
+	report := make(Report)
+	text = asciiDigits(text)
+
+	for _, p := range r.patterns {
+		var b strings.Builder
+		last := 0
+		for _, loc := range p.re.FindAllStringSubmatchIndex(text, -1) {
+			start, end := loc[0], loc[1]
+			if len(loc) >= 4 && loc[2] >= 0 {
+				start, end = loc[2], loc[3]
+			}
+			match := text[start:end]
+			if p.isolated && !isolated(text, start, end) {
+				continue
+			}
+			if p.accept != nil && !p.accept(match) {
+				continue
+			}
+
+			b.WriteString(text[last:start])
+			b.WriteString(p.placeholder)
+			last = end
+			report[p.kind]++
+		}
+		if last > 0 {
+			b.WriteString(text[last:])
+			text = b.String()
+		}
+	}
+
+	return text, report
+}
+
+// isolated reports whether text[start:end] is not glued to another digit or
+// Latin letter, which would make it part of a longer code
+func isolated(text string, start, end int) bool {
+	if start > 0 {
+		r, _ := utf8.DecodeLastRuneInString(text[:start])
+		if isAlnum(r) {
+			return false
+		}
+	}
+	if end < len(text) {
+		r, _ := utf8.DecodeRuneInString(text[end:])
+		if isAlnum(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func isAlnum(r rune) bool {
+	return r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z'
+}
+
+// asciiDigits converts Arabic-Indic and Persian digits to ASCII
+func asciiDigits(text string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= '٠' && r <= '٩':
+			return '0' + (r - '٠')
+		case r >= '۰' && r <= '۹':
+			return '0' + (r - '۰')
+		}
+		return r
+	}, text)
+}
@@ -0,0 +1,75 @@
+package rules
+
+import "github.com/hakim/backend/internal/models"
+
+// Built-in rules used until the classification_rules table has been loaded,
+// and by tools that run without a database. Migration 016 seeds the same
+// patterns so the defaults and the table start out identical.
+var (
+	defaultPriorityKeywords = map[string][]string{
+		"critical": {"urgent", "emergency", "critical", "danger", "طوارئ", "عاجل", "خطر", "حريق", "انفجار"},
+		"high":     {"important", "serious", "مهم", "خطير", "انقطاع"},
+		"low":      {"minor", "small", "بسيط", "صغير", "استفسار"},
+	}
+
+	defaultSpamPatterns = []string{
+		"test", "testing", "asdf", "qwerty", "aaaa", "1234", "xxxx",
+		"تجربة", "تست", "اختبار فقط",
+		"ههههه", "هاها", "lol", "haha",
+	}
+
+	defaultOffensivePatterns = []string{
+		"حمار", "غبي", "كلب", "خنزير", "لعنة",
+	}
+
+	// Legitimate reports about stray animals, which would otherwise trip the
+	// offensive filter
+	defaultOffensiveExceptions = []string{
+		"كلب ضال", "كلاب ضال", "كلب الضال", "كلاب الضال",
+	}
+)
+
+// Defaults returns the built-in rules
+func Defaults() []models.ClassificationRule {
+	var defaults []models.ClassificationRule
+
+	for _, priority := range priorityOrder {
+		for _, keyword := range defaultPriorityKeywords[priority] {
+			defaults = append(defaults, models.ClassificationRule{
+				RuleSet:  models.RuleSetPriority,
+				Priority: priority,
+				Pattern:  keyword,
+				Weight:   1,
+				IsActive: true,
+			})
+		}
+	}
+	for _, pattern := range defaultSpamPatterns {
+		defaults = append(defaults, models.ClassificationRule{
+			RuleSet:  models.RuleSetSpam,
+			Pattern:  pattern,
+			Weight:   1,
+			IsActive: true,
+		})
+	}
+	for _, pattern := range defaultOffensivePatterns {
+		defaults = append(defaults, models.ClassificationRule{
+			RuleSet:  models.RuleSetOffensive,
+			Pattern:  pattern,
+			Weight:   1,
+			IsActive: true,
+		})
+	}
+
+	for _, pattern := range defaultOffensiveExceptions {
+		defaults = append(defaults, models.ClassificationRule{
+			RuleSet:    models.RuleSetOffensive,
+			Pattern:    pattern,
+			IsNegative: true,
+			Weight:     1,
+			IsActive:   true,
+		})
+	}
+
+	return defaults
+}
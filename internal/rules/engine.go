@@ -0,0 +1,331 @@
+// Package rules evaluates the admin-managed keyword and regex rules used by the
+// keyword classifier and the junk filter. Rules live in the database and are
+// reloaded periodically, so changing them does not need a redeploy.
+package rules
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hakim/backend/internal/arabic"
+	"github.com/hakim/backend/internal/models"
+)
+
+// fireThreshold is the summed weight at which the priority, spam and
+// offensive rule sets reach a verdict
+const fireThreshold = 1.0
+
+// spamMaxRunes limits spam rules to short texts. A long, detailed complaint
+// that happens to contain "test" is not spam.
+const spamMaxRunes = 50
+
+// priorityOrder lists priorities from most to least severe. "medium" is the
+// default and has no rules of its own.
+var priorityOrder = []string{"critical", "high", "low"}
+
+// Source loads the active rules and how their rejections fared on appeal
+type Source interface {
+	GetActiveClassificationRules() ([]models.ClassificationRule, error)
+	GetRuleOverrideStats() ([]models.RuleOverrideStat, error)
+}
+
+type compiledRule struct {
+	rule  models.ClassificationRule
+	regex *regexp.Regexp
+	term  string // normalized pattern for substring matching
+}
+
+// Engine holds the current rule set. It is safe for concurrent use.
+type Engine struct {
+	source Source
+
+	// Spam and offensive rules overturned at least demoteRate of the time,
+	// over at least demoteMinAppeals decided appeals, stop rejecting
+	demoteMinAppeals int
+	demoteRate       float64
+
+	mu       sync.RWMutex
+	rules    []compiledRule
+	demoted  map[uuid.UUID]bool
+	loadedAt time.Time
+}
+
+// NewEngine creates an engine that starts with the built-in defaults. A nil
+// source keeps the defaults forever.
+func NewEngine(source Source) *Engine {
+	e := &Engine{source: source}
+	e.rules = compileAll(Defaults())
+	return e
+}
+
+// DemoteOverturned enables feedback from appeals: a spam or offensive rule
+// whose rejections are overturned at least rate of the time, over at least
+// minAppeals decided appeals, still shows up in evaluations but no longer
+// rejects complaints. A minAppeals of 0 disables demotion.
+func (e *Engine) DemoteOverturned(minAppeals int, rate float64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.demoteMinAppeals = minAppeals
+	e.demoteRate = rate
+}
+
+// Demoted reports whether appeals have demoted a rule
+func (e *Engine) Demoted(ruleID uuid.UUID) bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.demoted[ruleID]
+}
+
+// Compile checks that a rule's pattern is usable
+func Compile(rule models.ClassificationRule) error {
+	_, err := compile(rule)
+	return err
+}
+
+func compile(rule models.ClassificationRule) (compiledRule, error) {
+	compiled := compiledRule{rule: rule}
+	if rule.IsRegex {
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return compiled, fmt.Errorf("invalid regex %q: %w", rule.Pattern, err)
+		}
+		compiled.regex = re
+	} else {
+		compiled.term = arabic.Normalize(strings.TrimSpace(rule.Pattern))
+		if compiled.term == "" {
+			return compiled, fmt.Errorf("pattern is empty")
+		}
+	}
+	return compiled, nil
+}
+
+// compileAll compiles rules, skipping and logging the ones that fail
+func compileAll(rules []models.ClassificationRule) []compiledRule {
+	compiled := make([]compiledRule, 0, len(rules))
+	for _, rule := range rules {
+		c, err := compile(rule)
+		if err != nil {
+			slog.Warn("Skipping classification rule", "rule_id", rule.ID, "error", err)
+			continue
+		}
+		compiled = append(compiled, c)
+	}
+	return compiled
+}
+
+// Reload replaces the rules with the active rules from the source. An empty
+// table keeps the built-in defaults; on error the current rules stay in use.
+func (e *Engine) Reload() error {
+	if e.source == nil {
+		return nil
+	}
+
+	loaded, err := e.source.GetActiveClassificationRules()
+	if err != nil {
+		return err
+	}
+	if len(loaded) == 0 {
+		loaded = Defaults()
+	}
+	compiled := compileAll(loaded)
+
+	e.mu.RLock()
+	minAppeals, rate := e.demoteMinAppeals, e.demoteRate
+	e.mu.RUnlock()
+
+	demoted := make(map[uuid.UUID]bool)
+	if minAppeals > 0 {
+		stats, err := e.source.GetRuleOverrideStats()
+		if err != nil {
+			return err
+		}
+		for _, stat := range stats {
+			if ShouldDemote(stat, minAppeals, rate) {
+				demoted[stat.RuleID] = true
+			}
+		}
+	}
+
+	e.mu.Lock()
+	e.rules = compiled
+	e.demoted = demoted
+	e.loadedAt = time.Now()
+	e.mu.Unlock()
+
+	return nil
+}
+
+// ShouldDemote applies the demotion policy to one rule's appeal record
+func ShouldDemote(stat models.RuleOverrideStat, minAppeals int, rate float64) bool {
+	decided := stat.Upheld + stat.Overturned
+	return minAppeals > 0 && decided >= minAppeals &&
+		float64(stat.Overturned)/float64(decided) >= rate
+}
+
+// Start loads the rules and keeps reloading them every interval until ctx is
+// cancelled
+func (e *Engine) Start(ctx context.Context, interval time.Duration) {
+	if e.source == nil {
+		return
+	}
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	if err := e.Reload(); err != nil {
+		slog.Warn("Failed to load classification rules, using defaults", "error", err)
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := e.Reload(); err != nil {
+					slog.Warn("Failed to reload classification rules", "error", err)
+				}
+			}
+		}
+	}()
+}
+
+// LoadedAt returns when rules were last loaded from the source, zero while
+// the defaults are in use
+func (e *Engine) LoadedAt() time.Time {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.loadedAt
+}
+
+// Evaluate runs every rule against the text and combines the matches.
+// Negative rules veto their target: a category or priority is ruled out, and
+// a spam or offensive verdict is cancelled.
+func (e *Engine) Evaluate(text string) *models.RuleEvaluation {
+	e.mu.RLock()
+	rules, demoted := e.rules, e.demoted
+	e.mu.RUnlock()
+
+	prepared := arabic.NewText(text)
+	normalized := prepared.Normalized()
+
+	eval := &models.RuleEvaluation{
+		Matches:        []models.RuleMatch{},
+		CategoryScores: make(map[string]float64),
+	}
+
+	vetoedCategories := make(map[string]bool)
+	priorityScores := make(map[string]float64)
+	vetoedPriorities := make(map[string]bool)
+	var spamScore, offensiveScore float64
+	var spamVetoed, offensiveVetoed bool
+
+	for i := range rules {
+		r := &rules[i]
+		if !r.matches(prepared, normalized) {
+			continue
+		}
+
+		rule := r.rule
+		match := models.RuleMatch{
+			RuleSet:    rule.RuleSet,
+			CategoryID: rule.CategoryID,
+			Priority:   rule.Priority,
+			Pattern:    rule.Pattern,
+			IsNegative: rule.IsNegative,
+			Weight:     rule.Weight,
+		}
+		if rule.ID != uuid.Nil {
+			id := rule.ID
+			match.RuleID = &id
+		}
+
+		switch rule.RuleSet {
+		case models.RuleSetCategory:
+			if rule.CategoryID == nil {
+				continue
+			}
+			key := rule.CategoryID.String()
+			if rule.IsNegative {
+				vetoedCategories[key] = true
+			} else {
+				eval.CategoryScores[key] += rule.Weight
+			}
+		case models.RuleSetPriority:
+			if rule.IsNegative {
+				vetoedPriorities[rule.Priority] = true
+			} else {
+				priorityScores[rule.Priority] += rule.Weight
+			}
+		case models.RuleSetSpam:
+			if arabic.RuneLen(normalized) >= spamMaxRunes {
+				continue
+			}
+			switch {
+			case rule.IsNegative:
+				spamVetoed = true
+			case demoted[rule.ID]:
+				// Reported for visibility, but no longer counts toward a rejection
+				match.Demoted = true
+			default:
+				spamScore += rule.Weight
+			}
+		case models.RuleSetOffensive:
+			switch {
+			case rule.IsNegative:
+				offensiveVetoed = true
+			case demoted[rule.ID]:
+				match.Demoted = true
+			default:
+				offensiveScore += rule.Weight
+			}
+		default:
+			continue
+		}
+
+		eval.Matches = append(eval.Matches, match)
+	}
+
+	eval.VetoedCategories = make([]string, 0, len(vetoedCategories))
+	for id := range vetoedCategories {
+		delete(eval.CategoryScores, id)
+		eval.VetoedCategories = append(eval.VetoedCategories, id)
+	}
+
+	for _, priority := range priorityOrder {
+		if !vetoedPriorities[priority] && priorityScores[priority] >= fireThreshold {
+			eval.Priority = priority
+			break
+		}
+	}
+
+	eval.Spam = !spamVetoed && spamScore >= fireThreshold
+	eval.Offensive = !offensiveVetoed && offensiveScore >= fireThreshold
+
+	return eval
+}
+
+// matches reports whether the rule fires. Category and priority keywords
+// match whole words after stemming; spam and offensive patterns match
+// anywhere, so they also catch words glued to other text. Regexes run on the
+// normalized text.
+func (r *compiledRule) matches(text *arabic.Text, normalized string) bool {
+	if r.regex != nil {
+		return r.regex.MatchString(normalized)
+	}
+
+	switch r.rule.RuleSet {
+	case models.RuleSetSpam, models.RuleSetOffensive:
+		return strings.Contains(normalized, r.term)
+	default:
+		return text.Contains(r.term)
+	}
+}
@@ -0,0 +1,217 @@
+// Package safeurl guards server-side requests to citizen-supplied URLs.
+// URLs must be HTTPS and match an allowlist of storage locations, and every
+// connection is checked after DNS resolution so a URL cannot reach private,
+// loopback or link-local addresses, including through DNS rebinding.
+// Redirects are never followed.
+package safeurl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"syscall"
+	"time"
+)
+
+var (
+	ErrInvalid        = errors.New("not a valid URL")
+	ErrScheme         = errors.New("only https URLs are accepted")
+	ErrNotAllowed     = errors.New("URL is not on an allowed storage location")
+	ErrPrivateAddress = errors.New("URL resolves to a private or internal address")
+	ErrRedirect       = errors.New("redirects are not followed")
+)
+
+// blockedNetworks are ranges that are not publicly routable but are not
+// covered by the net.IP helpers
+var blockedNetworks = mustParseCIDRs(
+	"0.0.0.0/8",       // "this" network
+	"100.64.0.0/10",   // carrier-grade NAT
+	"192.0.0.0/24",    // IETF protocol assignments
+	"192.0.2.0/24",    // documentation
+	"198.18.0.0/15",   // benchmarking
+	"198.51.100.0/24", // documentation
+	"203.0.113.0/24",  // documentation
+	"240.0.0.0/4",     // reserved, including broadcast
+	"64:ff9b::/96",    // NAT64, which can embed private IPv4 addresses
+	"2001:db8::/32",   // documentation
+)
+
+// pattern is one allowed location: a host, or every subdomain of one, and a
+// path prefix
+type pattern struct {
+	host       string
+	subdomains bool
+	pathPrefix string
+}
+
+// Policy is the set of locations attachment URLs may point to
+type Policy struct {
+	patterns []pattern
+	resolver *net.Resolver
+}
+
+// NewPolicy parses allowlist entries of the form "host/path/prefix". A host
+// starting with "*." matches its subdomains only.
+func NewPolicy(entries []string) (*Policy, error) {
+	p := &Policy{resolver: net.DefaultResolver}
+	for _, entry := range entries {
+		host, prefix, _ := strings.Cut(strings.TrimSpace(entry), "/")
+		host = strings.ToLower(host)
+		if host == "" || strings.ContainsAny(host, ":@") {
+			return nil, fmt.Errorf("invalid attachment URL pattern %q", entry)
+		}
+
+		// Prefixes match whole path segments, so "img" does not allow "imgs"
+		prefix = strings.TrimSuffix("/"+prefix, "/") + "/"
+		pat := pattern{host: host, pathPrefix: prefix}
+		if rest, ok := strings.CutPrefix(host, "*."); ok {
+			pat.host, pat.subdomains = rest, true
+		}
+		p.patterns = append(p.patterns, pat)
+	}
+	return p, nil
+}
+
+// Check validates the form of a URL and that it is on the allowlist, without
+// network access
+func (p *Policy) Check(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" || u.Opaque != "" {
+		return ErrInvalid
+	}
+	if u.Scheme != "https" {
+		return ErrScheme
+	}
+	// Credentials and unusual ports are never part of a storage URL
+	if u.User != nil || (u.Port() != "" && u.Port() != "443") {
+		return ErrNotAllowed
+	}
+
+	host := strings.ToLower(u.Hostname())
+	// "/a/../b" must not escape the allowed prefix
+	cleaned := path.Clean(u.Path)
+	for _, pat := range p.patterns {
+		if !pat.matchesHost(host) {
+			continue
+		}
+		if strings.HasPrefix(cleaned+"/", pat.pathPrefix) {
+			return nil
+		}
+	}
+	return ErrNotAllowed
+}
+
+// Validate checks a URL and resolves its host, rejecting hosts that resolve
+// to any internal address
+func (p *Policy) Validate(ctx context.Context, rawURL string) error {
+	if err := p.Check(rawURL); err != nil {
+		return err
+	}
+
+	u, _ := url.Parse(rawURL)
+	if ip := net.ParseIP(u.Hostname()); ip != nil {
+		if !IsPublic(ip) {
+			return ErrPrivateAddress
+		}
+		return nil
+	}
+
+	addrs, err := p.resolver.LookupIPAddr(ctx, u.Hostname())
+	if err != nil || len(addrs) == 0 {
+		return fmt.Errorf("%w: host does not resolve", ErrInvalid)
+	}
+	for _, addr := range addrs {
+		if !IsPublic(addr.IP) {
+			return ErrPrivateAddress
+		}
+	}
+	return nil
+}
+
+func (pat pattern) matchesHost(host string) bool {
+	if pat.subdomains {
+		return strings.HasSuffix(host, "."+pat.host)
+	}
+	return host == pat.host
+}
+
+// IsPublic reports whether ip is a publicly routable unicast address
+func IsPublic(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+	for _, network := range blockedNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// NewHTTPClient returns a client for fetching citizen-supplied URLs. It only
+// speaks HTTPS, refuses to connect to internal addresses after resolution,
+// ignores proxy settings and does not follow redirects.
+func NewHTTPClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		// Control runs with the resolved address, so a host that resolves
+		// differently at connect time is still checked
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !IsPublic(ip) {
+				return ErrPrivateAddress
+			}
+			return nil
+		},
+	}
+
+	transport := &http.Transport{
+		Proxy:               nil,
+		DialContext:         dialer.DialContext,
+		TLSHandshakeTimeout: timeout,
+		MaxIdleConns:        10,
+		IdleConnTimeout:     90 * time.Second,
+	}
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: httpsOnly{transport},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return ErrRedirect
+		},
+	}
+}
+
+// httpsOnly refuses plain HTTP requests
+type httpsOnly struct {
+	next http.RoundTripper
+}
+
+func (t httpsOnly) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Scheme != "https" {
+		return nil, ErrScheme
+	}
+	return t.next.RoundTrip(req)
+}
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
@@ -0,0 +1,288 @@
+// Package textmodel is a small statistical text classifier: multinomial Naive
+// Bayes over TF-IDF weighted, normalized Arabic tokens. It is trained offline
+// from historical complaints and stored as a versioned JSON file, and serves
+// as the classifier when no LLM is available.
+package textmodel
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/hakim/backend/internal/arabic"
+)
+
+// FormatVersion is bumped whenever the file layout or feature extraction
+// changes, so a model trained by older code is refused instead of misread
+const FormatVersion = 2
+
+// smoothing is the additive (Lidstone) smoothing applied to term weights
+const smoothing = 0.1
+
+var ErrNoExamples = errors.New("no training examples")
+
+// Label is one class the model predicts, normally a category
+type Label struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// Example is one labeled text used for training
+type Example struct {
+	Text  string
+	Label Label
+}
+
+// Prediction is the most likely label for a text
+type Prediction struct {
+	Label      Label   `json:"label"`
+	Confidence float64 `json:"confidence"`
+}
+
+// Model is a trained classifier as stored on disk
+type Model struct {
+	FormatVersion int       `json:"format_version"`
+	Version       string    `json:"version"`
+	TrainedAt     time.Time `json:"trained_at"`
+	Examples      int       `json:"examples"`
+	// HoldoutAccuracy is measured by the training command on data the model
+	// did not see; nil when no holdout split was used
+	HoldoutAccuracy *float64 `json:"holdout_accuracy,omitempty"`
+
+	Labels     []Label        `json:"labels"`
+	Vocabulary map[string]int `json:"vocabulary"`
+	IDF        []float64      `json:"idf"`
+	LogPriors  []float64      `json:"log_priors"`
+	// LogLikelihoods[label][term] is log P(term | label)
+	LogLikelihoods [][]float64 `json:"log_likelihoods"`
+}
+
+// TrainOptions tune training
+type TrainOptions struct {
+	// MinDocFrequency drops terms seen in fewer documents than this
+	MinDocFrequency int
+}
+
+// Name identifies the model in classification records
+func (m *Model) Name() string {
+	return "local-nb@" + m.Version
+}
+
+// Train fits a model on the examples
+func Train(examples []Example, opts TrainOptions) (*Model, error) {
+	if len(examples) == 0 {
+		return nil, ErrNoExamples
+	}
+	if opts.MinDocFrequency < 1 {
+		opts.MinDocFrequency = 1
+	}
+
+	docs := make([]map[string]int, len(examples))
+	docFrequency := make(map[string]int)
+	for i, example := range examples {
+		docs[i] = termCounts(example.Text)
+		for term := range docs[i] {
+			docFrequency[term]++
+		}
+	}
+
+	vocabulary := make(map[string]int)
+	var idf []float64
+	n := float64(len(examples))
+	for term, df := range docFrequency {
+		if df < opts.MinDocFrequency {
+			continue
+		}
+		vocabulary[term] = len(idf)
+		// Smoothed IDF, as in scikit-learn
+		idf = append(idf, math.Log((1+n)/(1+float64(df)))+1)
+	}
+	if len(vocabulary) == 0 {
+		return nil, fmt.Errorf("no terms appear in at least %d documents", opts.MinDocFrequency)
+	}
+
+	labelIndex := make(map[string]int)
+	var labels []Label
+	for _, example := range examples {
+		if _, ok := labelIndex[example.Label.ID]; !ok {
+			labelIndex[example.Label.ID] = len(labels)
+			labels = append(labels, example.Label)
+		}
+	}
+
+	labelCounts := make([]float64, len(labels))
+	termWeights := make([][]float64, len(labels))
+	for i := range termWeights {
+		termWeights[i] = make([]float64, len(idf))
+	}
+	for i, example := range examples {
+		l := labelIndex[example.Label.ID]
+		labelCounts[l]++
+		for term, weight := range weigh(docs[i], vocabulary, idf) {
+			termWeights[l][term] += weight
+		}
+	}
+
+	model := &Model{
+		FormatVersion:  FormatVersion,
+		TrainedAt:      time.Now().UTC(),
+		Examples:       len(examples),
+		Labels:         labels,
+		Vocabulary:     vocabulary,
+		IDF:            idf,
+		LogPriors:      make([]float64, len(labels)),
+		LogLikelihoods: make([][]float64, len(labels)),
+	}
+	model.Version = model.TrainedAt.Format("20060102-150405")
+
+	vocabSize := float64(len(idf))
+	for l := range labels {
+		model.LogPriors[l] = math.Log(labelCounts[l] / n)
+
+		total := 0.0
+		for _, w := range termWeights[l] {
+			total += w
+		}
+		model.LogLikelihoods[l] = make([]float64, len(idf))
+		for t, w := range termWeights[l] {
+			model.LogLikelihoods[l][t] = math.Log((w + smoothing) / (total + smoothing*vocabSize))
+		}
+	}
+
+	return model, nil
+}
+
+// Predict returns the most likely label, or nil when the text shares no
+// terms with the training data
+func (m *Model) Predict(text string) *Prediction {
+	features := weigh(termCounts(text), m.Vocabulary, m.IDF)
+	if len(features) == 0 {
+		return nil
+	}
+
+	scores := make([]float64, len(m.Labels))
+	best := 0
+	for l := range m.Labels {
+		score := m.LogPriors[l]
+		for term, weight := range features {
+			score += weight * m.LogLikelihoods[l][term]
+		}
+		scores[l] = score
+		if score > scores[best] {
+			best = l
+		}
+	}
+
+	// Softmax over the log scores gives the posterior of the best label
+	sum := 0.0
+	for _, score := range scores {
+		sum += math.Exp(score - scores[best])
+	}
+
+	return &Prediction{
+		Label:      m.Labels[best],
+		Confidence: 1 / sum,
+	}
+}
+
+// termCounts counts the stemmed tokens of a text, ignoring single letters
+func termCounts(text string) map[string]int {
+	counts := make(map[string]int)
+	for _, token := range arabic.Tokens(text) {
+		if arabic.RuneLen(token) < 2 {
+			continue
+		}
+		counts[token]++
+	}
+	return counts
+}
+
+// weigh turns raw counts into L2-normalized, sublinear TF-IDF weights for the
+// terms in the vocabulary
+func weigh(counts map[string]int, vocabulary map[string]int, idf []float64) map[int]float64 {
+	weights := make(map[int]float64, len(counts))
+	norm := 0.0
+	for term, count := range counts {
+		t, ok := vocabulary[term]
+		if !ok {
+			continue
+		}
+		w := (1 + math.Log(float64(count))) * idf[t]
+		weights[t] = w
+		norm += w * w
+	}
+
+	if norm > 0 {
+		norm = math.Sqrt(norm)
+		for t := range weights {
+			weights[t] /= norm
+		}
+	}
+	return weights
+}
+
+// Load reads a model file written by Save
+func Load(path string) (*Model, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var model Model
+	if err := json.Unmarshal(data, &model); err != nil {
+		return nil, fmt.Errorf("failed to parse model %s: %w", path, err)
+	}
+	if model.FormatVersion != FormatVersion {
+		return nil, fmt.Errorf("model %s has format version %d, expected %d; retrain it", path, model.FormatVersion, FormatVersion)
+	}
+	if len(model.Labels) == 0 || len(model.LogPriors) != len(model.Labels) ||
+		len(model.LogLikelihoods) != len(model.Labels) || len(model.IDF) != len(model.Vocabulary) {
+		return nil, fmt.Errorf("model %s is incomplete", path)
+	}
+	for _, row := range model.LogLikelihoods {
+		if len(row) != len(model.IDF) {
+			return nil, fmt.Errorf("model %s is incomplete", path)
+		}
+	}
+
+	return &model, nil
+}
+
+// Save writes the model atomically, so a running server never reads a
+// half-written file
+func (m *Model) Save(path string) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".model-*.json")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(0o644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,107 @@
+package textmodel
+
+import (
+	"errors"
+	"math"
+	"path/filepath"
+	"testing"
+)
+
+var (
+	water       = Label{ID: "water", Name: "Water"}
+	roads       = Label{ID: "roads", Name: "Roads"}
+	electricity = Label{ID: "electricity", Name: "Electricity"}
+)
+
+var trainingExamples = []Example{
+	{"المياه مقطوعة عن الحي منذ ثلاثة أيام", water},
+	{"انقطاع المياه المتكرر في منطقتنا", water},
+	{"تسريب مياه من الخط الرئيسي في الشارع", water},
+	{"المياه الواصلة للبيوت ملوثة ولونها غريب", water},
+	{"حفرة كبيرة في الشارع تسبب حوادث", roads},
+	{"الطريق مليء بالحفر بعد الأمطار", roads},
+	{"الشارع بحاجة إلى تعبيد وإصلاح الحفر", roads},
+	{"تشققات خطيرة في الطريق الرئيسي", roads},
+	{"انقطاع الكهرباء عن الحي طوال الليل", electricity},
+	{"عمود الكهرباء مائل وأسلاكه مكشوفة", electricity},
+	{"الكهرباء تنقطع كل يوم في المساء", electricity},
+	{"أسلاك كهرباء متدلية قرب المدرسة", electricity},
+}
+
+func TestTrainPredict(t *testing.T) {
+	model, err := Train(trainingExamples, TrainOptions{})
+	if err != nil {
+		t.Fatalf("Train: %v", err)
+	}
+	if model.Examples != len(trainingExamples) || len(model.Labels) != 3 {
+		t.Fatalf("model has %d examples and %d labels, want %d and 3", model.Examples, len(model.Labels), len(trainingExamples))
+	}
+
+	tests := []struct {
+		text string
+		want Label
+	}{
+		{"المياه مقطوعة من يومين", water},
+		{"حفرة عميقة في الطريق", roads},
+		{"انقطاع الكهرباء والأسلاك مكشوفة", electricity},
+	}
+	for _, tt := range tests {
+		prediction := model.Predict(tt.text)
+		if prediction == nil {
+			t.Errorf("Predict(%q) = nil, want %s", tt.text, tt.want.ID)
+			continue
+		}
+		if prediction.Label != tt.want {
+			t.Errorf("Predict(%q) = %s, want %s", tt.text, prediction.Label.ID, tt.want.ID)
+		}
+		if prediction.Confidence <= 1.0/3 || prediction.Confidence > 1 {
+			t.Errorf("Predict(%q) confidence = %v, want between 1/3 and 1", tt.text, prediction.Confidence)
+		}
+	}
+}
+
+func TestPredictUnknownText(t *testing.T) {
+	model, err := Train(trainingExamples, TrainOptions{})
+	if err != nil {
+		t.Fatalf("Train: %v", err)
+	}
+	if prediction := model.Predict("hello world"); prediction != nil {
+		t.Errorf("Predict on unseen terms = %+v, want nil", prediction)
+	}
+}
+
+func TestTrainErrors(t *testing.T) {
+	if _, err := Train(nil, TrainOptions{}); !errors.Is(err, ErrNoExamples) {
+		t.Errorf("Train(nil) = %v, want ErrNoExamples", err)
+	}
+	if _, err := Train(trainingExamples, TrainOptions{MinDocFrequency: 100}); err == nil {
+		t.Error("Train with an unreachable MinDocFrequency succeeded, want error")
+	}
+}
+
+func TestSaveLoadRoundTrip(t *testing.T) {
+	model, err := Train(trainingExamples, TrainOptions{})
+	if err != nil {
+		t.Fatalf("Train: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "models", "model.json")
+	if err := model.Save(path); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	if loaded.Name() != model.Name() {
+		t.Errorf("loaded name = %s, want %s", loaded.Name(), model.Name())
+	}
+	for _, example := range trainingExamples {
+		want := model.Predict(example.Text)
+		got := loaded.Predict(example.Text)
+		if want == nil || got == nil || got.Label != want.Label || math.Abs(got.Confidence-want.Confidence) > 1e-9 {
+			t.Errorf("Predict(%q) after reload = %+v, want %+v", example.Text, got, want)
+		}
+	}
+}
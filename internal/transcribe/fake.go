@@ -0,0 +1,54 @@
+package transcribe
+
+import (
+	"context"
+	"sync"
+)
+
+// fakeDefaultText is a plausible Jordanian Arabic voice complaint
+const fakeDefaultText = "السلام عليكم، صارلنا أسبوع المي مقطوعة عنا بالحي وما حدا رد علينا"
+
+// FakeTranscriber returns canned transcripts without network access. Texts
+// are returned in order and the last one repeats; Err, when set, is
+// returned instead.
+type FakeTranscriber struct {
+	Texts []string
+	Err   error
+
+	mu     sync.Mutex
+	audios []*Audio
+}
+
+func (f *FakeTranscriber) Name() string {
+	return "fake"
+}
+
+func (f *FakeTranscriber) Transcribe(ctx context.Context, audio *Audio) (*Transcript, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	call := len(f.audios)
+	f.audios = append(f.audios, audio)
+
+	if f.Err != nil {
+		return nil, f.Err
+	}
+
+	text := fakeDefaultText
+	if len(f.Texts) > 0 {
+		if call >= len(f.Texts) {
+			call = len(f.Texts) - 1
+		}
+		text = f.Texts[call]
+	}
+
+	return &Transcript{Text: text, Language: "ar"}, nil
+}
+
+// Audios returns every voice note the fake has received
+func (f *FakeTranscriber) Audios() []*Audio {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]*Audio(nil), f.audios...)
+}
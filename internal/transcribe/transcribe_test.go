@@ -0,0 +1,161 @@
+package transcribe
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hakim/backend/internal/safeurl"
+)
+
+func TestWhisperTranscribe(t *testing.T) {
+	var fields map[string]string
+	var fileName, fileData, auth string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/audio/transcriptions" {
+			http.NotFound(w, r)
+			return
+		}
+		auth = r.Header.Get("Authorization")
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		fields = make(map[string]string)
+		for key, values := range r.MultipartForm.Value {
+			fields[key] = values[0]
+		}
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+		data, _ := io.ReadAll(file)
+		fileName, fileData = header.Filename, string(data)
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"text":"المي مقطوعة","language":"arabic","duration":3.5}`))
+	}))
+	defer server.Close()
+
+	whisper := NewWhisperTranscriber(WhisperOptions{
+		BaseURL:  server.URL + "/v1/",
+		APIKey:   "secret",
+		Model:    "large-v3",
+		Language: "ar",
+	})
+	if whisper.Name() != "whisper:large-v3" {
+		t.Errorf("Name() = %s, want whisper:large-v3", whisper.Name())
+	}
+
+	transcript, err := whisper.Transcribe(context.Background(), &Audio{Data: []byte("audio"), FileName: "note.ogg"})
+	if err != nil {
+		t.Fatalf("Transcribe: %v", err)
+	}
+
+	if transcript.Text != "المي مقطوعة" || transcript.Language != "arabic" || transcript.Duration != 3500*time.Millisecond {
+		t.Errorf("transcript = %+v", transcript)
+	}
+	if auth != "Bearer secret" {
+		t.Errorf("Authorization = %q, want Bearer secret", auth)
+	}
+	if fileName != "note.ogg" || fileData != "audio" {
+		t.Errorf("uploaded file %q with %q, want note.ogg with audio", fileName, fileData)
+	}
+	want := map[string]string{
+		"model":           "large-v3",
+		"language":        "ar",
+		"response_format": "verbose_json",
+		"temperature":     "0",
+	}
+	for key, value := range want {
+		if fields[key] != value {
+			t.Errorf("field %s = %q, want %q", key, fields[key], value)
+		}
+	}
+	if _, ok := fields["prompt"]; ok {
+		t.Error("empty prompt was sent")
+	}
+}
+
+func TestWhisperTranscribeErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+	}{
+		{"error object", http.StatusBadRequest, `{"error":{"message":"unsupported format"}}`},
+		{"error status", http.StatusInternalServerError, `{"text":""}`},
+		{"not json", http.StatusBadGateway, `bad gateway`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			whisper := NewWhisperTranscriber(WhisperOptions{BaseURL: server.URL, Model: "base"})
+			if _, err := whisper.Transcribe(context.Background(), &Audio{Data: []byte("audio"), FileName: "note.ogg"}); err == nil {
+				t.Error("Transcribe succeeded, want error")
+			}
+		})
+	}
+}
+
+func TestFakeTranscriber(t *testing.T) {
+	fake := &FakeTranscriber{Texts: []string{"first", "second"}}
+	audio := &Audio{FileName: "note.ogg"}
+
+	for _, want := range []string{"first", "second", "second"} {
+		transcript, err := fake.Transcribe(context.Background(), audio)
+		if err != nil {
+			t.Fatalf("Transcribe: %v", err)
+		}
+		if transcript.Text != want {
+			t.Errorf("Transcribe() = %q, want %q", transcript.Text, want)
+		}
+	}
+	if got := len(fake.Audios()); got != 3 {
+		t.Errorf("Audios() has %d entries, want 3", got)
+	}
+
+	failing := &FakeTranscriber{Err: errors.New("down")}
+	if _, err := failing.Transcribe(context.Background(), audio); err == nil {
+		t.Error("Transcribe with Err set succeeded, want error")
+	}
+}
+
+func TestServiceRejectsURLsOutsidePolicy(t *testing.T) {
+	policy, err := safeurl.NewPolicy([]string{"project.supabase.co/storage/v1/object/public/voice"})
+	if err != nil {
+		t.Fatalf("NewPolicy: %v", err)
+	}
+	fake := &FakeTranscriber{}
+	service := NewService(fake, Options{MaxBytes: 1 << 20, Timeout: time.Second, Policy: policy})
+
+	tests := []struct {
+		url  string
+		want error
+	}{
+		{"http://project.supabase.co/storage/v1/object/public/voice/a.ogg", safeurl.ErrScheme},
+		{"https://evil.com/storage/v1/object/public/voice/a.ogg", safeurl.ErrNotAllowed},
+		{"https://project.supabase.co/storage/v1/object/public/voice/../private/a.ogg", safeurl.ErrNotAllowed},
+	}
+	for _, tt := range tests {
+		if _, err := service.Transcribe(context.Background(), tt.url, "a.ogg"); !errors.Is(err, tt.want) {
+			t.Errorf("Transcribe(%q) = %v, want %v", tt.url, err, tt.want)
+		}
+	}
+	if len(fake.Audios()) != 0 {
+		t.Error("a rejected voice note reached the transcriber")
+	}
+}
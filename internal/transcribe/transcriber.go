@@ -0,0 +1,196 @@
+// Package transcribe turns citizens' voice notes into text. Audio is
+// downloaded by the server under the same URL rules as images and handed to
+// a pluggable Transcriber, usually a self-hosted Whisper-compatible server.
+package transcribe
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hakim/backend/internal/config"
+	"github.com/hakim/backend/internal/safeurl"
+)
+
+var (
+	ErrTooLarge        = errors.New("voice note exceeds the size limit")
+	ErrUnsupportedType = errors.New("attachment is not a supported audio type")
+	ErrEmpty           = errors.New("no speech was recognized")
+)
+
+// Transcriber converts recorded speech to text
+type Transcriber interface {
+	// Name identifies the transcriber in logs and on stored transcripts
+	Name() string
+	Transcribe(ctx context.Context, audio *Audio) (*Transcript, error)
+}
+
+// Audio is a downloaded voice note
+type Audio struct {
+	Data []byte
+	// FileName helps the server pick a decoder; it may be a guess
+	FileName string
+	MimeType string
+}
+
+// Transcript is the recognized text of a voice note
+type Transcript struct {
+	Text     string
+	Language string
+	Duration time.Duration
+}
+
+// audioTypes are the sniffed content types accepted as audio. Common voice
+// note formats such as m4a, amr and opus in CAF are not recognized by the
+// sniffer and show as application/octet-stream; the transcriber decides
+// whether those decode.
+var audioTypes = map[string]bool{
+	"audio/aiff":               true,
+	"audio/basic":              true,
+	"audio/mpeg":               true,
+	"audio/wave":               true,
+	"application/ogg":          true,
+	"video/mp4":                true,
+	"video/webm":               true,
+	"application/octet-stream": true,
+}
+
+type Options struct {
+	// MaxBytes is the largest download accepted
+	MaxBytes int64
+	// Timeout bounds the download and the transcription of one voice note
+	Timeout time.Duration
+	// Policy is the allowlist attachment URLs must match
+	Policy *safeurl.Policy
+	// Authorize adds credentials to requests for attachments in private
+	// storage. It is optional.
+	Authorize func(*http.Request)
+}
+
+// Service downloads voice notes and transcribes them
+type Service struct {
+	transcriber Transcriber
+	httpClient  *http.Client
+	opts        Options
+}
+
+func NewService(transcriber Transcriber, opts Options) *Service {
+	return &Service{
+		transcriber: transcriber,
+		httpClient:  safeurl.NewHTTPClient(opts.Timeout),
+		opts:        opts,
+	}
+}
+
+// Name identifies the underlying transcriber
+func (s *Service) Name() string {
+	return s.transcriber.Name()
+}
+
+// Transcribe downloads the voice note at fileURL and returns its text
+func (s *Service) Transcribe(ctx context.Context, fileURL, fileName string) (*Transcript, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.opts.Timeout)
+	defer cancel()
+
+	audio, err := s.fetch(ctx, fileURL)
+	if err != nil {
+		return nil, err
+	}
+	audio.FileName = fileName
+	if audio.FileName == "" {
+		audio.FileName = "voice-note"
+	}
+
+	transcript, err := s.transcriber.Transcribe(ctx, audio)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", s.transcriber.Name(), err)
+	}
+	transcript.Text = strings.TrimSpace(transcript.Text)
+	if transcript.Text == "" {
+		return nil, ErrEmpty
+	}
+	return transcript, nil
+}
+
+// fetch downloads at most MaxBytes of audio, failing early on a declared
+// size or type that is out of bounds
+func (s *Service) fetch(ctx context.Context, fileURL string) (*Audio, error) {
+	if err := s.opts.Policy.Check(fileURL); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fileURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid voice note URL: %w", err)
+	}
+	if s.opts.Authorize != nil {
+		s.opts.Authorize(req)
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download voice note: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("failed to download voice note: status %d", resp.StatusCode)
+	}
+	if resp.ContentLength > s.opts.MaxBytes {
+		return nil, fmt.Errorf("%w: %d bytes", ErrTooLarge, resp.ContentLength)
+	}
+	declared, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if declared != "" && !audioTypes[declared] && !strings.HasPrefix(declared, "audio/") {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedType, declared)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, s.opts.MaxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to download voice note: %w", err)
+	}
+	if int64(len(data)) > s.opts.MaxBytes {
+		return nil, fmt.Errorf("%w: more than %d bytes", ErrTooLarge, s.opts.MaxBytes)
+	}
+
+	// The sniffed type is trusted over what the server claims
+	sniffed := http.DetectContentType(data)
+	if !audioTypes[sniffed] {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedType, sniffed)
+	}
+
+	mimeType := declared
+	if mimeType == "" || mimeType == "application/octet-stream" {
+		mimeType = sniffed
+	}
+	return &Audio{Data: data, MimeType: mimeType}, nil
+}
+
+// NewFromConfig builds the transcriber named in TRANSCRIBER. nil means voice
+// notes are stored but not transcribed.
+func NewFromConfig(cfg *config.Config) (Transcriber, error) {
+	switch strings.ToLower(cfg.Transcriber) {
+	case "":
+		return nil, nil
+	case "whisper":
+		if cfg.WhisperBaseURL == "" {
+			return nil, errors.New("WHISPER_BASE_URL is required for the whisper transcriber")
+		}
+		return NewWhisperTranscriber(WhisperOptions{
+			BaseURL:  cfg.WhisperBaseURL,
+			APIKey:   cfg.WhisperAPIKey,
+			Model:    cfg.WhisperModel,
+			Language: cfg.WhisperLanguage,
+			Prompt:   cfg.WhisperPrompt,
+			Timeout:  time.Duration(cfg.VoiceTimeoutSeconds) * time.Second,
+		}), nil
+	case "fake":
+		return &FakeTranscriber{}, nil
+	default:
+		return nil, fmt.Errorf("unknown transcriber: %q", cfg.Transcriber)
+	}
+}
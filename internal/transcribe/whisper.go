@@ -0,0 +1,133 @@
+package transcribe
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type WhisperOptions struct {
+	// BaseURL is the API root, e.g. http://whisper:8000/v1
+	BaseURL string
+	APIKey  string
+	Model   string
+	// Language is the ISO 639-1 code of the expected speech; empty lets the
+	// server detect it
+	Language string
+	// Prompt is text in the style of the expected speech, which steers
+	// recognition towards dialect words and spelling
+	Prompt  string
+	Timeout time.Duration
+}
+
+// WhisperTranscriber talks to any /audio/transcriptions endpoint in the
+// OpenAI format, such as faster-whisper-server, LocalAI or OpenAI itself
+type WhisperTranscriber struct {
+	opts       WhisperOptions
+	httpClient *http.Client
+}
+
+// whisperResponse is the verbose_json transcription response
+type whisperResponse struct {
+	Text     string  `json:"text"`
+	Language string  `json:"language"`
+	Duration float64 `json:"duration"`
+	Error    *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func NewWhisperTranscriber(opts WhisperOptions) *WhisperTranscriber {
+	if opts.Timeout == 0 {
+		opts.Timeout = 120 * time.Second
+	}
+	opts.BaseURL = strings.TrimSuffix(opts.BaseURL, "/")
+
+	return &WhisperTranscriber{
+		opts:       opts,
+		httpClient: &http.Client{Timeout: opts.Timeout},
+	}
+}
+
+func (w *WhisperTranscriber) Name() string {
+	return "whisper:" + w.opts.Model
+}
+
+func (w *WhisperTranscriber) Transcribe(ctx context.Context, audio *Audio) (*Transcript, error) {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+
+	file, err := form.CreateFormFile("file", audio.FileName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+	if _, err := file.Write(audio.Data); err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+	fields := [][2]string{
+		{"model", w.opts.Model},
+		{"language", w.opts.Language},
+		{"prompt", w.opts.Prompt},
+		{"response_format", "verbose_json"},
+		{"temperature", "0"},
+	}
+	for _, field := range fields {
+		if field[1] == "" {
+			continue
+		}
+		if err := form.WriteField(field[0], field[1]); err != nil {
+			return nil, fmt.Errorf("failed to build request: %w", err)
+		}
+	}
+	if err := form.Close(); err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", w.opts.BaseURL+"/audio/transcriptions", &body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	if w.opts.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+w.opts.APIKey)
+	}
+
+	resp, err := w.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("API request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	var result whisperResponse
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse response (status %d): %w", resp.StatusCode, err)
+	}
+	if result.Error != nil {
+		return nil, fmt.Errorf("transcriber error: %s", result.Error.Message)
+	}
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("transcriber error: status %d", resp.StatusCode)
+	}
+
+	language := result.Language
+	if language == "" {
+		language = w.opts.Language
+	}
+
+	return &Transcript{
+		Text:     result.Text,
+		Language: language,
+		Duration: time.Duration(result.Duration * float64(time.Second)),
+	}, nil
+}
@@ -0,0 +1,368 @@
+package supabase
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hakim/backend/internal/models"
+)
+
+// ============================================
+// REJECTION APPEAL METHODS
+// ============================================
+
+var (
+	ErrNotAppealable    = errors.New("only automatically rejected complaints can be appealed")
+	ErrAlreadyAppealed  = errors.New("this rejection has already been appealed")
+	ErrAppealNotPending = errors.New("appeal has already been decided")
+	ErrAppealNotFound   = errors.New("appeal not found")
+)
+
+// appealComplaintColumns are the complaint columns embedded in an appeal
+const appealComplaintColumns = "id,tracking_number,user_id,title,description,status,category_id,department_id,created_at"
+
+type appealRow struct {
+	ID               string   `json:"id"`
+	ComplaintID      string   `json:"complaint_id"`
+	UserID           string   `json:"user_id"`
+	Statement        string   `json:"statement"`
+	Status           string   `json:"status"`
+	RejectionReason  *string  `json:"rejection_reason"`
+	RejectionSource  *string  `json:"rejection_source"`
+	RejectionRuleIDs []string `json:"rejection_rule_ids"`
+	ReviewedBy       *string  `json:"reviewed_by"`
+	ReviewNote       *string  `json:"review_note"`
+	ReviewedAt       *string  `json:"reviewed_at"`
+	CreatedAt        string   `json:"created_at"`
+	Complaints       *struct {
+		ID             string  `json:"id"`
+		TrackingNumber string  `json:"tracking_number"`
+		UserID         string  `json:"user_id"`
+		Title          string  `json:"title"`
+		Description    string  `json:"description"`
+		Status         string  `json:"status"`
+		CategoryID     *string `json:"category_id"`
+		DepartmentID   *string `json:"department_id"`
+		CreatedAt      string  `json:"created_at"`
+	} `json:"complaints,omitempty"`
+}
+
+func rowToAppeal(row *appealRow) *models.RejectionAppeal {
+	appeal := &models.RejectionAppeal{
+		ID:          uuid.MustParse(row.ID),
+		ComplaintID: uuid.MustParse(row.ComplaintID),
+		UserID:      uuid.MustParse(row.UserID),
+		Statement:   row.Statement,
+		Status:      models.AppealStatus(row.Status),
+		ReviewedBy:  parseOptionalUUID(row.ReviewedBy),
+	}
+	if row.RejectionReason != nil {
+		appeal.RejectionReason = *row.RejectionReason
+	}
+	if row.RejectionSource != nil {
+		appeal.RejectionSource = *row.RejectionSource
+	}
+	for _, id := range row.RejectionRuleIDs {
+		if ruleID, err := uuid.Parse(id); err == nil {
+			appeal.RejectionRuleIDs = append(appeal.RejectionRuleIDs, ruleID)
+		}
+	}
+	if row.ReviewNote != nil {
+		appeal.ReviewNote = *row.ReviewNote
+	}
+	if row.ReviewedAt != nil {
+		if t, err := time.Parse(time.RFC3339, *row.ReviewedAt); err == nil {
+			appeal.ReviewedAt = &t
+		}
+	}
+	if t, err := time.Parse(time.RFC3339, row.CreatedAt); err == nil {
+		appeal.CreatedAt = t
+	}
+
+	if row.Complaints != nil {
+		complaint := &models.Complaint{
+			ID:             uuid.MustParse(row.Complaints.ID),
+			TrackingNumber: row.Complaints.TrackingNumber,
+			UserID:         uuid.MustParse(row.Complaints.UserID),
+			Title:          row.Complaints.Title,
+			Description:    row.Complaints.Description,
+			Status:         models.ComplaintStatus(row.Complaints.Status),
+		}
+		if row.Complaints.CategoryID != nil {
+			complaint.CategoryID = uuid.MustParse(*row.Complaints.CategoryID)
+		}
+		if row.Complaints.DepartmentID != nil {
+			complaint.DepartmentID = uuid.MustParse(*row.Complaints.DepartmentID)
+		}
+		if t, err := time.Parse(time.RFC3339, row.Complaints.CreatedAt); err == nil {
+			complaint.CreatedAt = t
+		}
+		appeal.Complaint = complaint
+	}
+
+	return appeal
+}
+
+// AppealRejection files the citizen's appeal against an automatic rejection
+func (c *Client) AppealRejection(token, complaintID, userID, statement string) (*models.RejectionAppeal, error) {
+	complaint, err := c.GetComplaintSystem(complaintID)
+	if err != nil || complaint.UserID.String() != userID {
+		return nil, fmt.Errorf("complaint not found")
+	}
+	if complaint.Status != models.StatusRejected || complaint.RejectionSource == "" {
+		return nil, ErrNotAppealable
+	}
+
+	existing, err := c.GetComplaintAppeal(token, complaintID)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, ErrAlreadyAppealed
+	}
+
+	// Snapshot the rejection so statistics survive later changes to the complaint
+	insert := map[string]interface{}{
+		"complaint_id":       complaintID,
+		"user_id":            userID,
+		"statement":          statement,
+		"rejection_reason":   complaint.RejectionReason,
+		"rejection_source":   complaint.RejectionSource,
+		"rejection_rule_ids": uuidStrings(complaint.RejectionRuleIDs),
+	}
+
+	resp, err := c.doRequest("POST", "/rest/v1/rejection_appeals?select=*", insert, token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create appeal: %w", err)
+	}
+
+	var rows []appealRow
+	if err := json.Unmarshal(resp, &rows); err != nil {
+		return nil, fmt.Errorf("failed to parse appeal: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("appeal was not created")
+	}
+
+	return rowToAppeal(&rows[0]), nil
+}
+
+// GetComplaintAppeal returns the appeal filed against a complaint's
+// rejection, or nil if there is none
+func (c *Client) GetComplaintAppeal(token, complaintID string) (*models.RejectionAppeal, error) {
+	resp, err := c.doRequest("GET", "/rest/v1/rejection_appeals?select=*&complaint_id=eq."+complaintID, nil, token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get appeal: %w", err)
+	}
+
+	var rows []appealRow
+	if err := json.Unmarshal(resp, &rows); err != nil {
+		return nil, fmt.Errorf("failed to parse appeal: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	return rowToAppeal(&rows[0]), nil
+}
+
+// GetAppeals lists appeals for moderation, oldest first
+func (c *Client) GetAppeals(token, status string, page, limit int) ([]models.RejectionAppeal, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	offset := (page - 1) * limit
+
+	query := "/rest/v1/rejection_appeals?select=" + url.QueryEscape("*,complaints("+appealComplaintColumns+")") +
+		"&order=created_at.asc&offset=" + strconv.Itoa(offset) + "&limit=" + strconv.Itoa(limit)
+	if status != "" {
+		query += "&status=eq." + status
+	}
+
+	resp, err := c.doRequest("GET", query, nil, token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get appeals: %w", err)
+	}
+
+	var rows []appealRow
+	if err := json.Unmarshal(resp, &rows); err != nil {
+		return nil, fmt.Errorf("failed to parse appeals: %w", err)
+	}
+
+	appeals := make([]models.RejectionAppeal, 0, len(rows))
+	for i := range rows {
+		appeals = append(appeals, *rowToAppeal(&rows[i]))
+	}
+
+	return appeals, nil
+}
+
+// DecideAppeal upholds or overturns a rejection. An overturned complaint goes
+// back to submitted with screening switched off; the caller queues it for
+// classification.
+func (c *Client) DecideAppeal(token, appealID string, overturn bool, decision *models.AppealDecision, reviewer *UserProfile) (*models.RejectionAppeal, error) {
+	query := "/rest/v1/rejection_appeals?select=" + url.QueryEscape("*,complaints("+appealComplaintColumns+")") + "&id=eq." + appealID
+	resp, err := c.doRequest("GET", query, nil, token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get appeal: %w", err)
+	}
+
+	var rows []appealRow
+	if err := json.Unmarshal(resp, &rows); err != nil {
+		return nil, fmt.Errorf("failed to parse appeal: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, ErrAppealNotFound
+	}
+	appeal := rowToAppeal(&rows[0])
+	if appeal.Status != models.AppealPending {
+		return nil, ErrAppealNotPending
+	}
+	complaint := appeal.Complaint
+	if complaint == nil {
+		return nil, fmt.Errorf("complaint not found")
+	}
+
+	status := models.AppealUpheld
+	if overturn {
+		status = models.AppealOverturned
+	}
+
+	update := map[string]interface{}{
+		"status":      string(status),
+		"reviewed_by": reviewer.ID.String(),
+		"reviewed_at": time.Now().UTC().Format(time.RFC3339),
+	}
+	if decision.Note != "" {
+		update["review_note"] = decision.Note
+	}
+
+	// The status filter stops two moderators deciding the same appeal
+	resp, err = c.doRequest("PATCH", "/rest/v1/rejection_appeals?select=*&status=eq.pending&id=eq."+appealID, update, token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update appeal: %w", err)
+	}
+	var updated []appealRow
+	if err := json.Unmarshal(resp, &updated); err != nil {
+		return nil, fmt.Errorf("failed to parse appeal: %w", err)
+	}
+	if len(updated) == 0 {
+		return nil, ErrAppealNotPending
+	}
+
+	if overturn {
+		if err := c.reinstateComplaint(token, complaint, decision.Note, reviewer.ID.String()); err != nil {
+			// Put the appeal back so it can be decided again
+			reopen := map[string]interface{}{
+				"status":      string(models.AppealPending),
+				"reviewed_by": nil,
+				"reviewed_at": nil,
+				"review_note": nil,
+			}
+			_, _ = c.doRequest("PATCH", "/rest/v1/rejection_appeals?status=eq."+string(models.AppealOverturned)+"&id=eq."+appealID, reopen, token)
+			return nil, err
+		}
+		c.createNotification("", complaint.UserID.String(), complaint.ID.String(), "appeal_accepted",
+			"Appeal accepted", "تم قبول الاعتراض",
+			"Your appeal was accepted and complaint "+complaint.TrackingNumber+" is being processed",
+			"تم قبول اعتراضك وجاري معالجة الشكوى "+complaint.TrackingNumber)
+	} else {
+		body, bodyAr := "Your appeal for complaint "+complaint.TrackingNumber+" was reviewed and the rejection stands",
+			"تمت مراجعة اعتراضك على الشكوى "+complaint.TrackingNumber+" وتم تأكيد الرفض"
+		if decision.Note != "" {
+			body += ": " + decision.Note
+			bodyAr += ": " + decision.Note
+		}
+		c.createNotification("", complaint.UserID.String(), complaint.ID.String(), "appeal_rejected",
+			"Appeal not accepted", "لم يتم قبول الاعتراض", body, bodyAr)
+	}
+
+	decided := rowToAppeal(&updated[0])
+	decided.Complaint = complaint
+	return decided, nil
+}
+
+// reinstateComplaint returns an overturned complaint to the normal flow
+func (c *Client) reinstateComplaint(token string, complaint *models.Complaint, note, changedBy string) error {
+	update := map[string]interface{}{
+		"status":                string(models.StatusSubmitted),
+		"classification_status": ClassificationPending,
+		"screening_overridden":  true,
+	}
+	if _, err := c.doRequest("PATCH", "/rest/v1/complaints?id=eq."+complaint.ID.String(), update, token); err != nil {
+		return fmt.Errorf("failed to reinstate complaint: %w", err)
+	}
+
+	historyNote := "تم قبول الاعتراض وإلغاء الرفض التلقائي"
+	if note != "" {
+		historyNote += ": " + note
+	}
+	historyInsert := map[string]interface{}{
+		"complaint_id": complaint.ID.String(),
+		"old_status":   string(models.StatusRejected),
+		"new_status":   string(models.StatusSubmitted),
+		"changed_by":   changedBy,
+		"notes":        historyNote,
+	}
+	_, _ = c.doRequest("POST", "/rest/v1/status_history", historyInsert, token)
+
+	complaint.Status = models.StatusSubmitted
+	return nil
+}
+
+// GetAppealStats returns override statistics per rejection source and per
+// screening rule, most overturned rules first
+func (c *Client) GetAppealStats(token string) (*models.AppealStats, error) {
+	resp, err := c.doRequest("GET", "/rest/v1/rejection_source_stats?select=*", nil, token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rejection stats: %w", err)
+	}
+
+	var sources []models.RejectionSourceStat
+	if err := json.Unmarshal(resp, &sources); err != nil {
+		return nil, fmt.Errorf("failed to parse rejection stats: %w", err)
+	}
+	for i := range sources {
+		s := &sources[i]
+		if s.Rejections > 0 {
+			s.AppealRate = float64(s.Appeals) / float64(s.Rejections)
+		}
+		if decided := s.Upheld + s.Overturned; decided > 0 {
+			s.OverturnRate = float64(s.Overturned) / float64(decided)
+		}
+	}
+
+	rules, err := c.getRuleOverrideStats(token)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.AppealStats{Sources: sources, Rules: rules}, nil
+}
+
+// GetRuleOverrideStats loads per-rule appeal outcomes for the rule engine
+func (c *Client) GetRuleOverrideStats() ([]models.RuleOverrideStat, error) {
+	return c.getRuleOverrideStats("")
+}
+
+func (c *Client) getRuleOverrideStats(token string) ([]models.RuleOverrideStat, error) {
+	resp, err := c.doRequest("GET", "/rest/v1/rejection_rule_stats?select=*&order=overturned.desc,rejections.desc", nil, token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rule override stats: %w", err)
+	}
+
+	var stats []models.RuleOverrideStat
+	if err := json.Unmarshal(resp, &stats); err != nil {
+		return nil, fmt.Errorf("failed to parse rule override stats: %w", err)
+	}
+
+	return stats, nil
+}
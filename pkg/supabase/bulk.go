@@ -0,0 +1,386 @@
+package supabase
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hakim/backend/internal/models"
+)
+
+// ============================================
+// BULK OPERATION METHODS
+// ============================================
+
+// FindComplaintIDs returns the IDs of complaints matching the filter, up to max
+func (c *Client) FindComplaintIDs(token string, filter *models.ComplaintFilter, max int) ([]string, error) {
+	query := "/rest/v1/complaints?select=id&merged_into=is.null&order=created_at.asc&limit=" + strconv.Itoa(max)
+
+	if filter.Status != "" {
+		query += "&status=eq." + filter.Status
+	}
+	if filter.Priority != "" {
+		query += "&priority=eq." + filter.Priority
+	}
+	if filter.DepartmentID != "" {
+		query += "&department_id=eq." + filter.DepartmentID
+	}
+	if filter.CategoryID != "" {
+		query += "&category_id=eq." + filter.CategoryID
+	}
+	if filter.CreatedFrom != "" {
+		query += "&created_at=gte." + url.QueryEscape(filter.CreatedFrom)
+	}
+	if filter.CreatedTo != "" {
+		query += "&created_at=lte." + url.QueryEscape(filter.CreatedTo)
+	}
+
+	resp, err := c.doRequest("GET", query, nil, token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get complaints: %w", err)
+	}
+
+	var rows []struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(resp, &rows); err != nil {
+		return nil, fmt.Errorf("failed to parse complaints: %w", err)
+	}
+
+	ids := make([]string, 0, len(rows))
+	for _, row := range rows {
+		ids = append(ids, row.ID)
+	}
+
+	return ids, nil
+}
+
+// UpdateComplaintPriority changes a complaint's priority and records it in the history
+func (c *Client) UpdateComplaintPriority(token, id, priority, note, changedBy string) (*models.Complaint, error) {
+	update := map[string]interface{}{
+		"priority": priority,
+	}
+
+	query := "/rest/v1/complaints?select=" + url.QueryEscape(complaintSelectFields) + "&id=eq." + id
+
+	resp, err := c.doRequest("PATCH", query, update, token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update complaint priority: %w", err)
+	}
+
+	var rows []complaintRow
+	if err := json.Unmarshal(resp, &rows); err != nil {
+		return nil, fmt.Errorf("failed to parse complaint: %w", err)
+	}
+
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("complaint not found")
+	}
+
+	historyNote := "تم تغيير الأولوية إلى " + priority
+	if note != "" {
+		historyNote += ": " + note
+	}
+	historyInsert := map[string]interface{}{
+		"complaint_id": id,
+		"old_status":   rows[0].Status,
+		"new_status":   rows[0].Status,
+		"changed_by":   changedBy,
+		"notes":        historyNote,
+	}
+	_, _ = c.doRequest("POST", "/rest/v1/status_history", historyInsert, token)
+
+	return rowToComplaint(&rows[0]), nil
+}
+
+// AddComplaintTag appends a staff tag to a complaint and records it in the history
+func (c *Client) AddComplaintTag(token, id, tag, changedBy string) error {
+	resp, err := c.doRequest("GET", "/rest/v1/complaints?select=status,tags&id=eq."+id, nil, token)
+	if err != nil {
+		return fmt.Errorf("failed to get complaint: %w", err)
+	}
+
+	var rows []struct {
+		Status string   `json:"status"`
+		Tags   []string `json:"tags"`
+	}
+	if err := json.Unmarshal(resp, &rows); err != nil {
+		return fmt.Errorf("failed to parse complaint: %w", err)
+	}
+	if len(rows) == 0 {
+		return fmt.Errorf("complaint not found")
+	}
+
+	for _, existing := range rows[0].Tags {
+		if existing == tag {
+			return nil
+		}
+	}
+
+	update := map[string]interface{}{
+		"tags": append(rows[0].Tags, tag),
+	}
+	if _, err := c.doRequest("PATCH", "/rest/v1/complaints?id=eq."+id, update, token); err != nil {
+		return fmt.Errorf("failed to tag complaint: %w", err)
+	}
+
+	historyInsert := map[string]interface{}{
+		"complaint_id": id,
+		"old_status":   rows[0].Status,
+		"new_status":   rows[0].Status,
+		"changed_by":   changedBy,
+		"notes":        "تمت إضافة الوسم: " + tag,
+	}
+	_, _ = c.doRequest("POST", "/rest/v1/status_history", historyInsert, token)
+
+	return nil
+}
+
+// bulkJobFields leaves out the queued item IDs, which can be long
+const bulkJobFields = "id,action,status,total,processed,succeeded,failed,results,error,created_by,created_at,completed_at"
+
+type bulkJobRow struct {
+	ID          string                  `json:"id"`
+	Action      string                  `json:"action"`
+	Status      string                  `json:"status"`
+	Total       int                     `json:"total"`
+	Processed   int                     `json:"processed"`
+	Succeeded   int                     `json:"succeeded"`
+	Failed      int                     `json:"failed"`
+	Results     []models.BulkItemResult `json:"results"`
+	Error       *string                 `json:"error"`
+	CreatedBy   string                  `json:"created_by"`
+	CreatedAt   string                  `json:"created_at"`
+	CompletedAt *string                 `json:"completed_at"`
+}
+
+func rowToBulkJob(row *bulkJobRow) *models.BulkJob {
+	job := &models.BulkJob{
+		ID:        uuid.MustParse(row.ID),
+		Action:    models.BulkAction(row.Action),
+		Status:    models.BulkJobStatus(row.Status),
+		Total:     row.Total,
+		Processed: row.Processed,
+		Succeeded: row.Succeeded,
+		Failed:    row.Failed,
+		Results:   row.Results,
+		CreatedBy: uuid.MustParse(row.CreatedBy),
+	}
+	if row.Error != nil {
+		job.Error = *row.Error
+	}
+	if t, err := time.Parse(time.RFC3339, row.CreatedAt); err == nil {
+		job.CreatedAt = t
+	}
+	if row.CompletedAt != nil {
+		if t, err := time.Parse(time.RFC3339, *row.CompletedAt); err == nil {
+			job.CompletedAt = &t
+		}
+	}
+	return job
+}
+
+// ErrBulkJobLost means a worker no longer holds the job it was running,
+// because it was marked abandoned
+var ErrBulkJobLost = errors.New("bulk job is no longer held by this worker")
+
+// QueuedBulkJob is a claimed bulk job with what a worker needs to run it
+type QueuedBulkJob struct {
+	Job     *models.BulkJob
+	Request models.BulkOperationRequest
+	ItemIDs []string
+}
+
+// CreateBulkJob queues a bulk job for the given complaints. results holds
+// items already settled when the job was queued.
+func (c *Client) CreateBulkJob(token string, req *models.BulkOperationRequest, itemIDs []string, results []models.BulkItemResult, createdBy string) (*models.BulkJob, error) {
+	if results == nil {
+		results = []models.BulkItemResult{}
+	}
+	insert := map[string]interface{}{
+		"action":     string(req.Action),
+		"status":     string(models.BulkJobQueued),
+		"total":      len(itemIDs) + len(results),
+		"processed":  len(results),
+		"failed":     len(results),
+		"results":    results,
+		"request":    req,
+		"item_ids":   itemIDs,
+		"created_by": createdBy,
+	}
+
+	resp, err := c.doRequest("POST", "/rest/v1/bulk_jobs?select="+bulkJobFields, insert, token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create bulk job: %w", err)
+	}
+
+	var rows []bulkJobRow
+	if err := json.Unmarshal(resp, &rows); err != nil {
+		return nil, fmt.Errorf("failed to parse bulk job: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("bulk job was not created")
+	}
+
+	return rowToBulkJob(&rows[0]), nil
+}
+
+// ClaimBulkJob locks the oldest queued bulk job for this worker, or returns
+// nil when the queue is empty
+func (c *Client) ClaimBulkJob(workerID string) (*QueuedBulkJob, error) {
+	body := map[string]interface{}{
+		"p_worker": workerID,
+		"p_limit":  1,
+	}
+
+	resp, err := c.doRequest("POST", "/rest/v1/rpc/claim_bulk_jobs", body, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim bulk job: %w", err)
+	}
+
+	var rows []struct {
+		bulkJobRow
+		Request models.BulkOperationRequest `json:"request"`
+		ItemIDs []string                    `json:"item_ids"`
+	}
+	if err := json.Unmarshal(resp, &rows); err != nil {
+		return nil, fmt.Errorf("failed to parse bulk job: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	return &QueuedBulkJob{
+		Job:     rowToBulkJob(&rows[0].bulkJobRow),
+		Request: rows[0].Request,
+		ItemIDs: rows[0].ItemIDs,
+	}, nil
+}
+
+// SaveBulkJob writes a claimed job's progress. A running job's lock is
+// refreshed; any other status releases it. ErrBulkJobLost is returned when
+// the worker no longer holds the job.
+func (c *Client) SaveBulkJob(workerID string, job *models.BulkJob) error {
+	update := map[string]interface{}{
+		"status":    string(job.Status),
+		"processed": job.Processed,
+		"succeeded": job.Succeeded,
+		"failed":    job.Failed,
+		"results":   job.Results,
+	}
+	if job.Status == models.BulkJobRunning {
+		update["locked_at"] = time.Now().UTC().Format(time.RFC3339)
+	} else {
+		update["locked_at"] = nil
+		update["locked_by"] = nil
+	}
+	if job.Error != "" {
+		update["error"] = job.Error
+	}
+	if job.CompletedAt != nil {
+		update["completed_at"] = job.CompletedAt.UTC().Format(time.RFC3339)
+	}
+
+	query := "/rest/v1/bulk_jobs?select=id&id=eq." + job.ID.String() +
+		"&status=eq." + string(models.BulkJobRunning) +
+		"&locked_by=eq." + url.QueryEscape(workerID)
+
+	resp, err := c.doRequest("PATCH", query, update, "")
+	if err != nil {
+		return fmt.Errorf("failed to update bulk job: %w", err)
+	}
+
+	var rows []struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(resp, &rows); err != nil {
+		return fmt.Errorf("failed to parse bulk job: %w", err)
+	}
+	if len(rows) == 0 {
+		return ErrBulkJobLost
+	}
+	return nil
+}
+
+// FailStaleBulkJobs marks running jobs whose lock has not been refreshed
+// within staleAfter as failed. Their worker died partway, so some items may
+// have been applied; the saved results say which.
+func (c *Client) FailStaleBulkJobs(staleAfter time.Duration) error {
+	now := time.Now().UTC()
+	update := map[string]interface{}{
+		"status":       string(models.BulkJobFailed),
+		"error":        "job was interrupted; results list the items that were processed",
+		"locked_at":    nil,
+		"locked_by":    nil,
+		"completed_at": now.Format(time.RFC3339),
+	}
+
+	query := "/rest/v1/bulk_jobs?status=eq." + string(models.BulkJobRunning) +
+		"&locked_at=lt." + url.QueryEscape(now.Add(-staleAfter).Format(time.RFC3339))
+
+	if _, err := c.doRequest("PATCH", query, update, ""); err != nil {
+		return fmt.Errorf("failed to fail stale bulk jobs: %w", err)
+	}
+	return nil
+}
+
+// visibleIDsBatch is how many IDs are checked per request
+const visibleIDsBatch = 100
+
+// VisibleComplaintIDs returns the subset of ids the caller can see
+func (c *Client) VisibleComplaintIDs(token string, ids []string) ([]string, error) {
+	visible := make([]string, 0, len(ids))
+	if len(ids) == 0 {
+		return visible, nil
+	}
+
+	found := make(map[string]bool, len(ids))
+	// Batched to keep the URL short
+	for start := 0; start < len(ids); start += visibleIDsBatch {
+		end := min(start+visibleIDsBatch, len(ids))
+		query := "/rest/v1/complaints?select=id&id=in.(" + strings.Join(ids[start:end], ",") + ")"
+
+		resp, err := c.doRequest("GET", query, nil, token)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get complaints: %w", err)
+		}
+
+		var rows []struct {
+			ID string `json:"id"`
+		}
+		if err := json.Unmarshal(resp, &rows); err != nil {
+			return nil, fmt.Errorf("failed to parse complaints: %w", err)
+		}
+		for _, row := range rows {
+			found[row.ID] = true
+		}
+	}
+	for _, id := range ids {
+		if found[id] {
+			visible = append(visible, id)
+		}
+	}
+	return visible, nil
+}
+
+func (c *Client) GetBulkJob(token, id string) (*models.BulkJob, error) {
+	resp, err := c.doRequest("GET", "/rest/v1/bulk_jobs?select="+bulkJobFields+"&id=eq."+id, nil, token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bulk job: %w", err)
+	}
+
+	var rows []bulkJobRow
+	if err := json.Unmarshal(resp, &rows); err != nil {
+		return nil, fmt.Errorf("failed to parse bulk job: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("bulk job not found")
+	}
+
+	return rowToBulkJob(&rows[0]), nil
+}
@@ -0,0 +1,224 @@
+package supabase
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hakim/backend/internal/models"
+)
+
+// ============================================
+// CLARIFICATION METHODS
+// ============================================
+
+var (
+	ErrNoOpenQuestions = errors.New("complaint has no open questions")
+	ErrNoAnswers       = errors.New("at least one question must be answered")
+)
+
+type clarificationRow struct {
+	ID          string  `json:"id"`
+	ComplaintID string  `json:"complaint_id"`
+	Round       int     `json:"round"`
+	Question    string  `json:"question"`
+	Answer      *string `json:"answer"`
+	Source      string  `json:"source"`
+	AnsweredAt  *string `json:"answered_at"`
+	CreatedAt   string  `json:"created_at"`
+}
+
+func rowToClarification(row *clarificationRow) models.ClarificationQuestion {
+	q := models.ClarificationQuestion{
+		ID:          uuid.MustParse(row.ID),
+		ComplaintID: uuid.MustParse(row.ComplaintID),
+		Round:       row.Round,
+		Question:    row.Question,
+		Source:      row.Source,
+	}
+	if row.Answer != nil {
+		q.Answer = *row.Answer
+	}
+	if row.AnsweredAt != nil {
+		if t, err := time.Parse(time.RFC3339, *row.AnsweredAt); err == nil {
+			q.AnsweredAt = &t
+		}
+	}
+	if t, err := time.Parse(time.RFC3339, row.CreatedAt); err == nil {
+		q.CreatedAt = t
+	}
+	return q
+}
+
+// RequestClarification puts a new round of questions to the citizen and
+// parks the complaint until they answer. Questions from the background
+// classifier are also sent as a notification; the pre-screen's are returned
+// in the submission response.
+func (c *Client) RequestClarification(complaint *models.Complaint, source string, questions []string, notify bool) ([]models.ClarificationQuestion, error) {
+	rounds, err := c.GetClarificationRounds(complaint.ID.String())
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([]map[string]interface{}, 0, len(questions))
+	for _, question := range questions {
+		rows = append(rows, map[string]interface{}{
+			"complaint_id": complaint.ID.String(),
+			"round":        rounds + 1,
+			"question":     strings.TrimSpace(question),
+			"source":       source,
+		})
+	}
+
+	resp, err := c.doRequest("POST", "/rest/v1/complaint_clarifications?select=*", rows, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to store clarifying questions: %w", err)
+	}
+
+	var inserted []clarificationRow
+	if err := json.Unmarshal(resp, &inserted); err != nil {
+		return nil, fmt.Errorf("failed to parse clarifying questions: %w", err)
+	}
+
+	if err := c.SetClassificationStatus(complaint.ID.String(), ClassificationNeedsInfo); err != nil {
+		return nil, err
+	}
+	complaint.ClassificationStatus = ClassificationNeedsInfo
+
+	if notify {
+		c.createNotification("", complaint.UserID.String(), complaint.ID.String(), "clarification_needed",
+			"More details needed", "نحتاج إلى تفاصيل إضافية",
+			"Please answer a few questions about complaint "+complaint.TrackingNumber+" so it can be routed",
+			"يرجى الإجابة على بعض الأسئلة حول الشكوى "+complaint.TrackingNumber+" ليتم توجيهها")
+	}
+
+	result := make([]models.ClarificationQuestion, 0, len(inserted))
+	for i := range inserted {
+		result = append(result, rowToClarification(&inserted[i]))
+	}
+	return result, nil
+}
+
+// GetClarificationRounds returns how many rounds of questions a complaint
+// has been asked
+func (c *Client) GetClarificationRounds(complaintID string) (int, error) {
+	query := "/rest/v1/complaint_clarifications?select=round&complaint_id=eq." + complaintID + "&order=round.desc&limit=1"
+	resp, err := c.doRequest("GET", query, nil, "")
+	if err != nil {
+		return 0, fmt.Errorf("failed to get clarification rounds: %w", err)
+	}
+
+	var rows []struct {
+		Round int `json:"round"`
+	}
+	if err := json.Unmarshal(resp, &rows); err != nil {
+		return 0, fmt.Errorf("failed to parse clarification rounds: %w", err)
+	}
+	if len(rows) == 0 {
+		return 0, nil
+	}
+	return rows[0].Round, nil
+}
+
+// GetClarifications lists the questions put to the citizen and their answers
+func (c *Client) GetClarifications(token, complaintID string) ([]models.ClarificationQuestion, error) {
+	query := "/rest/v1/complaint_clarifications?select=*&complaint_id=eq." + complaintID + "&order=round.asc,created_at.asc"
+	resp, err := c.doRequest("GET", query, nil, token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get clarifications: %w", err)
+	}
+
+	var rows []clarificationRow
+	if err := json.Unmarshal(resp, &rows); err != nil {
+		return nil, fmt.Errorf("failed to parse clarifications: %w", err)
+	}
+
+	questions := make([]models.ClarificationQuestion, 0, len(rows))
+	for i := range rows {
+		questions = append(questions, rowToClarification(&rows[i]))
+	}
+	return questions, nil
+}
+
+// AnswerClarifications stores the citizen's answers to the open round and
+// appends them to the complaint description, so staff, search and duplicate
+// detection see the details too. Questions left unanswered are closed with
+// the round. The caller queues the complaint for classification.
+func (c *Client) AnswerClarifications(complaintID, userID string, answers []models.ClarificationAnswer) (*models.Complaint, error) {
+	complaint, err := c.GetComplaintSystem(complaintID)
+	if err != nil || complaint.UserID.String() != userID {
+		return nil, fmt.Errorf("complaint not found")
+	}
+	if complaint.ClassificationStatus != ClassificationNeedsInfo {
+		return nil, ErrNoOpenQuestions
+	}
+
+	query := "/rest/v1/complaint_clarifications?select=*&answered_at=is.null&complaint_id=eq." + complaintID + "&order=created_at.asc"
+	resp, err := c.doRequest("GET", query, nil, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get clarifications: %w", err)
+	}
+	var open []clarificationRow
+	if err := json.Unmarshal(resp, &open); err != nil {
+		return nil, fmt.Errorf("failed to parse clarifications: %w", err)
+	}
+	if len(open) == 0 {
+		return nil, ErrNoOpenQuestions
+	}
+
+	given := make(map[string]string, len(answers))
+	for _, a := range answers {
+		if answer := strings.TrimSpace(a.Answer); answer != "" {
+			given[a.QuestionID.String()] = answer
+		}
+	}
+
+	var details strings.Builder
+	for _, q := range open {
+		if answer, ok := given[q.ID]; ok {
+			details.WriteString("\n- " + q.Question + " " + answer)
+		}
+	}
+	if details.Len() == 0 {
+		return nil, ErrNoAnswers
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	for _, q := range open {
+		update := map[string]interface{}{"answered_at": now}
+		if answer, ok := given[q.ID]; ok {
+			update["answer"] = answer
+		}
+		if _, err := c.doRequest("PATCH", "/rest/v1/complaint_clarifications?answered_at=is.null&id=eq."+q.ID, update, ""); err != nil {
+			return nil, fmt.Errorf("failed to store answer: %w", err)
+		}
+	}
+
+	description := complaint.Description + "\n\nتفاصيل إضافية:" + details.String()
+	update := map[string]interface{}{
+		"description":           description,
+		"classification_status": ClassificationPending,
+	}
+	// The status filter stops two submissions both appending their answers
+	// and queueing the complaint
+	resp, err = c.doRequest("PATCH", "/rest/v1/complaints?select=id&classification_status=eq."+ClassificationNeedsInfo+"&id=eq."+complaintID, update, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to update complaint: %w", err)
+	}
+	var updated []struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(resp, &updated); err != nil {
+		return nil, fmt.Errorf("failed to parse complaint: %w", err)
+	}
+	if len(updated) == 0 {
+		return nil, ErrNoOpenQuestions
+	}
+
+	complaint.Description = description
+	complaint.ClassificationStatus = ClassificationPending
+	return complaint, nil
+}
@@ -0,0 +1,212 @@
+package supabase
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hakim/backend/internal/models"
+)
+
+// ============================================
+// AI CLASSIFICATION METHODS
+// ============================================
+
+type classificationRow struct {
+	ID            string   `json:"id"`
+	ComplaintID   string   `json:"complaint_id"`
+	Source        string   `json:"source"`
+	Model         *string  `json:"model"`
+	CategoryID    *string  `json:"category_id"`
+	CategoryName  *string  `json:"category_name"`
+	Priority      *string  `json:"priority"`
+	Confidence    *float64 `json:"confidence"`
+	Summary       *string  `json:"summary"`
+	Sentiment     *string  `json:"sentiment"`
+	ImageAnalysis *string  `json:"image_analysis"`
+	Tags          []string `json:"tags"`
+	LatencyMs     int64    `json:"latency_ms"`
+	RawResponse   *string  `json:"raw_response"`
+	CreatedAt     string   `json:"created_at"`
+
+	SecondOpinionCategoryID *string  `json:"second_opinion_category_id"`
+	SecondOpinionConfidence *float64 `json:"second_opinion_confidence"`
+	SecondOpinionModel      *string  `json:"second_opinion_model"`
+	ModelDisagreement       bool     `json:"model_disagreement"`
+
+	RedactionKinds []string       `json:"redaction_kinds"`
+	Redactions     map[string]int `json:"redactions"`
+
+	ManipulationSignals []string `json:"manipulation_signals"`
+	Cached              bool     `json:"cached"`
+	VoiceTranscriptUsed bool     `json:"voice_transcript_used"`
+}
+
+func rowToClassification(row *classificationRow) *models.AIClassificationRecord {
+	record := &models.AIClassificationRecord{
+		ID:          uuid.MustParse(row.ID),
+		ComplaintID: uuid.MustParse(row.ComplaintID),
+		Source:      row.Source,
+		CategoryID:  parseOptionalUUID(row.CategoryID),
+		Tags:        row.Tags,
+		LatencyMs:   row.LatencyMs,
+
+		ManipulationSignals: row.ManipulationSignals,
+		Cached:              row.Cached,
+		VoiceTranscriptUsed: row.VoiceTranscriptUsed,
+	}
+	if row.Model != nil {
+		record.Model = *row.Model
+	}
+	if row.CategoryName != nil {
+		record.CategoryName = *row.CategoryName
+	}
+	if row.Priority != nil {
+		record.Priority = *row.Priority
+	}
+	if row.Confidence != nil {
+		record.Confidence = *row.Confidence
+	}
+	if row.Summary != nil {
+		record.Summary = *row.Summary
+	}
+	if row.Sentiment != nil {
+		record.Sentiment = *row.Sentiment
+	}
+	if row.ImageAnalysis != nil {
+		record.ImageAnalysis = *row.ImageAnalysis
+	}
+	if row.RawResponse != nil {
+		record.RawResponse = *row.RawResponse
+	}
+	if t, err := time.Parse(time.RFC3339, row.CreatedAt); err == nil {
+		record.CreatedAt = t
+	}
+	if row.SecondOpinionCategoryID != nil {
+		record.SecondOpinion = &models.ClassificationSecondOpinion{
+			CategoryID: parseOptionalUUID(row.SecondOpinionCategoryID),
+			Disagrees:  row.ModelDisagreement,
+		}
+		if row.SecondOpinionConfidence != nil {
+			record.SecondOpinion.Confidence = *row.SecondOpinionConfidence
+		}
+		if row.SecondOpinionModel != nil {
+			record.SecondOpinion.Model = *row.SecondOpinionModel
+		}
+	}
+	if row.RedactionKinds != nil {
+		record.Redaction = &models.ClassificationRedaction{
+			Kinds:  row.RedactionKinds,
+			Counts: row.Redactions,
+		}
+	}
+	return record
+}
+
+// RecordClassification stores the full output of a classification run
+func (c *Client) RecordClassification(complaintID string, result *ClassificationResult) error {
+	insert := map[string]interface{}{
+		"complaint_id":   complaintID,
+		"source":         result.Source,
+		"category_name":  result.CategoryName,
+		"priority":       result.Priority,
+		"confidence":     result.Confidence,
+		"summary":        result.Summary,
+		"sentiment":      result.Sentiment,
+		"image_analysis": result.ImageAnalysis,
+		"tags":           result.Tags,
+		"latency_ms":     result.LatencyMs,
+	}
+	if result.CategoryID != uuid.Nil {
+		insert["category_id"] = result.CategoryID.String()
+	}
+	if result.Model != "" {
+		insert["model"] = result.Model
+	}
+	if result.RawResponse != "" {
+		insert["raw_response"] = result.RawResponse
+	}
+	if opinion := result.SecondOpinion; opinion != nil {
+		insert["second_opinion_category_id"] = opinion.CategoryID.String()
+		insert["second_opinion_confidence"] = opinion.Confidence
+		insert["second_opinion_model"] = opinion.Model
+		insert["model_disagreement"] = opinion.Disagrees
+	}
+	if result.Cached {
+		insert["cached"] = true
+	}
+	if result.InformationRequest {
+		insert["information_request"] = true
+	}
+	if result.VoiceTranscriptUsed {
+		insert["voice_transcript_used"] = true
+	}
+	if len(result.ManipulationSignals) > 0 {
+		insert["manipulation_signals"] = result.ManipulationSignals
+	}
+	if redaction := result.Redaction; redaction != nil {
+		insert["redaction_kinds"] = redaction.Kinds
+		insert["redactions"] = redaction.Counts
+	}
+
+	if _, err := c.doRequest("POST", "/rest/v1/ai_classifications", insert, ""); err != nil {
+		return fmt.Errorf("failed to record classification: %w", err)
+	}
+	return nil
+}
+
+// GetLatestClassification returns the most recent classification of a complaint
+func (c *Client) GetLatestClassification(token, complaintID string) (*models.AIClassificationRecord, error) {
+	query := "/rest/v1/ai_classifications?select=*&complaint_id=eq." + complaintID + "&order=created_at.desc&limit=1"
+
+	resp, err := c.doRequest("GET", query, nil, token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get classification: %w", err)
+	}
+
+	var rows []classificationRow
+	if err := json.Unmarshal(resp, &rows); err != nil {
+		return nil, fmt.Errorf("failed to parse classification: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	return rowToClassification(&rows[0]), nil
+}
+
+// GetCategoryDisagreementStats reports, per category chosen by citizens, how
+// often the AI picked a different one, highest disagreement rate first
+func (c *Client) GetCategoryDisagreementStats(token string) ([]models.CategoryDisagreement, error) {
+	resp, err := c.doRequest("GET", "/rest/v1/category_disagreement_stats?select=*", nil, token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get category disagreement stats: %w", err)
+	}
+
+	var stats []models.CategoryDisagreement
+	if err := json.Unmarshal(resp, &stats); err != nil {
+		return nil, fmt.Errorf("failed to parse category disagreement stats: %w", err)
+	}
+
+	categoryNames := make(map[string]string)
+	if categories, err := c.GetCategories(); err == nil {
+		for _, cat := range categories {
+			categoryNames[cat.ID.String()] = cat.NameAr
+		}
+	}
+
+	for i := range stats {
+		s := &stats[i]
+		s.CategoryName = categoryNames[s.CategoryID]
+		if s.Complaints > 0 {
+			s.DisagreementRate = float64(s.CitizenKept+s.Disputed) / float64(s.Complaints)
+		}
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].DisagreementRate > stats[j].DisagreementRate
+	})
+
+	return stats, nil
+}
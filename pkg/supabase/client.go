@@ -3,6 +3,7 @@ package supabase
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"math"
@@ -14,17 +15,103 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/hakim/backend/internal/arabic"
 	"github.com/hakim/backend/internal/config"
 	"github.com/hakim/backend/internal/models"
 )
 
+// Classification sources record which path produced a result
+const (
+	ClassificationSourceLLM      = "llm"
+	ClassificationSourceKeywords = "keywords"
+	ClassificationSourceLocal    = "local_model"
+)
+
 // ClassificationResult holds AI classification data
 type ClassificationResult struct {
+	CategoryID    uuid.UUID `json:"category_id"`
+	DepartmentID  uuid.UUID `json:"department_id"`
+	CategoryName  string    `json:"category_name"`
+	Priority      string    `json:"priority"`
+	Confidence    float64   `json:"confidence"`
+	Summary       string    `json:"summary"`
+	Sentiment     string    `json:"sentiment,omitempty"`
+	ImageAnalysis string    `json:"image_analysis,omitempty"`
+	Tags          []string  `json:"tags,omitempty"`
+	Source        string    `json:"source"`
+	Model         string    `json:"model,omitempty"`
+	LatencyMs     int64     `json:"latency_ms"`
+	RawResponse   string    `json:"raw_response,omitempty"`
+	// Token usage across every LLM call made for this result
+	PromptTokens     int `json:"prompt_tokens,omitempty"`
+	CompletionTokens int `json:"completion_tokens,omitempty"`
+	// Fallback is set when the result came from a fallback path (keywords
+	// after an LLM failure, or a default category) and needs human review
+	Fallback bool `json:"fallback,omitempty"`
+	// SecondOpinion is the local model's prediction for an LLM result
+	SecondOpinion *SecondOpinion `json:"second_opinion,omitempty"`
+	// Redaction is set when personal data was stripped before the LLM call
+	Redaction *Redaction `json:"redaction,omitempty"`
+	// ManipulationSignals lists signs of prompt injection: instruction-like
+	// text in the complaint, or an LLM priority that contradicts the keywords
+	ManipulationSignals []string `json:"manipulation_signals,omitempty"`
+	// Usage has one entry per LLM call made for this result
+	Usage []LLMUsage `json:"usage,omitempty"`
+	// Cached is set when the LLM verdict was reused from an earlier
+	// complaint with the same content
+	Cached bool `json:"cached,omitempty"`
+	// InformationRequest is set when the citizen is asking for information
+	// or a service rather than reporting a problem
+	InformationRequest bool `json:"information_request,omitempty"`
+	// Images has the model's findings per attached image, in the order the
+	// images were sent
+	Images []AttachmentAnalysis `json:"images,omitempty"`
+	// VoiceTranscriptUsed is set when voice note transcripts were classified
+	// along with a short written description
+	VoiceTranscriptUsed bool `json:"voice_transcript_used,omitempty"`
+}
+
+// AttachmentAnalysis is what the model saw in one image attachment
+type AttachmentAnalysis struct {
+	Labels      []string `json:"labels"`
+	Description string   `json:"description"`
+}
+
+// LLMUsage is the token usage and cost of one LLM call
+type LLMUsage struct {
+	Purpose          string  `json:"purpose"`
+	Provider         string  `json:"provider,omitempty"`
+	Model            string  `json:"model,omitempty"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	CostUSD          float64 `json:"cost_usd"`
+	LatencyMs        int64   `json:"latency_ms"`
+	ImageDetail      string  `json:"image_detail,omitempty"`
+}
+
+// LLM call purposes recorded with usage
+const (
+	UsagePurposeClassification    = "classification"
+	UsagePurposeRepair            = "repair"
+	UsagePurposeReplyDraft        = "reply_draft"
+	UsagePurposeResolutionSummary = "resolution_summary"
+)
+
+// Redaction records which kinds of personal data were removed from the text
+// sent to the LLM and how many of each were found. Values are never kept.
+type Redaction struct {
+	Kinds  []string       `json:"kinds"`
+	Counts map[string]int `json:"counts"`
+}
+
+// SecondOpinion is what the local statistical model predicted alongside the
+// LLM. Disagrees is set when it was confident in a different category.
+type SecondOpinion struct {
 	CategoryID   uuid.UUID `json:"category_id"`
-	DepartmentID uuid.UUID `json:"department_id"`
-	Priority     string    `json:"priority"`
+	CategoryName string    `json:"category_name"`
 	Confidence   float64   `json:"confidence"`
-	Summary      string    `json:"summary"`
+	Model        string    `json:"model"`
+	Disagrees    bool      `json:"disagrees"`
 }
 
 type Client struct {
@@ -68,6 +155,18 @@ func New() *Client {
 	}
 }
 
+// AuthorizeStorageRequest adds the service key to requests for files in this
+// project's storage, so private attachments can be downloaded. Requests to
+// other hosts are left alone.
+func (c *Client) AuthorizeStorageRequest(req *http.Request) {
+	base, err := url.Parse(c.baseURL)
+	if err != nil || req.URL.Host != base.Host || !strings.HasPrefix(req.URL.Path, "/storage/") {
+		return
+	}
+	req.Header.Set("apikey", c.apiKey)
+	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+}
+
 func (c *Client) doRequest(method, path string, body interface{}, token string) ([]byte, error) {
 	var bodyReader io.Reader
 	if body != nil {
@@ -278,6 +377,29 @@ func (c *Client) GetCategories() ([]Category, error) {
 	return categories, nil
 }
 
+// ErrCategoryNotFound is returned for a category that does not exist or has
+// been deactivated
+var ErrCategoryNotFound = errors.New("category not found")
+
+// GetCategory returns an active category, or nil when it does not exist or
+// has been deactivated
+func (c *Client) GetCategory(id string) (*Category, error) {
+	resp, err := c.doRequest("GET", "/rest/v1/categories?select=*&is_active=eq.true&id=eq."+id, nil, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get category: %w", err)
+	}
+
+	var categories []Category
+	if err := json.Unmarshal(resp, &categories); err != nil {
+		return nil, fmt.Errorf("failed to parse category: %w", err)
+	}
+	if len(categories) == 0 {
+		return nil, nil
+	}
+
+	return &categories[0], nil
+}
+
 func (c *Client) GetCategoriesByDepartment(departmentID string) ([]models.Category, error) {
 	query := "/rest/v1/categories?select=*&is_active=eq.true&order=name_ar.asc"
 	if departmentID != "" {
@@ -301,6 +423,9 @@ func (c *Client) GetCategoriesByDepartment(departmentID string) ([]models.Catego
 // COMPLAINT METHODS
 // ============================================
 
+// complaintSelectFields lists the columns and relations returned for a complaint
+const complaintSelectFields = "id,tracking_number,user_id,category_id,department_id,assigned_to,title,description,status,priority,latitude,longitude,address,ai_category,ai_category_confidence,ai_priority,ai_tags,ai_summary,ai_sentiment,ai_source,classification_status,citizen_category_id,ai_category_id,category_reconciliation,rejection_reason,rejection_source,rejection_rule_ids,screening_overridden,deflection_declined,suggested_duplicate_of,merged_into,endorsement_count,sla_deadline,resolved_at,created_at,updated_at,categories(id,department_id,name,name_ar,icon),departments(id,name,name_ar)"
+
 // complaintInsert is the structure for inserting a complaint into Supabase
 type complaintInsert struct {
 	UserID               string   `json:"user_id"`
@@ -313,34 +438,60 @@ type complaintInsert struct {
 	Latitude             *float64 `json:"latitude,omitempty"`
 	Longitude            *float64 `json:"longitude,omitempty"`
 	Address              string   `json:"address,omitempty"`
+	AICategory           string   `json:"ai_category,omitempty"`
 	AICategoryConfidence float64  `json:"ai_category_confidence,omitempty"`
+	AIPriority           string   `json:"ai_priority,omitempty"`
+	AITags               []string `json:"ai_tags,omitempty"`
+	AISummary            string   `json:"ai_summary,omitempty"`
+	AISentiment          string   `json:"ai_sentiment,omitempty"`
+	AISource             string   `json:"ai_source,omitempty"`
+	ClassificationStatus string   `json:"classification_status,omitempty"`
+	CitizenCategoryID    string   `json:"citizen_category_id,omitempty"`
 }
 
 // complaintRow matches the database row structure
 type complaintRow struct {
-	ID                   string    `json:"id"`
-	TrackingNumber       string    `json:"tracking_number"`
-	UserID               string    `json:"user_id"`
-	CategoryID           *string   `json:"category_id"`
-	DepartmentID         *string   `json:"department_id"`
-	AssignedTo           *string   `json:"assigned_to"`
-	Title                string    `json:"title"`
-	Description          string    `json:"description"`
-	Status               string    `json:"status"`
-	Priority             string    `json:"priority"`
-	Latitude             *float64  `json:"latitude"`
-	Longitude            *float64  `json:"longitude"`
-	Address              *string   `json:"address"`
-	AICategoryConfidence *float64  `json:"ai_category_confidence"`
-	SLADeadline          *string   `json:"sla_deadline"`
-	EscalationLevel      int       `json:"escalation_level"`
-	IsEscalated          bool      `json:"is_escalated"`
-	ResolvedAt           *string   `json:"resolved_at"`
-	ClosedAt             *string   `json:"closed_at"`
-	CreatedAt            string    `json:"created_at"`
-	UpdatedAt            string    `json:"updated_at"`
-	Categories           *Category `json:"categories,omitempty"`
-	Departments          *struct {
+	ID                     string    `json:"id"`
+	TrackingNumber         string    `json:"tracking_number"`
+	UserID                 string    `json:"user_id"`
+	CategoryID             *string   `json:"category_id"`
+	DepartmentID           *string   `json:"department_id"`
+	AssignedTo             *string   `json:"assigned_to"`
+	Title                  string    `json:"title"`
+	Description            string    `json:"description"`
+	Status                 string    `json:"status"`
+	Priority               string    `json:"priority"`
+	Latitude               *float64  `json:"latitude"`
+	Longitude              *float64  `json:"longitude"`
+	Address                *string   `json:"address"`
+	AICategory             *string   `json:"ai_category"`
+	AICategoryConfidence   *float64  `json:"ai_category_confidence"`
+	AIPriority             *string   `json:"ai_priority"`
+	AITags                 []string  `json:"ai_tags"`
+	AISummary              *string   `json:"ai_summary"`
+	AISentiment            *string   `json:"ai_sentiment"`
+	AISource               *string   `json:"ai_source"`
+	ClassificationStatus   *string   `json:"classification_status"`
+	CitizenCategoryID      *string   `json:"citizen_category_id"`
+	AICategoryID           *string   `json:"ai_category_id"`
+	CategoryReconciliation *string   `json:"category_reconciliation"`
+	RejectionReason        *string   `json:"rejection_reason"`
+	RejectionSource        *string   `json:"rejection_source"`
+	RejectionRuleIDs       []string  `json:"rejection_rule_ids"`
+	ScreeningOverridden    bool      `json:"screening_overridden"`
+	DeflectionDeclined     bool      `json:"deflection_declined"`
+	SuggestedDuplicateOf   *string   `json:"suggested_duplicate_of"`
+	MergedInto             *string   `json:"merged_into"`
+	EndorsementCount       int       `json:"endorsement_count"`
+	SLADeadline            *string   `json:"sla_deadline"`
+	EscalationLevel        int       `json:"escalation_level"`
+	IsEscalated            bool      `json:"is_escalated"`
+	ResolvedAt             *string   `json:"resolved_at"`
+	ClosedAt               *string   `json:"closed_at"`
+	CreatedAt              string    `json:"created_at"`
+	UpdatedAt              string    `json:"updated_at"`
+	Categories             *Category `json:"categories,omitempty"`
+	Departments            *struct {
 		ID     string `json:"id"`
 		Name   string `json:"name"`
 		NameAr string `json:"name_ar"`
@@ -349,15 +500,16 @@ type complaintRow struct {
 
 func rowToComplaint(row *complaintRow) *models.Complaint {
 	complaint := &models.Complaint{
-		ID:             uuid.MustParse(row.ID),
-		TrackingNumber: row.TrackingNumber,
-		UserID:         uuid.MustParse(row.UserID),
-		Title:          row.Title,
-		Description:    row.Description,
-		Status:         models.ComplaintStatus(row.Status),
-		Priority:       models.ComplaintPriority(row.Priority),
-		Latitude:       row.Latitude,
-		Longitude:      row.Longitude,
+		ID:               uuid.MustParse(row.ID),
+		TrackingNumber:   row.TrackingNumber,
+		UserID:           uuid.MustParse(row.UserID),
+		Title:            row.Title,
+		Description:      row.Description,
+		Status:           models.ComplaintStatus(row.Status),
+		Priority:         models.ComplaintPriority(row.Priority),
+		Latitude:         row.Latitude,
+		Longitude:        row.Longitude,
+		EndorsementCount: row.EndorsementCount,
 	}
 
 	if row.CategoryID != nil {
@@ -373,9 +525,59 @@ func rowToComplaint(row *complaintRow) *models.Complaint {
 	if row.Address != nil {
 		complaint.Address = *row.Address
 	}
+	if row.AICategory != nil {
+		complaint.AIClassification = *row.AICategory
+	}
 	if row.AICategoryConfidence != nil {
 		complaint.AIConfidence = *row.AICategoryConfidence
 	}
+	if row.AIPriority != nil {
+		complaint.AIPriority = *row.AIPriority
+	}
+	if row.AISummary != nil {
+		complaint.AISummary = *row.AISummary
+	}
+	if row.AISentiment != nil {
+		complaint.AISentiment = *row.AISentiment
+	}
+	if row.AISource != nil {
+		complaint.AISource = *row.AISource
+	}
+	complaint.AITags = row.AITags
+	if row.ClassificationStatus != nil {
+		complaint.ClassificationStatus = *row.ClassificationStatus
+	}
+	if row.RejectionReason != nil {
+		complaint.RejectionReason = *row.RejectionReason
+	}
+	if row.RejectionSource != nil {
+		complaint.RejectionSource = *row.RejectionSource
+	}
+	for _, id := range row.RejectionRuleIDs {
+		if ruleID, err := uuid.Parse(id); err == nil {
+			complaint.RejectionRuleIDs = append(complaint.RejectionRuleIDs, ruleID)
+		}
+	}
+	complaint.ScreeningOverridden = row.ScreeningOverridden
+	complaint.DeflectionDeclined = row.DeflectionDeclined
+	complaint.CitizenCategoryID = parseOptionalUUID(row.CitizenCategoryID)
+	complaint.AICategoryID = parseOptionalUUID(row.AICategoryID)
+	if row.CategoryReconciliation != nil {
+		complaint.CategoryReconciliation = *row.CategoryReconciliation
+	}
+	if row.SuggestedDuplicateOf != nil {
+		suggestedID := uuid.MustParse(*row.SuggestedDuplicateOf)
+		complaint.SuggestedDuplicate = &suggestedID
+	}
+	if row.MergedInto != nil {
+		masterID := uuid.MustParse(*row.MergedInto)
+		complaint.MergedInto = &masterID
+	}
+	if row.SLADeadline != nil {
+		if t, err := time.Parse(time.RFC3339, *row.SLADeadline); err == nil {
+			complaint.ExpectedResolution = &t
+		}
+	}
 	if row.ResolvedAt != nil {
 		if t, err := time.Parse(time.RFC3339, *row.ResolvedAt); err == nil {
 			complaint.ResolvedAt = &t
@@ -419,8 +621,12 @@ func (c *Client) CreateComplaint(token, userID string, req *models.CreateComplai
 		Longitude:   req.Longitude,
 		Address:     req.Address,
 	}
+	if req.CategoryID != uuid.Nil {
+		insert.CitizenCategoryID = req.CategoryID.String()
+	}
 
-	// AI classification takes precedence
+	// A classification made before saving is applied as is; background
+	// classification reconciles it with the citizen's choice
 	if classification != nil {
 		if classification.CategoryID != uuid.Nil {
 			insert.CategoryID = classification.CategoryID.String()
@@ -431,15 +637,24 @@ func (c *Client) CreateComplaint(token, userID string, req *models.CreateComplai
 		if classification.Priority != "" {
 			insert.Priority = classification.Priority
 		}
+		insert.AICategory = classification.CategoryName
 		insert.AICategoryConfidence = classification.Confidence
-	} else if req.CategoryID != uuid.Nil {
-		// Fallback to user-provided category only if no AI classification
-		insert.CategoryID = req.CategoryID.String()
+		insert.AIPriority = classification.Priority
+		insert.AITags = classification.Tags
+		insert.AISummary = classification.Summary
+		insert.AISentiment = classification.Sentiment
+		insert.AISource = classification.Source
+		insert.ClassificationStatus = ClassificationCompleted
+	} else {
+		// Classified later by the background pipeline
+		insert.ClassificationStatus = ClassificationPending
+		if req.CategoryID != uuid.Nil {
+			// Use the citizen's category until classification finishes
+			insert.CategoryID = req.CategoryID.String()
+		}
 	}
 
-	selectFields := "id,tracking_number,user_id,category_id,department_id,assigned_to,title,description,status,priority,latitude,longitude,address,ai_category_confidence,resolved_at,created_at,updated_at,categories(id,department_id,name,name_ar,icon),departments(id,name,name_ar)"
-
-	resp, err := c.doRequest("POST", "/rest/v1/complaints?select="+url.QueryEscape(selectFields), insert, token)
+	resp, err := c.doRequest("POST", "/rest/v1/complaints?select="+url.QueryEscape(complaintSelectFields), insert, token)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create complaint: %w", err)
 	}
@@ -455,16 +670,27 @@ func (c *Client) CreateComplaint(token, userID string, req *models.CreateComplai
 
 	complaint := rowToComplaint(&rows[0])
 
+	// Keep the full classification output for staff review
+	if classification != nil {
+		_ = c.RecordClassification(complaint.ID.String(), classification)
+	}
+
 	// Insert attachments if provided
-	if len(req.Attachments) > 0 {
-		for _, fileURL := range req.Attachments {
-			attachmentInsert := map[string]interface{}{
-				"complaint_id": complaint.ID.String(),
-				"file_url":     fileURL,
-				"file_type":    "image",
-			}
-			_, _ = c.doRequest("POST", "/rest/v1/attachments", attachmentInsert, token)
+	for _, fileURL := range req.Attachments {
+		attachmentInsert := map[string]interface{}{
+			"complaint_id": complaint.ID.String(),
+			"file_url":     fileURL,
+			"file_type":    models.AttachmentImage,
+		}
+		_, _ = c.doRequest("POST", "/rest/v1/attachments", attachmentInsert, token)
+	}
+	for _, fileURL := range req.VoiceNotes {
+		attachmentInsert := map[string]interface{}{
+			"complaint_id": complaint.ID.String(),
+			"file_url":     fileURL,
+			"file_type":    models.AttachmentVoice,
 		}
+		_, _ = c.doRequest("POST", "/rest/v1/attachments", attachmentInsert, token)
 	}
 
 	return complaint, nil
@@ -479,9 +705,7 @@ func (c *Client) GetUserComplaints(token, userID, status string, page, limit int
 	}
 	offset := (page - 1) * limit
 
-	selectFields := "id,tracking_number,user_id,category_id,department_id,assigned_to,title,description,status,priority,latitude,longitude,address,ai_category_confidence,resolved_at,created_at,updated_at,categories(id,department_id,name,name_ar,icon),departments(id,name,name_ar)"
-
-	query := "/rest/v1/complaints?select=" + url.QueryEscape(selectFields) +
+	query := "/rest/v1/complaints?select=" + url.QueryEscape(complaintSelectFields) +
 		"&user_id=eq." + userID +
 		"&order=created_at.desc" +
 		"&limit=" + strconv.Itoa(limit) +
@@ -510,9 +734,7 @@ func (c *Client) GetUserComplaints(token, userID, status string, page, limit int
 }
 
 func (c *Client) GetComplaint(token, id, userID string) (*models.Complaint, error) {
-	selectFields := "id,tracking_number,user_id,category_id,department_id,assigned_to,title,description,status,priority,latitude,longitude,address,ai_category_confidence,resolved_at,created_at,updated_at,categories(id,department_id,name,name_ar,icon),departments(id,name,name_ar)"
-
-	query := "/rest/v1/complaints?select=" + url.QueryEscape(selectFields) +
+	query := "/rest/v1/complaints?select=" + url.QueryEscape(complaintSelectFields) +
 		"&id=eq." + id +
 		"&user_id=eq." + userID
 
@@ -553,9 +775,7 @@ func (c *Client) UpdateComplaint(token, id, userID string, req *models.UpdateCom
 		return c.GetComplaint(token, id, userID)
 	}
 
-	selectFields := "id,tracking_number,user_id,category_id,department_id,assigned_to,title,description,status,priority,latitude,longitude,address,ai_category_confidence,resolved_at,created_at,updated_at,categories(id,department_id,name,name_ar,icon),departments(id,name,name_ar)"
-
-	query := "/rest/v1/complaints?select=" + url.QueryEscape(selectFields) +
+	query := "/rest/v1/complaints?select=" + url.QueryEscape(complaintSelectFields) +
 		"&id=eq." + id +
 		"&user_id=eq." + userID
 
@@ -686,7 +906,9 @@ func (c *Client) GetStatusHistory(token, complaintID string) ([]models.StatusHis
 // ADMIN METHODS
 // ============================================
 
-func (c *Client) GetAllComplaints(token, status, departmentID string, page, limit int) ([]models.Complaint, error) {
+// GetAllComplaints lists complaints for staff. search, when set, must match
+// every word of the query against the normalized complaint text.
+func (c *Client) GetAllComplaints(token, status, departmentID, search string, page, limit int) ([]models.Complaint, error) {
 	if page < 1 {
 		page = 1
 	}
@@ -695,9 +917,7 @@ func (c *Client) GetAllComplaints(token, status, departmentID string, page, limi
 	}
 	offset := (page - 1) * limit
 
-	selectFields := "id,tracking_number,user_id,category_id,department_id,assigned_to,title,description,status,priority,latitude,longitude,address,ai_category_confidence,resolved_at,created_at,updated_at,categories(id,department_id,name,name_ar,icon),departments(id,name,name_ar)"
-
-	query := "/rest/v1/complaints?select=" + url.QueryEscape(selectFields) +
+	query := "/rest/v1/complaints?select=" + url.QueryEscape(complaintSelectFields) +
 		"&order=created_at.desc" +
 		"&limit=" + strconv.Itoa(limit) +
 		"&offset=" + strconv.Itoa(offset)
@@ -708,6 +928,11 @@ func (c *Client) GetAllComplaints(token, status, departmentID string, page, limi
 	if departmentID != "" {
 		query += "&department_id=eq." + departmentID
 	}
+	// Stems are substrings of the words they came from, so matching stems
+	// against the normalized column also finds prefixed and suffixed forms
+	for _, token := range arabic.Tokens(search) {
+		query += "&search_text=ilike." + url.QueryEscape("*"+token+"*")
+	}
 
 	resp, err := c.doRequest("GET", query, nil, token)
 	if err != nil {
@@ -728,9 +953,7 @@ func (c *Client) GetAllComplaints(token, status, departmentID string, page, limi
 }
 
 func (c *Client) GetComplaintAdmin(token, id string) (*models.Complaint, error) {
-	selectFields := "id,tracking_number,user_id,category_id,department_id,assigned_to,title,description,status,priority,latitude,longitude,address,ai_category_confidence,resolved_at,created_at,updated_at,categories(id,department_id,name,name_ar,icon),departments(id,name,name_ar)"
-
-	query := "/rest/v1/complaints?select=" + url.QueryEscape(selectFields) + "&id=eq." + id
+	query := "/rest/v1/complaints?select=" + url.QueryEscape(complaintSelectFields) + "&id=eq." + id
 
 	resp, err := c.doRequest("GET", query, nil, token)
 	if err != nil {
@@ -755,9 +978,7 @@ func (c *Client) AssignComplaint(token, id, assigneeID, changedBy string) (*mode
 		"status":      string(models.StatusAssigned),
 	}
 
-	selectFields := "id,tracking_number,user_id,category_id,department_id,assigned_to,title,description,status,priority,latitude,longitude,address,ai_category_confidence,resolved_at,created_at,updated_at,categories(id,department_id,name,name_ar,icon),departments(id,name,name_ar)"
-
-	query := "/rest/v1/complaints?select=" + url.QueryEscape(selectFields) + "&id=eq." + id
+	query := "/rest/v1/complaints?select=" + url.QueryEscape(complaintSelectFields) + "&id=eq." + id
 
 	resp, err := c.doRequest("PATCH", query, update, token)
 	if err != nil {
@@ -795,9 +1016,7 @@ func (c *Client) UpdateComplaintStatus(token, id, status, note, changedBy string
 		update["resolved_at"] = time.Now().UTC().Format(time.RFC3339)
 	}
 
-	selectFields := "id,tracking_number,user_id,category_id,department_id,assigned_to,title,description,status,priority,latitude,longitude,address,ai_category_confidence,resolved_at,created_at,updated_at,categories(id,department_id,name,name_ar,icon),departments(id,name,name_ar)"
-
-	query := "/rest/v1/complaints?select=" + url.QueryEscape(selectFields) + "&id=eq." + id
+	query := "/rest/v1/complaints?select=" + url.QueryEscape(complaintSelectFields) + "&id=eq." + id
 
 	resp, err := c.doRequest("PATCH", query, update, token)
 	if err != nil {
@@ -824,7 +1043,12 @@ func (c *Client) UpdateComplaintStatus(token, id, status, note, changedBy string
 	}
 	_, _ = c.doRequest("POST", "/rest/v1/status_history", historyInsert, token)
 
-	return rowToComplaint(&rows[0]), nil
+	complaint := rowToComplaint(&rows[0])
+
+	// Fan the update out to merged duplicates and notify every reporter
+	c.propagateStatus(token, complaint, status, note, changedBy)
+
+	return complaint, nil
 }
 
 // ============================================
@@ -984,6 +1208,7 @@ func (c *Client) GetEmployees(token, departmentID string) ([]UserProfile, error)
 
 // PublicMapPoint represents an aggregated location on the public map
 type PublicMapPoint struct {
+	IncidentID   string  `json:"incident_id"`
 	Lat          float64 `json:"lat"`
 	Lng          float64 `json:"lng"`
 	Area         string  `json:"area"`
@@ -993,6 +1218,7 @@ type PublicMapPoint struct {
 	Status       string  `json:"status"`
 	Priority     string  `json:"priority"`
 	Count        int     `json:"count"`
+	Endorsements int     `json:"endorsements"`
 }
 
 // PublicMapStats represents category statistics for the public map
@@ -1007,10 +1233,21 @@ type PublicMapStats struct {
 	High       int    `json:"high"`
 }
 
+// publicComplaintFilter keeps rejected complaints, which are stored so they
+// can be appealed, and complaints still being classified or waiting for the
+// citizen's answers off the public map
+const publicComplaintFilter = "&status=neq.rejected&classification_status=eq.completed"
+
+// isPublicComplaint applies publicComplaintFilter to a loaded complaint
+func isPublicComplaint(complaint *models.Complaint) bool {
+	return complaint.Status != models.StatusRejected && complaint.ClassificationStatus == ClassificationCompleted
+}
+
 // GetPublicMapData returns aggregated complaint data for the public community map
 // This returns anonymized location data grouped by area
 func (c *Client) GetPublicMapData(category, timeRange string) ([]PublicMapPoint, error) {
-	query := "/rest/v1/complaints?select=latitude,longitude,address,status,priority,category:categories(id,name,name_ar,icon)&latitude=not.is.null&longitude=not.is.null&order=created_at.desc"
+	query := "/rest/v1/complaints?select=id,merged_into,latitude,longitude,address,status,priority,endorsement_count,category:categories(id,name,name_ar,icon)&latitude=not.is.null&longitude=not.is.null&order=created_at.desc" +
+		publicComplaintFilter
 
 	// Apply time filter
 	if timeRange != "" && timeRange != "all" {
@@ -1056,12 +1293,15 @@ func (c *Client) GetPublicMapData(category, timeRange string) ([]PublicMapPoint,
 	}
 
 	var complaints []struct {
-		Latitude  float64 `json:"latitude"`
-		Longitude float64 `json:"longitude"`
-		Address   string  `json:"address"`
-		Status    string  `json:"status"`
-		Priority  string  `json:"priority"`
-		Category  *struct {
+		ID               string  `json:"id"`
+		MergedInto       *string `json:"merged_into"`
+		Latitude         float64 `json:"latitude"`
+		Longitude        float64 `json:"longitude"`
+		Address          string  `json:"address"`
+		Status           string  `json:"status"`
+		Priority         string  `json:"priority"`
+		EndorsementCount int     `json:"endorsement_count"`
+		Category         *struct {
 			Name   string `json:"name"`
 			NameAr string `json:"name_ar"`
 			Icon   string `json:"icon"`
@@ -1081,11 +1321,19 @@ func (c *Client) GetPublicMapData(category, timeRange string) ([]PublicMapPoint,
 		lng := math.Round(complaint.Longitude*1000) / 1000
 		key := fmt.Sprintf("%.3f_%.3f", lat, lng)
 
+		// Endorsements target the master of a merged group
+		incidentID := complaint.ID
+		if complaint.MergedInto != nil {
+			incidentID = *complaint.MergedInto
+		}
+
 		if existing, ok := points[key]; ok {
 			existing.Count++
+			existing.Endorsements += complaint.EndorsementCount
 			// Update to highest priority
 			if priorityValue(complaint.Priority) > priorityValue(existing.Priority) {
 				existing.Priority = complaint.Priority
+				existing.IncidentID = incidentID
 			}
 		} else {
 			categoryName := "general"
@@ -1103,6 +1351,7 @@ func (c *Client) GetPublicMapData(category, timeRange string) ([]PublicMapPoint,
 			}
 
 			points[key] = &PublicMapPoint{
+				IncidentID:   incidentID,
 				Lat:          lat,
 				Lng:          lng,
 				Area:         address,
@@ -1112,6 +1361,7 @@ func (c *Client) GetPublicMapData(category, timeRange string) ([]PublicMapPoint,
 				Status:       complaint.Status,
 				Priority:     complaint.Priority,
 				Count:        1,
+				Endorsements: complaint.EndorsementCount,
 			}
 		}
 	}
@@ -1127,7 +1377,9 @@ func (c *Client) GetPublicMapData(category, timeRange string) ([]PublicMapPoint,
 
 // GetPublicMapStats returns category statistics for the public map
 func (c *Client) GetPublicMapStats() ([]PublicMapStats, error) {
-	query := "/rest/v1/complaints?select=status,priority,category:categories(name,name_ar,icon)&latitude=not.is.null"
+	// Merged duplicates are counted once, through their master
+	query := "/rest/v1/complaints?select=status,priority,category:categories(name,name_ar,icon)&latitude=not.is.null&merged_into=is.null" +
+		publicComplaintFilter
 
 	resp, err := c.doRequest("GET", query, nil, "")
 	if err != nil {
@@ -0,0 +1,253 @@
+package supabase
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hakim/backend/internal/models"
+)
+
+// ============================================
+// AI DRAFT METHODS
+// ============================================
+
+var (
+	ErrDraftNotFound  = errors.New("draft not found")
+	ErrDraftFinalized = errors.New("draft has already been finalized")
+)
+
+type draftRow struct {
+	ID           string                    `json:"id"`
+	ComplaintID  string                    `json:"complaint_id"`
+	Kind         string                    `json:"kind"`
+	Instructions *string                   `json:"instructions"`
+	DraftText    string                    `json:"draft_text"`
+	Summary      *models.ResolutionSummary `json:"summary"`
+	Model        *string                   `json:"model"`
+	FinalText    *string                   `json:"final_text"`
+	Edited       bool                      `json:"edited"`
+	CreatedBy    string                    `json:"created_by"`
+	FinalizedBy  *string                   `json:"finalized_by"`
+	FinalizedAt  *string                   `json:"finalized_at"`
+	CreatedAt    string                    `json:"created_at"`
+}
+
+func rowToDraft(row *draftRow) *models.AIDraft {
+	draft := &models.AIDraft{
+		ID:          uuid.MustParse(row.ID),
+		ComplaintID: uuid.MustParse(row.ComplaintID),
+		Kind:        models.DraftKind(row.Kind),
+		DraftText:   row.DraftText,
+		Summary:     row.Summary,
+		Edited:      row.Edited,
+		CreatedBy:   uuid.MustParse(row.CreatedBy),
+		FinalizedBy: parseOptionalUUID(row.FinalizedBy),
+	}
+	if row.Instructions != nil {
+		draft.Instructions = *row.Instructions
+	}
+	if row.Model != nil {
+		draft.Model = *row.Model
+	}
+	if row.FinalText != nil {
+		draft.FinalText = *row.FinalText
+	}
+	if row.FinalizedAt != nil {
+		if t, err := time.Parse(time.RFC3339, *row.FinalizedAt); err == nil {
+			draft.FinalizedAt = &t
+		}
+	}
+	if t, err := time.Parse(time.RFC3339, row.CreatedAt); err == nil {
+		draft.CreatedAt = t
+	}
+	return draft
+}
+
+// CreateDraft logs a generated draft before the employee edits it
+func (c *Client) CreateDraft(token string, draft *models.AIDraft) (*models.AIDraft, error) {
+	insert := map[string]interface{}{
+		"complaint_id": draft.ComplaintID.String(),
+		"kind":         string(draft.Kind),
+		"draft_text":   draft.DraftText,
+		"created_by":   draft.CreatedBy.String(),
+	}
+	if draft.Instructions != "" {
+		insert["instructions"] = draft.Instructions
+	}
+	if draft.Summary != nil {
+		insert["summary"] = draft.Summary
+	}
+	if draft.Model != "" {
+		insert["model"] = draft.Model
+	}
+
+	resp, err := c.doRequest("POST", "/rest/v1/ai_drafts?select=*", insert, token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create draft: %w", err)
+	}
+
+	var rows []draftRow
+	if err := json.Unmarshal(resp, &rows); err != nil {
+		return nil, fmt.Errorf("failed to parse draft: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("draft was not created")
+	}
+
+	return rowToDraft(&rows[0]), nil
+}
+
+// GetDraft returns one draft
+func (c *Client) GetDraft(token, id string) (*models.AIDraft, error) {
+	resp, err := c.doRequest("GET", "/rest/v1/ai_drafts?select=*&id=eq."+id, nil, token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get draft: %w", err)
+	}
+
+	var rows []draftRow
+	if err := json.Unmarshal(resp, &rows); err != nil {
+		return nil, fmt.Errorf("failed to parse draft: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, ErrDraftNotFound
+	}
+
+	return rowToDraft(&rows[0]), nil
+}
+
+// FinalizeDraft records the text that was actually sent and whether the
+// employee changed the draft
+func (c *Client) FinalizeDraft(token, id, finalText, finalizedBy string) (*models.AIDraft, error) {
+	draft, err := c.GetDraft(token, id)
+	if err != nil {
+		return nil, err
+	}
+	if draft.FinalizedAt != nil {
+		return nil, ErrDraftFinalized
+	}
+
+	update := map[string]interface{}{
+		"final_text":   finalText,
+		"edited":       strings.TrimSpace(finalText) != strings.TrimSpace(draft.DraftText),
+		"finalized_by": finalizedBy,
+		"finalized_at": time.Now().UTC().Format(time.RFC3339),
+	}
+
+	// The filter stops a draft being finalized twice
+	resp, err := c.doRequest("PATCH", "/rest/v1/ai_drafts?select=*&finalized_at=is.null&id=eq."+id, update, token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update draft: %w", err)
+	}
+
+	var rows []draftRow
+	if err := json.Unmarshal(resp, &rows); err != nil {
+		return nil, fmt.Errorf("failed to parse draft: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, ErrDraftFinalized
+	}
+
+	return rowToDraft(&rows[0]), nil
+}
+
+// ReopenDraft undoes a finalization whose follow-up failed, so the employee
+// can send the draft again
+func (c *Client) ReopenDraft(token, id string) error {
+	update := map[string]interface{}{
+		"final_text":   nil,
+		"edited":       false,
+		"finalized_by": nil,
+		"finalized_at": nil,
+	}
+
+	if _, err := c.doRequest("PATCH", "/rest/v1/ai_drafts?id=eq."+id, update, token); err != nil {
+		return fmt.Errorf("failed to reopen draft: %w", err)
+	}
+	return nil
+}
+
+// GetDrafts lists drafts for quality review, newest first
+func (c *Client) GetDrafts(token, kind, complaintID string, page, limit int) ([]models.AIDraft, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	offset := (page - 1) * limit
+
+	query := "/rest/v1/ai_drafts?select=*&order=created_at.desc&offset=" + strconv.Itoa(offset) + "&limit=" + strconv.Itoa(limit)
+	if kind != "" {
+		query += "&kind=eq." + kind
+	}
+	if complaintID != "" {
+		query += "&complaint_id=eq." + complaintID
+	}
+
+	resp, err := c.doRequest("GET", query, nil, token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get drafts: %w", err)
+	}
+
+	var rows []draftRow
+	if err := json.Unmarshal(resp, &rows); err != nil {
+		return nil, fmt.Errorf("failed to parse drafts: %w", err)
+	}
+
+	drafts := make([]models.AIDraft, 0, len(rows))
+	for i := range rows {
+		drafts = append(drafts, *rowToDraft(&rows[i]))
+	}
+
+	return drafts, nil
+}
+
+// GetReplyGuidelines returns a department's tone guidelines for drafted
+// replies, empty if it has none
+func (c *Client) GetReplyGuidelines(departmentID uuid.UUID) (string, error) {
+	resp, err := c.doRequest("GET", "/rest/v1/departments?select=reply_guidelines&id=eq."+departmentID.String(), nil, "")
+	if err != nil {
+		return "", fmt.Errorf("failed to get reply guidelines: %w", err)
+	}
+
+	var rows []struct {
+		ReplyGuidelines *string `json:"reply_guidelines"`
+	}
+	if err := json.Unmarshal(resp, &rows); err != nil {
+		return "", fmt.Errorf("failed to parse reply guidelines: %w", err)
+	}
+	if len(rows) == 0 || rows[0].ReplyGuidelines == nil {
+		return "", nil
+	}
+
+	return *rows[0].ReplyGuidelines, nil
+}
+
+// SetReplyGuidelines replaces a department's tone guidelines; empty clears them
+func (c *Client) SetReplyGuidelines(token, departmentID, guidelines string) error {
+	update := map[string]interface{}{"reply_guidelines": nil}
+	if guidelines = strings.TrimSpace(guidelines); guidelines != "" {
+		update["reply_guidelines"] = guidelines
+	}
+
+	resp, err := c.doRequest("PATCH", "/rest/v1/departments?select=id&id=eq."+departmentID, update, token)
+	if err != nil {
+		return fmt.Errorf("failed to update reply guidelines: %w", err)
+	}
+
+	var rows []struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(resp, &rows); err != nil {
+		return fmt.Errorf("failed to parse department: %w", err)
+	}
+	if len(rows) == 0 {
+		return fmt.Errorf("department not found")
+	}
+	return nil
+}
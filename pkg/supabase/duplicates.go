@@ -0,0 +1,306 @@
+package supabase
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hakim/backend/internal/models"
+)
+
+// ============================================
+// DUPLICATE & MERGE METHODS
+// ============================================
+
+// metersPerDegree is the approximate length of one degree of latitude
+const metersPerDegree = 111320.0
+
+// NearbyComplaintsQuery narrows the pool of complaints checked for duplicates
+type NearbyComplaintsQuery struct {
+	CategoryID   string
+	Latitude     *float64
+	Longitude    *float64
+	RadiusMeters float64
+	Since        time.Time
+	ExcludeID    string
+	Limit        int
+}
+
+// FindNearbyComplaints returns open, unmerged complaints matching the query.
+// Location is filtered with a bounding box; callers compute exact distances.
+func (c *Client) FindNearbyComplaints(q NearbyComplaintsQuery) ([]models.Complaint, error) {
+	if q.Limit < 1 {
+		q.Limit = 50
+	}
+
+	query := "/rest/v1/complaints?select=" + url.QueryEscape(complaintSelectFields) +
+		"&merged_into=is.null" +
+		"&status=not.in.(rejected,closed)" +
+		"&created_at=gte." + url.QueryEscape(q.Since.UTC().Format(time.RFC3339)) +
+		"&order=created_at.desc" +
+		"&limit=" + strconv.Itoa(q.Limit)
+
+	if q.CategoryID != "" {
+		query += "&category_id=eq." + q.CategoryID
+	}
+	if q.ExcludeID != "" {
+		query += "&id=neq." + q.ExcludeID
+	}
+	if q.Latitude != nil && q.Longitude != nil && q.RadiusMeters > 0 {
+		latDelta := q.RadiusMeters / metersPerDegree
+		lngDelta := q.RadiusMeters / (metersPerDegree * math.Max(math.Cos(*q.Latitude*math.Pi/180), 0.01))
+		query += "&latitude=gte." + formatCoord(*q.Latitude-latDelta) +
+			"&latitude=lte." + formatCoord(*q.Latitude+latDelta) +
+			"&longitude=gte." + formatCoord(*q.Longitude-lngDelta) +
+			"&longitude=lte." + formatCoord(*q.Longitude+lngDelta)
+	}
+
+	resp, err := c.doRequest("GET", query, nil, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get nearby complaints: %w", err)
+	}
+
+	var rows []complaintRow
+	if err := json.Unmarshal(resp, &rows); err != nil {
+		return nil, fmt.Errorf("failed to parse complaints: %w", err)
+	}
+
+	complaints := make([]models.Complaint, 0, len(rows))
+	for i := range rows {
+		complaints = append(complaints, *rowToComplaint(&rows[i]))
+	}
+
+	return complaints, nil
+}
+
+// SetSuggestedDuplicate records the best duplicate match so staff can review it
+func (c *Client) SetSuggestedDuplicate(complaintID, masterID string) error {
+	update := map[string]interface{}{
+		"suggested_duplicate_of": masterID,
+	}
+
+	_, err := c.doRequest("PATCH", "/rest/v1/complaints?id=eq."+complaintID, update, "")
+	if err != nil {
+		return fmt.Errorf("failed to set suggested duplicate: %w", err)
+	}
+
+	return nil
+}
+
+// GetMergedComplaints returns the complaints that were merged into the master
+func (c *Client) GetMergedComplaints(token, masterID string) ([]models.Complaint, error) {
+	query := "/rest/v1/complaints?select=" + url.QueryEscape(complaintSelectFields) +
+		"&merged_into=eq." + masterID +
+		"&order=created_at.asc"
+
+	resp, err := c.doRequest("GET", query, nil, token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get merged complaints: %w", err)
+	}
+
+	var rows []complaintRow
+	if err := json.Unmarshal(resp, &rows); err != nil {
+		return nil, fmt.Errorf("failed to parse complaints: %w", err)
+	}
+
+	complaints := make([]models.Complaint, 0, len(rows))
+	for i := range rows {
+		complaints = append(complaints, *rowToComplaint(&rows[i]))
+	}
+
+	return complaints, nil
+}
+
+// MergeComplaints links duplicates to a master complaint. Each duplicate takes
+// the master's status, gets a history entry, and its reporter is notified.
+func (c *Client) MergeComplaints(token, masterID string, duplicateIDs []uuid.UUID, note, changedBy string) (*models.MergeResult, error) {
+	master, err := c.GetComplaintAdmin(token, masterID)
+	if err != nil {
+		return nil, err
+	}
+	if master.MergedInto != nil {
+		return nil, fmt.Errorf("complaint %s is itself merged into another complaint", master.TrackingNumber)
+	}
+
+	result := &models.MergeResult{
+		Master: master,
+		Merged: make([]uuid.UUID, 0, len(duplicateIDs)),
+	}
+
+	historyNote := "تم دمج الشكوى مع الشكوى الرئيسية " + master.TrackingNumber
+	if note != "" {
+		historyNote += ": " + note
+	}
+
+	for _, dupID := range duplicateIDs {
+		if dupID == master.ID {
+			result.Failed = append(result.Failed, dupID.String()+": cannot merge a complaint into itself")
+			continue
+		}
+
+		update := map[string]interface{}{
+			"merged_into":            master.ID.String(),
+			"suggested_duplicate_of": nil,
+			"status":                 string(master.Status),
+		}
+
+		query := "/rest/v1/complaints?select=" + url.QueryEscape(complaintSelectFields) +
+			"&id=eq." + dupID.String() +
+			"&merged_into=is.null"
+
+		resp, err := c.doRequest("PATCH", query, update, token)
+		if err != nil {
+			result.Failed = append(result.Failed, dupID.String()+": "+err.Error())
+			continue
+		}
+
+		var rows []complaintRow
+		if err := json.Unmarshal(resp, &rows); err != nil || len(rows) == 0 {
+			result.Failed = append(result.Failed, dupID.String()+": complaint not found or already merged")
+			continue
+		}
+		duplicate := rowToComplaint(&rows[0])
+
+		// Re-point anything that was merged into the duplicate
+		_, _ = c.doRequest("PATCH", "/rest/v1/complaints?merged_into=eq."+dupID.String(),
+			map[string]interface{}{"merged_into": master.ID.String()}, token)
+
+		// Endorsements of the duplicate now count towards the master
+		_ = c.moveEndorsements(dupID.String(), master)
+
+		historyInsert := map[string]interface{}{
+			"complaint_id": dupID.String(),
+			"new_status":   string(master.Status),
+			"changed_by":   changedBy,
+			"notes":        historyNote,
+		}
+		_, _ = c.doRequest("POST", "/rest/v1/status_history", historyInsert, token)
+
+		c.createNotification(token, duplicate.UserID.String(), dupID.String(), "complaint_merged",
+			"Complaint linked", "تم ربط شكواك",
+			"Your complaint "+duplicate.TrackingNumber+" was linked to "+master.TrackingNumber+" and will receive its updates.",
+			"تم ربط شكواك "+duplicate.TrackingNumber+" بالشكوى "+master.TrackingNumber+" وستصلك تحديثاتها.")
+
+		result.Merged = append(result.Merged, dupID)
+	}
+
+	// Moved endorsements may push the master over a priority threshold
+	if len(result.Merged) > 0 {
+		if refreshed, err := c.refreshEndorsements(master.ID.String(), false); err == nil {
+			master.EndorsementCount = refreshed.EndorsementCount
+			master.Priority = refreshed.Priority
+		}
+	}
+
+	return result, nil
+}
+
+// propagateStatus copies a master complaint's status to its merged duplicates
+// and notifies every reporter and endorser involved
+func (c *Client) propagateStatus(token string, master *models.Complaint, status, note, changedBy string) {
+	merged, err := c.GetMergedComplaints(token, master.ID.String())
+	if err != nil {
+		return
+	}
+
+	update := map[string]interface{}{
+		"status": status,
+	}
+	if status == string(models.StatusResolved) {
+		update["resolved_at"] = time.Now().UTC().Format(time.RFC3339)
+	}
+
+	reporters := map[string]string{master.UserID.String(): master.ID.String()}
+
+	if len(merged) > 0 {
+		_, _ = c.doRequest("PATCH", "/rest/v1/complaints?merged_into=eq."+master.ID.String(), update, token)
+	}
+
+	historyNote := "تحديث من الشكوى الرئيسية " + master.TrackingNumber
+	if note != "" {
+		historyNote += ": " + note
+	}
+
+	for _, dup := range merged {
+		historyInsert := map[string]interface{}{
+			"complaint_id": dup.ID.String(),
+			"new_status":   status,
+			"changed_by":   changedBy,
+			"notes":        historyNote,
+		}
+		_, _ = c.doRequest("POST", "/rest/v1/status_history", historyInsert, token)
+
+		if _, ok := reporters[dup.UserID.String()]; !ok {
+			reporters[dup.UserID.String()] = dup.ID.String()
+		}
+	}
+
+	// Citizens who endorsed the incident are affected parties too
+	complaintIDs := make([]string, 0, len(merged)+1)
+	complaintIDs = append(complaintIDs, master.ID.String())
+	for _, dup := range merged {
+		complaintIDs = append(complaintIDs, dup.ID.String())
+	}
+	if endorsements, err := c.GetEndorsers(token, complaintIDs); err == nil {
+		for _, e := range endorsements {
+			if _, ok := reporters[e.UserID.String()]; !ok {
+				reporters[e.UserID.String()] = e.ComplaintID.String()
+			}
+		}
+	}
+
+	for userID, complaintID := range reporters {
+		c.notifyStatusChange(token, userID, complaintID, master.TrackingNumber, status)
+	}
+}
+
+// ============================================
+// NOTIFICATION METHODS
+// ============================================
+
+var statusLabelsAr = map[string]string{
+	string(models.StatusSubmitted):  "مقدمة",
+	string(models.StatusInReview):   "قيد المراجعة",
+	string(models.StatusAssigned):   "تم التعيين",
+	string(models.StatusInProgress): "قيد المعالجة",
+	string(models.StatusResolved):   "تم الحل",
+	string(models.StatusClosed):     "مغلقة",
+	string(models.StatusRejected):   "مرفوضة",
+}
+
+func (c *Client) notifyStatusChange(token, userID, complaintID, trackingNumber, status string) {
+	label := statusLabelsAr[status]
+	if label == "" {
+		label = status
+	}
+
+	c.createNotification(token, userID, complaintID, "status_update",
+		"Complaint status updated", "تحديث حالة الشكوى",
+		"Complaint "+trackingNumber+" is now "+strings.ReplaceAll(status, "_", " ")+".",
+		"أصبحت حالة الشكوى "+trackingNumber+": "+label)
+}
+
+// createNotification inserts an in-app notification; failures are not fatal
+func (c *Client) createNotification(token, userID, complaintID, notifType, title, titleAr, body, bodyAr string) {
+	insert := map[string]interface{}{
+		"user_id":  userID,
+		"title":    title,
+		"title_ar": titleAr,
+		"body":     body,
+		"body_ar":  bodyAr,
+		"type":     notifType,
+	}
+	if complaintID != "" {
+		insert["complaint_id"] = complaintID
+	}
+	_, _ = c.doRequest("POST", "/rest/v1/notifications", insert, token)
+}
+
+func formatCoord(v float64) string {
+	return strconv.FormatFloat(v, 'f', 6, 64)
+}
@@ -0,0 +1,250 @@
+package supabase
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hakim/backend/internal/models"
+)
+
+// ============================================
+// ENDORSEMENT METHODS
+// ============================================
+
+// Endorsement counts at which an incident's priority is raised
+const (
+	endorsementsForHigh     = 5
+	endorsementsForCritical = 25
+)
+
+var (
+	ErrAlreadyEndorsed = errors.New("incident already endorsed")
+	ErrOwnComplaint    = errors.New("cannot endorse your own complaint")
+	// ErrIncidentNotFound also covers complaints that are not public
+	ErrIncidentNotFound = errors.New("incident not found")
+)
+
+type endorsementRow struct {
+	ID          string `json:"id"`
+	ComplaintID string `json:"complaint_id"`
+	UserID      string `json:"user_id"`
+	CreatedAt   string `json:"created_at"`
+}
+
+// EndorsePriority returns the priority an incident should have given how many
+// citizens endorsed it. Endorsements only ever raise priority.
+func EndorsePriority(current string, endorsements int) string {
+	target := current
+	switch {
+	case endorsements >= endorsementsForCritical:
+		target = string(models.PriorityCritical)
+	case endorsements >= endorsementsForHigh:
+		target = string(models.PriorityHigh)
+	}
+
+	if priorityValue(target) > priorityValue(current) {
+		return target
+	}
+	return current
+}
+
+// resolveIncident returns the complaint that represents an incident, following
+// merge links to the master
+func (c *Client) resolveIncident(complaintID string) (*models.Complaint, error) {
+	query := "/rest/v1/complaints?select=" + url.QueryEscape(complaintSelectFields) + "&id=eq." + complaintID
+
+	resp, err := c.doRequest("GET", query, nil, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get incident: %w", err)
+	}
+
+	var rows []complaintRow
+	if err := json.Unmarshal(resp, &rows); err != nil {
+		return nil, fmt.Errorf("failed to parse incident: %w", err)
+	}
+
+	if len(rows) == 0 {
+		return nil, ErrIncidentNotFound
+	}
+
+	if rows[0].MergedInto != nil {
+		return c.resolveIncident(*rows[0].MergedInto)
+	}
+
+	return rowToComplaint(&rows[0]), nil
+}
+
+// EndorseIncident adds the citizen as an affected party of a public incident
+// and raises its priority when enough citizens have endorsed it
+func (c *Client) EndorseIncident(token, complaintID, userID string) (*models.EndorsementResult, error) {
+	incident, err := c.resolveIncident(complaintID)
+	if err != nil {
+		return nil, err
+	}
+	// Only incidents on the public map can be endorsed, so a known ID cannot
+	// be used to raise the priority of anyone else's complaint
+	if !isPublicComplaint(incident) {
+		return nil, ErrIncidentNotFound
+	}
+
+	if incident.UserID.String() == userID {
+		return nil, ErrOwnComplaint
+	}
+	if incident.Status == models.StatusRejected || incident.Status == models.StatusClosed {
+		return nil, fmt.Errorf("incident is no longer open")
+	}
+
+	existing, err := c.doRequest("GET", "/rest/v1/complaint_endorsements?select=id&complaint_id=eq."+incident.ID.String()+"&user_id=eq."+userID, nil, token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check endorsement: %w", err)
+	}
+	var existingRows []endorsementRow
+	if err := json.Unmarshal(existing, &existingRows); err == nil && len(existingRows) > 0 {
+		return nil, ErrAlreadyEndorsed
+	}
+
+	insert := map[string]interface{}{
+		"complaint_id": incident.ID.String(),
+		"user_id":      userID,
+	}
+	if _, err := c.doRequest("POST", "/rest/v1/complaint_endorsements", insert, token); err != nil {
+		return nil, fmt.Errorf("failed to endorse incident: %w", err)
+	}
+
+	return c.refreshEndorsements(incident.ID.String(), true)
+}
+
+// RemoveEndorsement withdraws the citizen's endorsement. Priority is not
+// lowered automatically; staff may adjust it.
+func (c *Client) RemoveEndorsement(token, complaintID, userID string) (*models.EndorsementResult, error) {
+	incident, err := c.resolveIncident(complaintID)
+	if err != nil {
+		return nil, err
+	}
+
+	query := "/rest/v1/complaint_endorsements?complaint_id=eq." + incident.ID.String() + "&user_id=eq." + userID
+	if _, err := c.doRequest("DELETE", query, nil, token); err != nil {
+		return nil, fmt.Errorf("failed to remove endorsement: %w", err)
+	}
+
+	return c.refreshEndorsements(incident.ID.String(), false)
+}
+
+// refreshEndorsements reloads the endorsement count and applies priority scoring
+func (c *Client) refreshEndorsements(complaintID string, endorsed bool) (*models.EndorsementResult, error) {
+	incident, err := c.resolveIncident(complaintID)
+	if err != nil {
+		return nil, err
+	}
+
+	priority := EndorsePriority(string(incident.Priority), incident.EndorsementCount)
+	if priority != string(incident.Priority) {
+		update := map[string]interface{}{"priority": priority}
+		if _, err := c.doRequest("PATCH", "/rest/v1/complaints?id=eq."+complaintID, update, ""); err != nil {
+			return nil, fmt.Errorf("failed to update priority: %w", err)
+		}
+
+		historyInsert := map[string]interface{}{
+			"complaint_id":        incident.ID.String(),
+			"old_status":          string(incident.Status),
+			"new_status":          string(incident.Status),
+			"notes":               fmt.Sprintf("تم رفع الأولوية إلى %s بعد %d تأييداً من المواطنين", priority, incident.EndorsementCount),
+			"is_system_generated": true,
+		}
+		_, _ = c.doRequest("POST", "/rest/v1/status_history", historyInsert, "")
+	}
+
+	return &models.EndorsementResult{
+		ComplaintID:      incident.ID,
+		Endorsed:         endorsed,
+		EndorsementCount: incident.EndorsementCount,
+		Priority:         models.ComplaintPriority(priority),
+	}, nil
+}
+
+// moveEndorsements re-points the endorsements of a merged duplicate to its
+// master so they count towards the master's priority. Citizens who already
+// endorsed the master, and the master's reporter, are not counted twice.
+func (c *Client) moveEndorsements(duplicateID string, master *models.Complaint) error {
+	resp, err := c.doRequest("GET", "/rest/v1/complaint_endorsements?select=*&complaint_id=in.("+duplicateID+","+master.ID.String()+")", nil, "")
+	if err != nil {
+		return fmt.Errorf("failed to get endorsements: %w", err)
+	}
+
+	var rows []endorsementRow
+	if err := json.Unmarshal(resp, &rows); err != nil {
+		return fmt.Errorf("failed to parse endorsements: %w", err)
+	}
+
+	skip := map[string]bool{master.UserID.String(): true}
+	for _, row := range rows {
+		if row.ComplaintID == master.ID.String() {
+			skip[row.UserID] = true
+		}
+	}
+
+	inserts := make([]map[string]interface{}, 0, len(rows))
+	for _, row := range rows {
+		if row.ComplaintID != duplicateID || skip[row.UserID] {
+			continue
+		}
+		skip[row.UserID] = true
+		inserts = append(inserts, map[string]interface{}{
+			"complaint_id": master.ID.String(),
+			"user_id":      row.UserID,
+			"created_at":   row.CreatedAt,
+		})
+	}
+	if len(inserts) == 0 {
+		return nil
+	}
+
+	// Rows are copied rather than updated in place so the count trigger,
+	// which runs on insert and delete, keeps both complaints in sync
+	if _, err := c.doRequest("POST", "/rest/v1/complaint_endorsements", inserts, ""); err != nil {
+		return fmt.Errorf("failed to move endorsements: %w", err)
+	}
+	if _, err := c.doRequest("DELETE", "/rest/v1/complaint_endorsements?complaint_id=eq."+duplicateID, nil, ""); err != nil {
+		return fmt.Errorf("failed to move endorsements: %w", err)
+	}
+	return nil
+}
+
+// GetEndorsers returns the users who endorsed any of the given complaints
+func (c *Client) GetEndorsers(token string, complaintIDs []string) ([]models.Endorsement, error) {
+	if len(complaintIDs) == 0 {
+		return nil, nil
+	}
+
+	query := "/rest/v1/complaint_endorsements?select=*&complaint_id=in.(" + strings.Join(complaintIDs, ",") + ")"
+
+	resp, err := c.doRequest("GET", query, nil, token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get endorsements: %w", err)
+	}
+
+	var rows []endorsementRow
+	if err := json.Unmarshal(resp, &rows); err != nil {
+		return nil, fmt.Errorf("failed to parse endorsements: %w", err)
+	}
+
+	endorsements := make([]models.Endorsement, 0, len(rows))
+	for _, row := range rows {
+		e := models.Endorsement{
+			ID:          uuid.MustParse(row.ID),
+			ComplaintID: uuid.MustParse(row.ComplaintID),
+			UserID:      uuid.MustParse(row.UserID),
+		}
+		if t, err := time.Parse(time.RFC3339, row.CreatedAt); err == nil {
+			e.CreatedAt = t
+		}
+		endorsements = append(endorsements, e)
+	}
+
+	return endorsements, nil
+}
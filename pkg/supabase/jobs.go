@@ -0,0 +1,320 @@
+package supabase
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hakim/backend/internal/models"
+)
+
+// ============================================
+// CLASSIFICATION QUEUE METHODS
+// ============================================
+
+// Classification statuses stored on complaints.classification_status
+const (
+	ClassificationPending    = "pending"
+	ClassificationProcessing = "processing"
+	ClassificationCompleted  = "completed"
+	ClassificationFailed     = "failed"
+	// Waiting for the citizen to answer clarifying questions
+	ClassificationNeedsInfo = "needs_info"
+)
+
+// ClassificationJob is a queued request to classify a complaint
+type ClassificationJob struct {
+	ID          string `json:"id"`
+	ComplaintID string `json:"complaint_id"`
+	Attempts    int    `json:"attempts"`
+}
+
+// EnqueueClassification adds a complaint to the durable classification queue
+func (c *Client) EnqueueClassification(complaintID string) error {
+	insert := map[string]interface{}{
+		"complaint_id": complaintID,
+	}
+
+	if _, err := c.doRequest("POST", "/rest/v1/classification_jobs", insert, ""); err != nil {
+		return fmt.Errorf("failed to enqueue classification: %w", err)
+	}
+	return nil
+}
+
+// ClaimClassificationJobs locks up to limit queued jobs for this worker.
+// Jobs whose lock has gone stale are reclaimed by the database function.
+func (c *Client) ClaimClassificationJobs(workerID string, limit int) ([]ClassificationJob, error) {
+	body := map[string]interface{}{
+		"p_worker": workerID,
+		"p_limit":  limit,
+	}
+
+	resp, err := c.doRequest("POST", "/rest/v1/rpc/claim_classification_jobs", body, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim classification jobs: %w", err)
+	}
+
+	var jobs []ClassificationJob
+	if err := json.Unmarshal(resp, &jobs); err != nil {
+		return nil, fmt.Errorf("failed to parse classification jobs: %w", err)
+	}
+
+	return jobs, nil
+}
+
+// CompleteClassificationJob marks a job done
+func (c *Client) CompleteClassificationJob(jobID string) error {
+	update := map[string]interface{}{
+		"status":       "done",
+		"completed_at": time.Now().UTC().Format(time.RFC3339),
+		"last_error":   nil,
+	}
+
+	if _, err := c.doRequest("PATCH", "/rest/v1/classification_jobs?id=eq."+jobID, update, ""); err != nil {
+		return fmt.Errorf("failed to complete classification job: %w", err)
+	}
+	return nil
+}
+
+// FailClassificationJob records an error and either requeues the job at
+// retryAt or, when retryAt is nil, marks it permanently failed
+func (c *Client) FailClassificationJob(jobID, errMsg string, retryAt *time.Time) error {
+	update := map[string]interface{}{
+		"last_error": errMsg,
+		"locked_at":  nil,
+		"locked_by":  nil,
+	}
+	if retryAt != nil {
+		update["status"] = "queued"
+		update["run_after"] = retryAt.UTC().Format(time.RFC3339)
+	} else {
+		update["status"] = "failed"
+		update["completed_at"] = time.Now().UTC().Format(time.RFC3339)
+	}
+
+	if _, err := c.doRequest("PATCH", "/rest/v1/classification_jobs?id=eq."+jobID, update, ""); err != nil {
+		return fmt.Errorf("failed to update classification job: %w", err)
+	}
+	return nil
+}
+
+// SetClassificationStatus updates the classification state shown on a complaint
+func (c *Client) SetClassificationStatus(complaintID, status string) error {
+	update := map[string]interface{}{
+		"classification_status": status,
+	}
+
+	if _, err := c.doRequest("PATCH", "/rest/v1/complaints?id=eq."+complaintID, update, ""); err != nil {
+		return fmt.Errorf("failed to set classification status: %w", err)
+	}
+	return nil
+}
+
+// GetComplaintSystem loads a complaint with the service key, for background workers
+func (c *Client) GetComplaintSystem(id string) (*models.Complaint, error) {
+	return c.GetComplaintAdmin("", id)
+}
+
+// GetImageAttachments returns the images attached to a complaint, oldest first
+func (c *Client) GetImageAttachments(complaintID string) ([]models.ComplaintAttachment, error) {
+	return c.getAttachments("", complaintID, models.AttachmentImage)
+}
+
+// GetVoiceAttachments returns the voice notes attached to a complaint,
+// oldest first
+func (c *Client) GetVoiceAttachments(complaintID string) ([]models.ComplaintAttachment, error) {
+	return c.getAttachments("", complaintID, models.AttachmentVoice)
+}
+
+// GetComplaintAttachments returns every attachment of a complaint, with
+// image analysis and voice transcripts, for staff
+func (c *Client) GetComplaintAttachments(token, complaintID string) ([]models.ComplaintAttachment, error) {
+	return c.getAttachments(token, complaintID, "")
+}
+
+// getAttachments lists a complaint's attachments, optionally of one type
+func (c *Client) getAttachments(token, complaintID, fileType string) ([]models.ComplaintAttachment, error) {
+	query := "/rest/v1/attachments?select=*&complaint_id=eq." + complaintID + "&order=created_at.asc"
+	if fileType != "" {
+		query += "&file_type=eq." + fileType
+	}
+
+	resp, err := c.doRequest("GET", query, nil, token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get attachments: %w", err)
+	}
+
+	var attachments []models.ComplaintAttachment
+	if err := json.Unmarshal(resp, &attachments); err != nil {
+		return nil, fmt.Errorf("failed to parse attachments: %w", err)
+	}
+	return attachments, nil
+}
+
+// SetAttachmentAnalysis stores what the model saw in an image attachment
+func (c *Client) SetAttachmentAnalysis(attachmentID string, analysis AttachmentAnalysis) error {
+	labels := analysis.Labels
+	if labels == nil {
+		labels = []string{}
+	}
+	update := map[string]interface{}{
+		"ai_labels":      labels,
+		"ai_description": analysis.Description,
+	}
+	if _, err := c.doRequest("PATCH", "/rest/v1/attachments?id=eq."+attachmentID, update, ""); err != nil {
+		return fmt.Errorf("failed to store attachment analysis: %w", err)
+	}
+	return nil
+}
+
+// Transcription is the outcome of transcribing one voice note. Err is set
+// when it failed, in which case the text fields are empty.
+type Transcription struct {
+	Text            string
+	Language        string
+	DurationSeconds float64
+	Transcriber     string
+	Err             error
+}
+
+// SetAttachmentTranscript stores the transcript of a voice note, or why it
+// could not be transcribed
+func (c *Client) SetAttachmentTranscript(attachmentID string, transcription Transcription) error {
+	update := map[string]interface{}{
+		"transcriber":    transcription.Transcriber,
+		"transcribed_at": time.Now().UTC().Format(time.RFC3339),
+	}
+	if transcription.Err != nil {
+		update["transcription_status"] = models.TranscriptionFailed
+		update["transcription_error"] = transcription.Err.Error()
+	} else {
+		update["transcription_status"] = models.TranscriptionCompleted
+		update["transcription_error"] = nil
+		update["transcript"] = transcription.Text
+		update["transcript_language"] = transcription.Language
+		update["transcript_duration_seconds"] = transcription.DurationSeconds
+	}
+
+	if _, err := c.doRequest("PATCH", "/rest/v1/attachments?id=eq."+attachmentID, update, ""); err != nil {
+		return fmt.Errorf("failed to store transcript: %w", err)
+	}
+	return nil
+}
+
+// CategoryDecision is where a classified complaint is routed once the AI
+// category has been reconciled with the citizen's choice
+type CategoryDecision struct {
+	CategoryID     uuid.UUID
+	DepartmentID   uuid.UUID
+	Reconciliation string // models.CategoryAIOnly, CategoryAgreed, ...
+}
+
+// ApplyClassification writes a finished classification to a complaint,
+// routing it to the decided category and keeping the AI's own pick apart
+func (c *Client) ApplyClassification(complaintID string, classification *ClassificationResult, decision *CategoryDecision) (*models.Complaint, error) {
+	update := map[string]interface{}{
+		"category_reconciliation": decision.Reconciliation,
+		"ai_category":             classification.CategoryName,
+		"ai_category_confidence":  classification.Confidence,
+		"ai_priority":             classification.Priority,
+		"ai_tags":                 classification.Tags,
+		"ai_summary":              classification.Summary,
+		"ai_sentiment":            classification.Sentiment,
+		"ai_source":               classification.Source,
+		"classification_status":   ClassificationCompleted,
+	}
+	if classification.CategoryID != uuid.Nil {
+		update["ai_category_id"] = classification.CategoryID.String()
+	}
+	if decision.CategoryID != uuid.Nil {
+		update["category_id"] = decision.CategoryID.String()
+	}
+	if decision.DepartmentID != uuid.Nil {
+		update["department_id"] = decision.DepartmentID.String()
+	}
+	if classification.Priority != "" {
+		update["priority"] = classification.Priority
+	}
+
+	query := "/rest/v1/complaints?select=" + url.QueryEscape(complaintSelectFields) + "&id=eq." + complaintID
+
+	resp, err := c.doRequest("PATCH", query, update, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply classification: %w", err)
+	}
+
+	var rows []complaintRow
+	if err := json.Unmarshal(resp, &rows); err != nil {
+		return nil, fmt.Errorf("failed to parse complaint: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("complaint not found")
+	}
+
+	if err := c.RecordClassification(complaintID, classification); err != nil {
+		return nil, err
+	}
+
+	return rowToComplaint(&rows[0]), nil
+}
+
+// RejectComplaintSystem rejects a complaint after automatic screening, keeping
+// the reason and the rules that fired for appeals, and tells the citizen why
+func (c *Client) RejectComplaintSystem(complaint *models.Complaint, reason, source string, ruleIDs []uuid.UUID) error {
+	update := map[string]interface{}{
+		"status":                string(models.StatusRejected),
+		"classification_status": ClassificationCompleted,
+		"rejection_reason":      reason,
+		"rejection_source":      source,
+		"rejection_rule_ids":    uuidStrings(ruleIDs),
+	}
+
+	if _, err := c.doRequest("PATCH", "/rest/v1/complaints?id=eq."+complaint.ID.String(), update, ""); err != nil {
+		return fmt.Errorf("failed to reject complaint: %w", err)
+	}
+
+	// Log the rejection reason alongside the trigger-generated entry
+	historyInsert := map[string]interface{}{
+		"complaint_id": complaint.ID.String(),
+		"new_status":   string(models.StatusRejected),
+		"notes":        reason,
+	}
+	_, _ = c.doRequest("POST", "/rest/v1/status_history", historyInsert, "")
+
+	complaint.Status = models.StatusRejected
+	complaint.ClassificationStatus = ClassificationCompleted
+	complaint.RejectionReason = reason
+	complaint.RejectionSource = source
+	complaint.RejectionRuleIDs = ruleIDs
+
+	c.createNotification("", complaint.UserID.String(), complaint.ID.String(), "complaint_rejected",
+		"Complaint not accepted", "لم يتم قبول الشكوى",
+		"Complaint "+complaint.TrackingNumber+" was not accepted: "+reason+". You can appeal this decision.",
+		"لم يتم قبول الشكوى "+complaint.TrackingNumber+": "+reason+". يمكنك الاعتراض على هذا القرار.")
+
+	return nil
+}
+
+// uuidStrings converts IDs for array columns, keeping nil as an empty array
+func uuidStrings(ids []uuid.UUID) []string {
+	values := make([]string, 0, len(ids))
+	for _, id := range ids {
+		values = append(values, id.String())
+	}
+	return values
+}
+
+// NotifyClassified tells the citizen which department their complaint was routed to
+func (c *Client) NotifyClassified(complaint *models.Complaint) {
+	department, departmentAr := "the relevant department", "الجهة المختصة"
+	if complaint.Department != nil {
+		department, departmentAr = complaint.Department.Name, complaint.Department.NameAr
+	}
+
+	c.createNotification("", complaint.UserID.String(), complaint.ID.String(), "complaint_classified",
+		"Complaint routed", "تم توجيه الشكوى",
+		"Complaint "+complaint.TrackingNumber+" was routed to "+department,
+		"تم توجيه الشكوى "+complaint.TrackingNumber+" إلى "+departmentAr)
+}
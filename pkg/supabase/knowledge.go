@@ -0,0 +1,403 @@
+package supabase
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hakim/backend/internal/models"
+)
+
+// ============================================
+// KNOWLEDGE BASE METHODS
+// ============================================
+
+var (
+	ErrKnowledgeEntryNotFound = errors.New("knowledge base entry not found")
+	ErrKnowledgeForbidden     = errors.New("employees can only manage their own department's entries")
+	ErrNotDeflected           = errors.New("complaint was not answered from the knowledge base")
+	ErrDeflectionEscalated    = errors.New("complaint has already been escalated")
+)
+
+// GetKnowledgeEntries lists entries, optionally for one department (entries
+// for every department included) and only active ones
+func (c *Client) GetKnowledgeEntries(token, departmentID string, activeOnly bool) ([]models.KnowledgeEntry, error) {
+	query := "/rest/v1/knowledge_base?select=*&order=created_at.asc"
+	if departmentID != "" {
+		query += "&or=" + url.QueryEscape("(department_id.eq."+departmentID+",department_id.is.null)")
+	}
+	if activeOnly {
+		query += "&is_active=eq.true"
+	}
+
+	resp, err := c.doRequest("GET", query, nil, token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get knowledge base: %w", err)
+	}
+
+	var entries []models.KnowledgeEntry
+	if err := json.Unmarshal(resp, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse knowledge base: %w", err)
+	}
+
+	return entries, nil
+}
+
+// GetKnowledgeEntry returns a single entry
+func (c *Client) GetKnowledgeEntry(token, entryID string) (*models.KnowledgeEntry, error) {
+	resp, err := c.doRequest("GET", "/rest/v1/knowledge_base?select=*&id=eq."+entryID, nil, token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get knowledge base entry: %w", err)
+	}
+
+	return parseKnowledgeEntry(resp)
+}
+
+// CreateKnowledgeEntry adds an entry. Employees may only add entries for
+// their own department.
+func (c *Client) CreateKnowledgeEntry(token string, req *models.CreateKnowledgeEntryRequest, editor *UserProfile) (*models.KnowledgeEntry, error) {
+	if !canEditKnowledge(editor, req.DepartmentID) {
+		return nil, ErrKnowledgeForbidden
+	}
+
+	keywords := req.Keywords
+	if keywords == nil {
+		keywords = []string{}
+	}
+
+	insert := map[string]interface{}{
+		"question":    req.Question,
+		"question_ar": req.QuestionAr,
+		"answer":      req.Answer,
+		"answer_ar":   req.AnswerAr,
+		"keywords":    keywords,
+		"created_by":  editor.ID.String(),
+	}
+	if req.DepartmentID != nil {
+		insert["department_id"] = req.DepartmentID.String()
+	}
+	if req.CategoryID != nil {
+		insert["category_id"] = req.CategoryID.String()
+	}
+	if req.URL != "" {
+		insert["url"] = req.URL
+	}
+
+	resp, err := c.doRequest("POST", "/rest/v1/knowledge_base?select=*", insert, token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create knowledge base entry: %w", err)
+	}
+
+	return parseKnowledgeEntry(resp)
+}
+
+// UpdateKnowledgeEntry changes the provided fields of an entry
+func (c *Client) UpdateKnowledgeEntry(token, entryID string, req *models.UpdateKnowledgeEntryRequest, editor *UserProfile) (*models.KnowledgeEntry, error) {
+	existing, err := c.GetKnowledgeEntry(token, entryID)
+	if err != nil {
+		return nil, err
+	}
+	if !canEditKnowledge(editor, existing.DepartmentID) {
+		return nil, ErrKnowledgeForbidden
+	}
+
+	update := make(map[string]interface{})
+	if req.CategoryID != nil {
+		update["category_id"] = req.CategoryID.String()
+	}
+	if req.Question != nil {
+		update["question"] = *req.Question
+	}
+	if req.QuestionAr != nil {
+		update["question_ar"] = *req.QuestionAr
+	}
+	if req.Answer != nil {
+		update["answer"] = *req.Answer
+	}
+	if req.AnswerAr != nil {
+		update["answer_ar"] = *req.AnswerAr
+	}
+	if req.URL != nil {
+		update["url"] = *req.URL
+	}
+	if req.Keywords != nil {
+		update["keywords"] = req.Keywords
+	}
+	if req.IsActive != nil {
+		update["is_active"] = *req.IsActive
+	}
+
+	resp, err := c.doRequest("PATCH", "/rest/v1/knowledge_base?select=*&id=eq."+entryID, update, token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update knowledge base entry: %w", err)
+	}
+
+	return parseKnowledgeEntry(resp)
+}
+
+// DeleteKnowledgeEntry removes an entry. Past deflections keep its ID.
+func (c *Client) DeleteKnowledgeEntry(token, entryID string, editor *UserProfile) error {
+	existing, err := c.GetKnowledgeEntry(token, entryID)
+	if err != nil {
+		return err
+	}
+	if !canEditKnowledge(editor, existing.DepartmentID) {
+		return ErrKnowledgeForbidden
+	}
+
+	if _, err := c.doRequest("DELETE", "/rest/v1/knowledge_base?id=eq."+entryID, nil, token); err != nil {
+		return fmt.Errorf("failed to delete knowledge base entry: %w", err)
+	}
+	return nil
+}
+
+// canEditKnowledge reports whether the editor may change entries of a
+// department; entries for every department are left to admins
+func canEditKnowledge(editor *UserProfile, departmentID *uuid.UUID) bool {
+	if editor.Role != string(models.RoleEmployee) {
+		return true
+	}
+	return departmentID != nil && editor.DepartmentID != nil && *editor.DepartmentID == *departmentID
+}
+
+func parseKnowledgeEntry(resp []byte) (*models.KnowledgeEntry, error) {
+	var entries []models.KnowledgeEntry
+	if err := json.Unmarshal(resp, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse knowledge base entry: %w", err)
+	}
+	if len(entries) == 0 {
+		return nil, ErrKnowledgeEntryNotFound
+	}
+	return &entries[0], nil
+}
+
+// ============================================
+// DEFLECTION METHODS
+// ============================================
+
+type deflectionRow struct {
+	ID           string   `json:"id"`
+	ComplaintID  string   `json:"complaint_id"`
+	DepartmentID *string  `json:"department_id"`
+	EntryIDs     []string `json:"entry_ids"`
+	Escalated    bool     `json:"escalated"`
+	EscalatedAt  *string  `json:"escalated_at"`
+	CreatedAt    string   `json:"created_at"`
+}
+
+func rowToDeflection(row *deflectionRow) *models.ComplaintDeflection {
+	d := &models.ComplaintDeflection{
+		ID:           uuid.MustParse(row.ID),
+		ComplaintID:  uuid.MustParse(row.ComplaintID),
+		DepartmentID: parseOptionalUUID(row.DepartmentID),
+		EntryIDs:     make([]uuid.UUID, 0, len(row.EntryIDs)),
+		Escalated:    row.Escalated,
+	}
+	for _, id := range row.EntryIDs {
+		if entryID, err := uuid.Parse(id); err == nil {
+			d.EntryIDs = append(d.EntryIDs, entryID)
+		}
+	}
+	if row.EscalatedAt != nil {
+		if t, err := time.Parse(time.RFC3339, *row.EscalatedAt); err == nil {
+			d.EscalatedAt = &t
+		}
+	}
+	if t, err := time.Parse(time.RFC3339, row.CreatedAt); err == nil {
+		d.CreatedAt = t
+	}
+	return d
+}
+
+// DeflectComplaint closes an information request with answers from the
+// knowledge base and sends them to the citizen, who can escalate it to a
+// complaint if they don't help
+func (c *Client) DeflectComplaint(complaint *models.Complaint, matches []models.KnowledgeMatch) (*models.ComplaintDeflection, error) {
+	entryIDs := make([]uuid.UUID, 0, len(matches))
+	for _, m := range matches {
+		entryIDs = append(entryIDs, m.ID)
+	}
+
+	insert := map[string]interface{}{
+		"complaint_id": complaint.ID.String(),
+		"entry_ids":    uuidStrings(entryIDs),
+	}
+	if complaint.DepartmentID != uuid.Nil {
+		insert["department_id"] = complaint.DepartmentID.String()
+	}
+
+	resp, err := c.doRequest("POST", "/rest/v1/complaint_deflections?select=*", insert, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to record deflection: %w", err)
+	}
+	var rows []deflectionRow
+	if err := json.Unmarshal(resp, &rows); err != nil {
+		return nil, fmt.Errorf("failed to parse deflection: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("failed to record deflection")
+	}
+
+	update := map[string]interface{}{"status": string(models.StatusClosed)}
+	if _, err := c.doRequest("PATCH", "/rest/v1/complaints?id=eq."+complaint.ID.String(), update, ""); err != nil {
+		return nil, fmt.Errorf("failed to close deflected complaint: %w", err)
+	}
+
+	historyInsert := map[string]interface{}{
+		"complaint_id": complaint.ID.String(),
+		"old_status":   string(complaint.Status),
+		"new_status":   string(models.StatusClosed),
+		"notes":        "تمت الإجابة على الاستفسار من قاعدة المعرفة",
+	}
+	_, _ = c.doRequest("POST", "/rest/v1/status_history", historyInsert, "")
+
+	var body, bodyAr strings.Builder
+	body.WriteString("Complaint " + complaint.TrackingNumber + " looks like an information request. These answers may help:")
+	bodyAr.WriteString("يبدو أن الشكوى " + complaint.TrackingNumber + " استفسار عن معلومات. قد تفيدك هذه الإجابات:")
+	for _, m := range matches {
+		body.WriteString("\n- " + m.Question + ": " + m.Answer)
+		bodyAr.WriteString("\n- " + m.QuestionAr + ": " + m.AnswerAr)
+		if m.URL != "" {
+			body.WriteString(" " + m.URL)
+			bodyAr.WriteString(" " + m.URL)
+		}
+	}
+	body.WriteString("\nIf this doesn't answer your question you can send it on as a complaint.")
+	bodyAr.WriteString("\nإذا لم تجد إجابة لسؤالك يمكنك تحويله إلى شكوى.")
+
+	c.createNotification("", complaint.UserID.String(), complaint.ID.String(), "complaint_deflected",
+		"Answers to your enquiry", "إجابات على استفسارك", body.String(), bodyAr.String())
+
+	complaint.Status = models.StatusClosed
+	deflection := rowToDeflection(&rows[0])
+	for _, m := range matches {
+		deflection.Entries = append(deflection.Entries, m.KnowledgeEntry)
+	}
+	return deflection, nil
+}
+
+// GetComplaintDeflection returns the answers a complaint was deflected with,
+// or ErrNotDeflected
+func (c *Client) GetComplaintDeflection(token, complaintID string) (*models.ComplaintDeflection, error) {
+	resp, err := c.doRequest("GET", "/rest/v1/complaint_deflections?select=*&complaint_id=eq."+complaintID, nil, token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get deflection: %w", err)
+	}
+
+	var rows []deflectionRow
+	if err := json.Unmarshal(resp, &rows); err != nil {
+		return nil, fmt.Errorf("failed to parse deflection: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, ErrNotDeflected
+	}
+	deflection := rowToDeflection(&rows[0])
+
+	// Entries deleted since are left out
+	if len(rows[0].EntryIDs) > 0 {
+		query := "/rest/v1/knowledge_base?select=*&id=in.(" + strings.Join(rows[0].EntryIDs, ",") + ")"
+		resp, err := c.doRequest("GET", query, nil, "")
+		if err != nil {
+			return nil, fmt.Errorf("failed to get knowledge base entries: %w", err)
+		}
+		if err := json.Unmarshal(resp, &deflection.Entries); err != nil {
+			return nil, fmt.Errorf("failed to parse knowledge base entries: %w", err)
+		}
+	}
+
+	return deflection, nil
+}
+
+// EscalateDeflection reopens a deflected complaint the citizen says was not
+// answered. It is not deflected again; the caller queues it for
+// classification.
+func (c *Client) EscalateDeflection(complaintID, userID string) (*models.Complaint, error) {
+	complaint, err := c.GetComplaintSystem(complaintID)
+	if err != nil || complaint.UserID.String() != userID {
+		return nil, fmt.Errorf("complaint not found")
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	query := "/rest/v1/complaint_deflections?select=id&escalated=eq.false&complaint_id=eq." + complaintID
+	resp, err := c.doRequest("PATCH", query, map[string]interface{}{"escalated": true, "escalated_at": now}, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to escalate deflection: %w", err)
+	}
+	var rows []struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(resp, &rows); err != nil {
+		return nil, fmt.Errorf("failed to parse deflection: %w", err)
+	}
+	if len(rows) == 0 {
+		if complaint.DeflectionDeclined {
+			return nil, ErrDeflectionEscalated
+		}
+		return nil, ErrNotDeflected
+	}
+
+	update := map[string]interface{}{
+		"status":                string(models.StatusSubmitted),
+		"classification_status": ClassificationPending,
+		"deflection_declined":   true,
+	}
+	if _, err := c.doRequest("PATCH", "/rest/v1/complaints?id=eq."+complaintID, update, ""); err != nil {
+		return nil, fmt.Errorf("failed to reopen complaint: %w", err)
+	}
+
+	historyInsert := map[string]interface{}{
+		"complaint_id": complaintID,
+		"old_status":   string(complaint.Status),
+		"new_status":   string(models.StatusSubmitted),
+		"changed_by":   userID,
+		"notes":        "لم تُجب قاعدة المعرفة على الاستفسار وطلب المواطن معالجته كشكوى",
+	}
+	_, _ = c.doRequest("POST", "/rest/v1/status_history", historyInsert, "")
+
+	complaint.Status = models.StatusSubmitted
+	complaint.ClassificationStatus = ClassificationPending
+	complaint.DeflectionDeclined = true
+	return complaint, nil
+}
+
+// GetDeflectionStats reports, per department, how many complaints were
+// answered from the knowledge base, highest deflection rate first
+func (c *Client) GetDeflectionStats(token string) ([]models.DeflectionStat, error) {
+	resp, err := c.doRequest("GET", "/rest/v1/deflection_stats?select=*", nil, token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get deflection stats: %w", err)
+	}
+
+	var stats []models.DeflectionStat
+	if err := json.Unmarshal(resp, &stats); err != nil {
+		return nil, fmt.Errorf("failed to parse deflection stats: %w", err)
+	}
+
+	departmentNames := make(map[string]string)
+	if departments, err := c.GetDepartments(); err == nil {
+		for _, d := range departments {
+			departmentNames[d.ID.String()] = d.NameAr
+		}
+	}
+
+	for i := range stats {
+		s := &stats[i]
+		s.DepartmentName = departmentNames[s.DepartmentID]
+		if s.Complaints > 0 {
+			s.DeflectionRate = float64(s.Deflected) / float64(s.Complaints)
+		}
+		if s.Deflected > 0 {
+			s.EscalationRate = float64(s.Escalated) / float64(s.Deflected)
+		}
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].DeflectionRate > stats[j].DeflectionRate
+	})
+
+	return stats, nil
+}
@@ -0,0 +1,349 @@
+package supabase
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hakim/backend/internal/models"
+)
+
+// ============================================
+// CLASSIFICATION REVIEW METHODS
+// ============================================
+
+var (
+	ErrReviewNotPending = errors.New("review has already been resolved")
+	ErrReviewForbidden  = errors.New("only the complaint's department can review it")
+	ErrCategoryRequired = errors.New("category_id is required: the complaint has no category")
+)
+
+// reviewComplaintColumns are the complaint columns embedded in a review
+const reviewComplaintColumns = "id,tracking_number,title,description,status,priority,category_id,citizen_category_id,department_id,created_at"
+
+type reviewRow struct {
+	ID                  string   `json:"id"`
+	ComplaintID         string   `json:"complaint_id"`
+	Reason              string   `json:"reason"`
+	Status              string   `json:"status"`
+	PredictedCategoryID *string  `json:"predicted_category_id"`
+	PredictedPriority   *string  `json:"predicted_priority"`
+	PredictedConfidence *float64 `json:"predicted_confidence"`
+	PredictedSource     *string  `json:"predicted_source"`
+	FinalCategoryID     *string  `json:"final_category_id"`
+	FinalPriority       *string  `json:"final_priority"`
+	ReviewedBy          *string  `json:"reviewed_by"`
+	ReviewNote          *string  `json:"review_note"`
+	ReviewedAt          *string  `json:"reviewed_at"`
+	CreatedAt           string   `json:"created_at"`
+	Complaints          *struct {
+		ID                string  `json:"id"`
+		TrackingNumber    string  `json:"tracking_number"`
+		Title             string  `json:"title"`
+		Description       string  `json:"description"`
+		Status            string  `json:"status"`
+		Priority          string  `json:"priority"`
+		CategoryID        *string `json:"category_id"`
+		CitizenCategoryID *string `json:"citizen_category_id"`
+		DepartmentID      *string `json:"department_id"`
+		CreatedAt         string  `json:"created_at"`
+	} `json:"complaints,omitempty"`
+}
+
+func rowToReview(row *reviewRow) *models.ClassificationReview {
+	review := &models.ClassificationReview{
+		ID:                  uuid.MustParse(row.ID),
+		ComplaintID:         uuid.MustParse(row.ComplaintID),
+		Reason:              row.Reason,
+		Status:              models.ReviewStatus(row.Status),
+		PredictedCategoryID: parseOptionalUUID(row.PredictedCategoryID),
+		FinalCategoryID:     parseOptionalUUID(row.FinalCategoryID),
+		ReviewedBy:          parseOptionalUUID(row.ReviewedBy),
+	}
+	if row.PredictedPriority != nil {
+		review.PredictedPriority = *row.PredictedPriority
+	}
+	if row.PredictedConfidence != nil {
+		review.PredictedConfidence = *row.PredictedConfidence
+	}
+	if row.PredictedSource != nil {
+		review.PredictedSource = *row.PredictedSource
+	}
+	if row.FinalPriority != nil {
+		review.FinalPriority = *row.FinalPriority
+	}
+	if row.ReviewNote != nil {
+		review.ReviewNote = *row.ReviewNote
+	}
+	if row.ReviewedAt != nil {
+		if t, err := time.Parse(time.RFC3339, *row.ReviewedAt); err == nil {
+			review.ReviewedAt = &t
+		}
+	}
+	if t, err := time.Parse(time.RFC3339, row.CreatedAt); err == nil {
+		review.CreatedAt = t
+	}
+
+	if row.Complaints != nil {
+		complaint := &models.Complaint{
+			ID:             uuid.MustParse(row.Complaints.ID),
+			TrackingNumber: row.Complaints.TrackingNumber,
+			Title:          row.Complaints.Title,
+			Description:    row.Complaints.Description,
+			Status:         models.ComplaintStatus(row.Complaints.Status),
+			Priority:       models.ComplaintPriority(row.Complaints.Priority),
+
+			CitizenCategoryID: parseOptionalUUID(row.Complaints.CitizenCategoryID),
+		}
+		if row.Complaints.CategoryID != nil {
+			complaint.CategoryID = uuid.MustParse(*row.Complaints.CategoryID)
+		}
+		if row.Complaints.DepartmentID != nil {
+			complaint.DepartmentID = uuid.MustParse(*row.Complaints.DepartmentID)
+		}
+		if t, err := time.Parse(time.RFC3339, row.Complaints.CreatedAt); err == nil {
+			complaint.CreatedAt = t
+		}
+		review.Complaint = complaint
+	}
+
+	return review
+}
+
+// QueueClassificationReview puts a classification in the staff triage queue
+func (c *Client) QueueClassificationReview(complaintID string, result *ClassificationResult, reason string) error {
+	insert := map[string]interface{}{
+		"complaint_id":         complaintID,
+		"reason":               reason,
+		"predicted_priority":   result.Priority,
+		"predicted_confidence": result.Confidence,
+		"predicted_source":     result.Source,
+	}
+	if result.CategoryID != uuid.Nil {
+		insert["predicted_category_id"] = result.CategoryID.String()
+	}
+
+	if _, err := c.doRequest("POST", "/rest/v1/classification_reviews", insert, ""); err != nil {
+		return fmt.Errorf("failed to queue classification review: %w", err)
+	}
+	return nil
+}
+
+// GetClassificationReviews lists reviews, oldest first, optionally limited to
+// one status and the department a complaint is currently routed to
+func (c *Client) GetClassificationReviews(token, status, departmentID string, page, limit int) ([]models.ClassificationReview, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	offset := (page - 1) * limit
+
+	// An inner join lets the department filter apply to the review rows
+	embed := "complaints(" + reviewComplaintColumns + ")"
+	if departmentID != "" {
+		embed = "complaints!inner(" + reviewComplaintColumns + ")"
+	}
+
+	query := "/rest/v1/classification_reviews?select=" + url.QueryEscape("*,"+embed) +
+		"&order=created_at.asc&offset=" + strconv.Itoa(offset) + "&limit=" + strconv.Itoa(limit)
+	if status != "" {
+		query += "&status=eq." + status
+	}
+	if departmentID != "" {
+		query += "&complaints.department_id=eq." + departmentID
+	}
+
+	resp, err := c.doRequest("GET", query, nil, token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get classification reviews: %w", err)
+	}
+
+	var rows []reviewRow
+	if err := json.Unmarshal(resp, &rows); err != nil {
+		return nil, fmt.Errorf("failed to parse classification reviews: %w", err)
+	}
+
+	reviews := make([]models.ClassificationReview, 0, len(rows))
+	for i := range rows {
+		reviews = append(reviews, *rowToReview(&rows[i]))
+	}
+
+	return reviews, nil
+}
+
+func (c *Client) getReview(token, reviewID string) (*models.ClassificationReview, error) {
+	query := "/rest/v1/classification_reviews?select=" + url.QueryEscape("*,complaints("+reviewComplaintColumns+")") + "&id=eq." + reviewID
+
+	resp, err := c.doRequest("GET", query, nil, token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get classification review: %w", err)
+	}
+
+	var rows []reviewRow
+	if err := json.Unmarshal(resp, &rows); err != nil {
+		return nil, fmt.Errorf("failed to parse classification review: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("classification review not found")
+	}
+
+	return rowToReview(&rows[0]), nil
+}
+
+// ResolveClassificationReview confirms or corrects a queued classification.
+// Corrections are applied to the complaint, and every decision is stored as a
+// labeled example for training and evaluation.
+func (c *Client) ResolveClassificationReview(token, reviewID string, correction *models.ReviewCorrection, reviewer *UserProfile) (*models.ClassificationReview, error) {
+	review, err := c.getReview(token, reviewID)
+	if err != nil {
+		return nil, err
+	}
+	if review.Status != models.ReviewPending {
+		return nil, ErrReviewNotPending
+	}
+	complaint := review.Complaint
+	if complaint == nil {
+		return nil, fmt.Errorf("complaint not found")
+	}
+
+	if reviewer.Role == string(models.RoleEmployee) &&
+		(reviewer.DepartmentID == nil || *reviewer.DepartmentID != complaint.DepartmentID) {
+		return nil, ErrReviewForbidden
+	}
+
+	// Start from what the complaint currently has, which is the prediction
+	// unless staff already changed it some other way
+	finalCategoryID := complaint.CategoryID
+	finalPriority := string(complaint.Priority)
+	if correction.CategoryID != nil {
+		finalCategoryID = *correction.CategoryID
+	}
+	if correction.Priority != "" {
+		finalPriority = correction.Priority
+	}
+	if finalCategoryID == uuid.Nil {
+		return nil, ErrCategoryRequired
+	}
+
+	predictedCategoryID := uuid.Nil
+	if review.PredictedCategoryID != nil {
+		predictedCategoryID = *review.PredictedCategoryID
+	}
+	corrected := finalCategoryID != predictedCategoryID || finalPriority != review.PredictedPriority
+
+	status := models.ReviewConfirmed
+	if corrected {
+		status = models.ReviewCorrected
+	}
+
+	update := map[string]interface{}{
+		"status":            string(status),
+		"final_category_id": finalCategoryID.String(),
+		"final_priority":    finalPriority,
+		"reviewed_by":       reviewer.ID.String(),
+		"reviewed_at":       time.Now().UTC().Format(time.RFC3339),
+	}
+	if correction.Note != "" {
+		update["review_note"] = correction.Note
+	}
+
+	resp, err := c.doRequest("PATCH", "/rest/v1/classification_reviews?select=*&status=eq.pending&id=eq."+reviewID, update, token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update classification review: %w", err)
+	}
+
+	var rows []reviewRow
+	if err := json.Unmarshal(resp, &rows); err != nil {
+		return nil, fmt.Errorf("failed to parse classification review: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, ErrReviewNotPending
+	}
+
+	// The review is claimed first so that of two reviewers acting at once
+	// only one reroutes the complaint
+	if finalCategoryID != complaint.CategoryID || finalPriority != string(complaint.Priority) {
+		if err := c.applyReviewCorrection(token, complaint, finalCategoryID.String(), finalPriority, correction.Note, reviewer.ID.String()); err != nil {
+			c.reopenReview(token, reviewID)
+			return nil, err
+		}
+	}
+
+	label := map[string]interface{}{
+		"complaint_id":       complaint.ID.String(),
+		"review_id":          reviewID,
+		"title":              complaint.Title,
+		"description":        complaint.Description,
+		"category_id":        finalCategoryID.String(),
+		"priority":           finalPriority,
+		"predicted_priority": review.PredictedPriority,
+		"predicted_source":   review.PredictedSource,
+		"corrected":          corrected,
+		"labeled_by":         reviewer.ID.String(),
+	}
+	if review.PredictedCategoryID != nil {
+		label["predicted_category_id"] = review.PredictedCategoryID.String()
+	}
+	if _, err := c.doRequest("POST", "/rest/v1/classification_labels", label, token); err != nil {
+		return nil, fmt.Errorf("failed to record classification label: %w", err)
+	}
+
+	resolved := rowToReview(&rows[0])
+	resolved.Complaint = complaint
+	return resolved, nil
+}
+
+// reopenReview puts a claimed review back in the queue after its
+// correction could not be applied
+func (c *Client) reopenReview(token, reviewID string) {
+	update := map[string]interface{}{
+		"status":            string(models.ReviewPending),
+		"final_category_id": nil,
+		"final_priority":    nil,
+		"reviewed_by":       nil,
+		"reviewed_at":       nil,
+		"review_note":       nil,
+	}
+	_, _ = c.doRequest("PATCH", "/rest/v1/classification_reviews?id=eq."+reviewID, update, token)
+}
+
+// applyReviewCorrection reroutes a complaint to the reviewed category and priority
+func (c *Client) applyReviewCorrection(token string, complaint *models.Complaint, categoryID, priority, note, changedBy string) error {
+	category, err := c.GetCategory(categoryID)
+	if err != nil {
+		return err
+	}
+	if category == nil {
+		return ErrCategoryNotFound
+	}
+
+	update := map[string]interface{}{
+		"category_id":   categoryID,
+		"department_id": category.DepartmentID.String(),
+		"priority":      priority,
+	}
+	if _, err := c.doRequest("PATCH", "/rest/v1/complaints?id=eq."+complaint.ID.String(), update, token); err != nil {
+		return fmt.Errorf("failed to update complaint classification: %w", err)
+	}
+
+	historyNote := "تم تصحيح التصنيف إلى " + category.NameAr + " والأولوية إلى " + priority
+	if note != "" {
+		historyNote += ": " + note
+	}
+	historyInsert := map[string]interface{}{
+		"complaint_id": complaint.ID.String(),
+		"old_status":   string(complaint.Status),
+		"new_status":   string(complaint.Status),
+		"changed_by":   changedBy,
+		"notes":        historyNote,
+	}
+	_, _ = c.doRequest("POST", "/rest/v1/status_history", historyInsert, token)
+
+	return nil
+}
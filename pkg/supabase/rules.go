@@ -0,0 +1,171 @@
+package supabase
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/hakim/backend/internal/models"
+)
+
+// ============================================
+// CLASSIFICATION RULE METHODS
+// ============================================
+
+var (
+	ErrRuleNotFound  = errors.New("classification rule not found")
+	ErrRuleForbidden = errors.New("only admins can change classification rules")
+)
+
+// GetActiveClassificationRules loads every active rule for the rule engine
+func (c *Client) GetActiveClassificationRules() ([]models.ClassificationRule, error) {
+	resp, err := c.doRequest("GET", "/rest/v1/classification_rules?select=*&is_active=eq.true&order=created_at.asc", nil, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get classification rules: %w", err)
+	}
+
+	var rules []models.ClassificationRule
+	if err := json.Unmarshal(resp, &rules); err != nil {
+		return nil, fmt.Errorf("failed to parse classification rules: %w", err)
+	}
+
+	return rules, nil
+}
+
+// GetClassificationRules lists rules, active and inactive, optionally limited
+// to one rule set
+func (c *Client) GetClassificationRules(token, ruleSet string) ([]models.ClassificationRule, error) {
+	query := "/rest/v1/classification_rules?select=*&order=rule_set.asc,created_at.asc"
+	if ruleSet != "" {
+		query += "&rule_set=eq." + ruleSet
+	}
+
+	resp, err := c.doRequest("GET", query, nil, token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get classification rules: %w", err)
+	}
+
+	var rules []models.ClassificationRule
+	if err := json.Unmarshal(resp, &rules); err != nil {
+		return nil, fmt.Errorf("failed to parse classification rules: %w", err)
+	}
+
+	return rules, nil
+}
+
+// CreateClassificationRule adds a rule. Employees may read rules but only
+// admins change them.
+func (c *Client) CreateClassificationRule(token string, req *models.CreateRuleRequest, editor *UserProfile) (*models.ClassificationRule, error) {
+	if editor.Role == string(models.RoleEmployee) {
+		return nil, ErrRuleForbidden
+	}
+
+	weight := 1.0
+	if req.Weight != nil {
+		weight = *req.Weight
+	}
+
+	insert := map[string]interface{}{
+		"rule_set":    string(req.RuleSet),
+		"pattern":     req.Pattern,
+		"is_regex":    req.IsRegex,
+		"is_negative": req.IsNegative,
+		"weight":      weight,
+		"created_by":  editor.ID.String(),
+	}
+	if req.CategoryID != nil {
+		insert["category_id"] = req.CategoryID.String()
+	}
+	if req.Priority != "" {
+		insert["priority"] = req.Priority
+	}
+	if req.Description != "" {
+		insert["description"] = req.Description
+	}
+
+	resp, err := c.doRequest("POST", "/rest/v1/classification_rules?select=*", insert, token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create classification rule: %w", err)
+	}
+
+	return parseRule(resp)
+}
+
+// UpdateClassificationRule changes the provided fields of a rule
+func (c *Client) UpdateClassificationRule(token, ruleID string, req *models.UpdateRuleRequest, editor *UserProfile) (*models.ClassificationRule, error) {
+	if editor.Role == string(models.RoleEmployee) {
+		return nil, ErrRuleForbidden
+	}
+
+	update := make(map[string]interface{})
+	if req.Pattern != nil {
+		update["pattern"] = *req.Pattern
+	}
+	if req.IsRegex != nil {
+		update["is_regex"] = *req.IsRegex
+	}
+	if req.IsNegative != nil {
+		update["is_negative"] = *req.IsNegative
+	}
+	if req.Weight != nil {
+		update["weight"] = *req.Weight
+	}
+	if req.IsActive != nil {
+		update["is_active"] = *req.IsActive
+	}
+	if req.Description != nil {
+		update["description"] = *req.Description
+	}
+
+	resp, err := c.doRequest("PATCH", "/rest/v1/classification_rules?select=*&id=eq."+ruleID, update, token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update classification rule: %w", err)
+	}
+
+	return parseRule(resp)
+}
+
+// DeleteClassificationRule removes a rule
+func (c *Client) DeleteClassificationRule(token, ruleID string, editor *UserProfile) error {
+	if editor.Role == string(models.RoleEmployee) {
+		return ErrRuleForbidden
+	}
+
+	resp, err := c.doRequest("DELETE", "/rest/v1/classification_rules?select=id&id=eq."+ruleID, nil, token)
+	if err != nil {
+		return fmt.Errorf("failed to delete classification rule: %w", err)
+	}
+
+	var rows []struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(resp, &rows); err != nil {
+		return fmt.Errorf("failed to parse classification rule: %w", err)
+	}
+	if len(rows) == 0 {
+		return ErrRuleNotFound
+	}
+
+	return nil
+}
+
+// GetClassificationRule returns a single rule
+func (c *Client) GetClassificationRule(token, ruleID string) (*models.ClassificationRule, error) {
+	resp, err := c.doRequest("GET", "/rest/v1/classification_rules?select=*&id=eq."+ruleID, nil, token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get classification rule: %w", err)
+	}
+
+	return parseRule(resp)
+}
+
+func parseRule(resp []byte) (*models.ClassificationRule, error) {
+	var rules []models.ClassificationRule
+	if err := json.Unmarshal(resp, &rules); err != nil {
+		return nil, fmt.Errorf("failed to parse classification rule: %w", err)
+	}
+	if len(rules) == 0 {
+		return nil, ErrRuleNotFound
+	}
+	return &rules[0], nil
+}
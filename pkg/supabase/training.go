@@ -0,0 +1,88 @@
+package supabase
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// ============================================
+// TRAINING DATA METHODS
+// ============================================
+
+// TrainingExample is a complaint text with the category it was finally routed to
+type TrainingExample struct {
+	ComplaintID string `json:"complaint_id"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	CategoryID  string `json:"category_id"`
+}
+
+// GetTrainingExamples returns labeled complaint texts for the local
+// classifier, newest first. Staff review decisions take precedence; resolved
+// and closed complaints fill in with the category they ended up in.
+func (c *Client) GetTrainingExamples(limit int) ([]TrainingExample, error) {
+	if limit < 1 {
+		limit = 10000
+	}
+
+	resp, err := c.doRequest("GET", "/rest/v1/classification_labels?select=complaint_id,title,description,category_id"+
+		"&order=created_at.desc&limit="+strconv.Itoa(limit), nil, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get classification labels: %w", err)
+	}
+
+	var labels []TrainingExample
+	if err := json.Unmarshal(resp, &labels); err != nil {
+		return nil, fmt.Errorf("failed to parse classification labels: %w", err)
+	}
+
+	examples := make([]TrainingExample, 0, limit)
+	seen := make(map[string]bool)
+	for _, label := range labels {
+		// A complaint can be reviewed more than once; the newest decision wins
+		if seen[label.ComplaintID] {
+			continue
+		}
+		seen[label.ComplaintID] = true
+		examples = append(examples, label)
+	}
+	if len(examples) >= limit {
+		return examples[:limit], nil
+	}
+
+	resp, err = c.doRequest("GET", "/rest/v1/complaints?select=id,title,description,category_id"+
+		"&status=in.(resolved,closed)&category_id=not.is.null&merged_into=is.null"+
+		"&order=created_at.desc&limit="+strconv.Itoa(limit), nil, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get resolved complaints: %w", err)
+	}
+
+	var complaints []struct {
+		ID          string `json:"id"`
+		Title       string `json:"title"`
+		Description string `json:"description"`
+		CategoryID  string `json:"category_id"`
+	}
+	if err := json.Unmarshal(resp, &complaints); err != nil {
+		return nil, fmt.Errorf("failed to parse resolved complaints: %w", err)
+	}
+
+	for _, complaint := range complaints {
+		if len(examples) >= limit {
+			break
+		}
+		if seen[complaint.ID] {
+			continue
+		}
+		seen[complaint.ID] = true
+		examples = append(examples, TrainingExample{
+			ComplaintID: complaint.ID,
+			Title:       complaint.Title,
+			Description: complaint.Description,
+			CategoryID:  complaint.CategoryID,
+		})
+	}
+
+	return examples, nil
+}
@@ -0,0 +1,444 @@
+package supabase
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hakim/backend/internal/models"
+)
+
+// ============================================
+// TRANSFER METHODS
+// ============================================
+
+// defaultSLADays applies when the target category has no SLA configured
+const defaultSLADays = 14
+
+var (
+	ErrTransferNotPending     = errors.New("transfer is not pending")
+	ErrTransferForbidden      = errors.New("only the receiving department can respond to this transfer")
+	ErrUnknownDepartment      = errors.New("target department does not exist")
+	ErrSameDepartment         = errors.New("complaint already belongs to this department")
+	ErrCategoryNotInTarget    = errors.New("category does not belong to the target department")
+	ErrTransferAlreadyPending = errors.New("complaint already has a pending transfer")
+
+	// errComplaintNotMoved means the complaint itself could not be updated,
+	// so the transfer had no effect
+	errComplaintNotMoved = errors.New("failed to transfer complaint")
+)
+
+type transferRow struct {
+	ID               string  `json:"id"`
+	ComplaintID      string  `json:"complaint_id"`
+	FromDepartmentID *string `json:"from_department_id"`
+	ToDepartmentID   string  `json:"to_department_id"`
+	ToCategoryID     *string `json:"to_category_id"`
+	Reason           string  `json:"reason"`
+	Status           string  `json:"status"`
+	RequestedBy      *string `json:"requested_by"`
+	RespondedBy      *string `json:"responded_by"`
+	ResponseNote     *string `json:"response_note"`
+	RespondedAt      *string `json:"responded_at"`
+	CreatedAt        string  `json:"created_at"`
+}
+
+func parseOptionalUUID(s *string) *uuid.UUID {
+	if s == nil {
+		return nil
+	}
+	id, err := uuid.Parse(*s)
+	if err != nil {
+		return nil
+	}
+	return &id
+}
+
+func rowToTransfer(row *transferRow) *models.ComplaintTransfer {
+	transfer := &models.ComplaintTransfer{
+		ID:               uuid.MustParse(row.ID),
+		ComplaintID:      uuid.MustParse(row.ComplaintID),
+		FromDepartmentID: parseOptionalUUID(row.FromDepartmentID),
+		ToDepartmentID:   uuid.MustParse(row.ToDepartmentID),
+		ToCategoryID:     parseOptionalUUID(row.ToCategoryID),
+		Reason:           row.Reason,
+		Status:           models.TransferStatus(row.Status),
+		RequestedBy:      parseOptionalUUID(row.RequestedBy),
+		RespondedBy:      parseOptionalUUID(row.RespondedBy),
+	}
+	if row.ResponseNote != nil {
+		transfer.ResponseNote = *row.ResponseNote
+	}
+	if row.RespondedAt != nil {
+		if t, err := time.Parse(time.RFC3339, *row.RespondedAt); err == nil {
+			transfer.RespondedAt = &t
+		}
+	}
+	if t, err := time.Parse(time.RFC3339, row.CreatedAt); err == nil {
+		transfer.CreatedAt = t
+	}
+	return transfer
+}
+
+// TransferComplaint moves a complaint to another department. When acceptance
+// is required the transfer stays pending until the receiving department
+// responds; otherwise it is applied immediately.
+func (c *Client) TransferComplaint(token, complaintID string, req *models.TransferRequest, requestedBy string, requireAcceptance bool) (*models.ComplaintTransfer, error) {
+	complaint, err := c.GetComplaintAdmin(token, complaintID)
+	if err != nil {
+		return nil, err
+	}
+
+	if complaint.DepartmentID == req.ToDepartmentID {
+		return nil, ErrSameDepartment
+	}
+
+	departments, err := c.GetDepartments()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get departments: %w", err)
+	}
+	known := false
+	for _, d := range departments {
+		if d.ID == req.ToDepartmentID {
+			known = true
+			break
+		}
+	}
+	if !known {
+		return nil, ErrUnknownDepartment
+	}
+
+	if req.ToCategoryID != nil {
+		category, err := c.GetCategory(req.ToCategoryID.String())
+		if err != nil {
+			return nil, err
+		}
+		if category == nil {
+			return nil, ErrCategoryNotFound
+		}
+		if category.DepartmentID != req.ToDepartmentID {
+			return nil, ErrCategoryNotInTarget
+		}
+	}
+
+	// Only one open transfer per complaint
+	pending, err := c.doRequest("GET", "/rest/v1/complaint_transfers?select=id&status=eq.pending&complaint_id=eq."+complaintID, nil, token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check pending transfers: %w", err)
+	}
+	var pendingRows []transferRow
+	if err := json.Unmarshal(pending, &pendingRows); err == nil && len(pendingRows) > 0 {
+		return nil, ErrTransferAlreadyPending
+	}
+
+	insert := map[string]interface{}{
+		"complaint_id":     complaintID,
+		"to_department_id": req.ToDepartmentID.String(),
+		"reason":           req.Reason,
+		"status":           string(models.TransferPending),
+		"requested_by":     requestedBy,
+	}
+	if complaint.DepartmentID != uuid.Nil {
+		insert["from_department_id"] = complaint.DepartmentID.String()
+	}
+	if req.ToCategoryID != nil {
+		insert["to_category_id"] = req.ToCategoryID.String()
+	}
+
+	resp, err := c.doRequest("POST", "/rest/v1/complaint_transfers?select=*", insert, token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create transfer: %w", err)
+	}
+
+	var rows []transferRow
+	if err := json.Unmarshal(resp, &rows); err != nil {
+		return nil, fmt.Errorf("failed to parse transfer: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("transfer was not created")
+	}
+
+	transfer := rowToTransfer(&rows[0])
+
+	if requireAcceptance {
+		return transfer, nil
+	}
+
+	completed, err := c.completeTransfer(token, transfer, complaint, requestedBy, "")
+	if errors.Is(err, errComplaintNotMoved) {
+		// A pending row left behind would block every later transfer. Staff
+		// may not delete transfers, so the service key is used.
+		if _, delErr := c.doRequest("DELETE", "/rest/v1/complaint_transfers?id=eq."+transfer.ID.String(), nil, ""); delErr != nil {
+			return nil, fmt.Errorf("%w (and failed to remove the transfer: %v)", err, delErr)
+		}
+	}
+	return completed, err
+}
+
+// RespondToTransfer accepts or rejects a pending transfer on behalf of the
+// receiving department
+func (c *Client) RespondToTransfer(token, transferID string, accept bool, note string, responder *UserProfile) (*models.ComplaintTransfer, error) {
+	transfer, err := c.getTransfer(token, transferID)
+	if err != nil {
+		return nil, err
+	}
+
+	if transfer.Status != models.TransferPending {
+		return nil, ErrTransferNotPending
+	}
+
+	// Employees may only respond for their own department
+	if responder.Role == string(models.RoleEmployee) &&
+		(responder.DepartmentID == nil || *responder.DepartmentID != transfer.ToDepartmentID) {
+		return nil, ErrTransferForbidden
+	}
+
+	if !accept {
+		update := map[string]interface{}{
+			"status":        string(models.TransferRejected),
+			"responded_by":  responder.ID.String(),
+			"responded_at":  time.Now().UTC().Format(time.RFC3339),
+			"response_note": note,
+		}
+		resp, err := c.doRequest("PATCH", "/rest/v1/complaint_transfers?select=*&id=eq."+transferID, update, token)
+		if err != nil {
+			return nil, fmt.Errorf("failed to reject transfer: %w", err)
+		}
+		var rows []transferRow
+		if err := json.Unmarshal(resp, &rows); err != nil || len(rows) == 0 {
+			return nil, fmt.Errorf("transfer not found")
+		}
+		return rowToTransfer(&rows[0]), nil
+	}
+
+	complaint, err := c.GetComplaintAdmin(token, transfer.ComplaintID.String())
+	if err != nil {
+		return nil, err
+	}
+
+	return c.completeTransfer(token, transfer, complaint, responder.ID.String(), note)
+}
+
+// completeTransfer moves the complaint, resets its assignment, recalculates
+// the SLA deadline and records the move in the status history. Without a
+// target category the complaint keeps its category only if that belongs to
+// the receiving department; otherwise the category is cleared and the
+// default SLA applies.
+func (c *Client) completeTransfer(token string, transfer *models.ComplaintTransfer, complaint *models.Complaint, changedBy, note string) (*models.ComplaintTransfer, error) {
+	slaDays := defaultSLADays
+	var category *Category
+	if transfer.ToCategoryID != nil {
+		category, _ = c.GetCategory(transfer.ToCategoryID.String())
+	} else if complaint.CategoryID != uuid.Nil {
+		category, _ = c.GetCategory(complaint.CategoryID.String())
+		if category != nil && category.DepartmentID != transfer.ToDepartmentID {
+			category = nil
+		}
+	}
+	if category != nil && category.SLADays > 0 {
+		slaDays = category.SLADays
+	}
+
+	// Work restarts in the receiving department
+	status := complaint.Status
+	if status == models.StatusAssigned || status == models.StatusInProgress {
+		status = models.StatusInReview
+	}
+
+	update := map[string]interface{}{
+		"department_id": transfer.ToDepartmentID.String(),
+		"assigned_to":   nil,
+		"status":        string(status),
+		"sla_deadline":  time.Now().UTC().AddDate(0, 0, slaDays).Format(time.RFC3339),
+	}
+	switch {
+	case transfer.ToCategoryID != nil:
+		update["category_id"] = transfer.ToCategoryID.String()
+	case category == nil:
+		update["category_id"] = nil
+	}
+
+	if _, err := c.doRequest("PATCH", "/rest/v1/complaints?id=eq."+complaint.ID.String(), update, token); err != nil {
+		return nil, fmt.Errorf("%w: %w", errComplaintNotMoved, err)
+	}
+
+	// Duplicates merged into the complaint move with it
+	mergedUpdate := map[string]interface{}{
+		"department_id": update["department_id"],
+		"assigned_to":   nil,
+		"status":        update["status"],
+	}
+	if categoryID, ok := update["category_id"]; ok {
+		mergedUpdate["category_id"] = categoryID
+	}
+	_, _ = c.doRequest("PATCH", "/rest/v1/complaints?merged_into=eq."+complaint.ID.String(), mergedUpdate, token)
+
+	transferUpdate := map[string]interface{}{
+		"status":       string(models.TransferCompleted),
+		"responded_by": changedBy,
+		"responded_at": time.Now().UTC().Format(time.RFC3339),
+	}
+	if note != "" {
+		transferUpdate["response_note"] = note
+	}
+	resp, err := c.doRequest("PATCH", "/rest/v1/complaint_transfers?select=*&id=eq."+transfer.ID.String(), transferUpdate, token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to complete transfer: %w", err)
+	}
+
+	var rows []transferRow
+	if err := json.Unmarshal(resp, &rows); err != nil || len(rows) == 0 {
+		return nil, fmt.Errorf("transfer not found")
+	}
+
+	fromName := "غير محدد"
+	if complaint.Department != nil {
+		fromName = complaint.Department.NameAr
+	}
+	toName := transfer.ToDepartmentID.String()
+	if departments, err := c.GetDepartments(); err == nil {
+		for _, d := range departments {
+			if d.ID == transfer.ToDepartmentID {
+				toName = d.NameAr
+				break
+			}
+		}
+	}
+
+	historyInsert := map[string]interface{}{
+		"complaint_id": complaint.ID.String(),
+		"new_status":   string(status),
+		"changed_by":   changedBy,
+		"notes":        "تم تحويل الشكوى من " + fromName + " إلى " + toName + ": " + transfer.Reason,
+	}
+	if complaint.Status != status {
+		historyInsert["old_status"] = string(complaint.Status)
+	}
+	_, _ = c.doRequest("POST", "/rest/v1/status_history", historyInsert, token)
+
+	return rowToTransfer(&rows[0]), nil
+}
+
+func (c *Client) getTransfer(token, transferID string) (*models.ComplaintTransfer, error) {
+	resp, err := c.doRequest("GET", "/rest/v1/complaint_transfers?select=*&id=eq."+transferID, nil, token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transfer: %w", err)
+	}
+
+	var rows []transferRow
+	if err := json.Unmarshal(resp, &rows); err != nil {
+		return nil, fmt.Errorf("failed to parse transfer: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("transfer not found")
+	}
+
+	return rowToTransfer(&rows[0]), nil
+}
+
+// GetTransfers lists transfers, optionally filtered by status and receiving department
+func (c *Client) GetTransfers(token, status, toDepartmentID, complaintID string) ([]models.ComplaintTransfer, error) {
+	query := "/rest/v1/complaint_transfers?select=*&order=created_at.desc"
+	if status != "" {
+		query += "&status=eq." + status
+	}
+	if toDepartmentID != "" {
+		query += "&to_department_id=eq." + toDepartmentID
+	}
+	if complaintID != "" {
+		query += "&complaint_id=eq." + complaintID
+	}
+
+	resp, err := c.doRequest("GET", query, nil, token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transfers: %w", err)
+	}
+
+	var rows []transferRow
+	if err := json.Unmarshal(resp, &rows); err != nil {
+		return nil, fmt.Errorf("failed to parse transfers: %w", err)
+	}
+
+	transfers := make([]models.ComplaintTransfer, 0, len(rows))
+	for i := range rows {
+		transfers = append(transfers, *rowToTransfer(&rows[i]))
+	}
+
+	return transfers, nil
+}
+
+// GetTransferStats counts transfers per department pair so misrouting
+// patterns become visible
+func (c *Client) GetTransferStats(token string, since *time.Time) ([]models.TransferPairCount, error) {
+	query := "/rest/v1/complaint_transfers?select=from_department_id,to_department_id,status"
+	if since != nil {
+		query += "&created_at=gte." + url.QueryEscape(since.UTC().Format(time.RFC3339))
+	}
+
+	resp, err := c.doRequest("GET", query, nil, token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transfer stats: %w", err)
+	}
+
+	var rows []transferRow
+	if err := json.Unmarshal(resp, &rows); err != nil {
+		return nil, fmt.Errorf("failed to parse transfer stats: %w", err)
+	}
+
+	departmentNames := make(map[string]string)
+	if departments, err := c.GetDepartments(); err == nil {
+		for _, d := range departments {
+			departmentNames[d.ID.String()] = d.NameAr
+		}
+	}
+
+	pairs := make(map[string]*models.TransferPairCount)
+	for _, row := range rows {
+		from := ""
+		if row.FromDepartmentID != nil {
+			from = *row.FromDepartmentID
+		}
+		key := from + "_" + row.ToDepartmentID
+
+		pair, ok := pairs[key]
+		if !ok {
+			fromName := departmentNames[from]
+			if fromName == "" {
+				fromName = "غير محدد"
+			}
+			pair = &models.TransferPairCount{
+				FromDepartmentID:   from,
+				FromDepartmentName: fromName,
+				ToDepartmentID:     row.ToDepartmentID,
+				ToDepartmentName:   departmentNames[row.ToDepartmentID],
+			}
+			pairs[key] = pair
+		}
+
+		pair.Total++
+		switch models.TransferStatus(row.Status) {
+		case models.TransferCompleted:
+			pair.Completed++
+		case models.TransferRejected:
+			pair.Rejected++
+		case models.TransferPending:
+			pair.Pending++
+		}
+	}
+
+	result := make([]models.TransferPairCount, 0, len(pairs))
+	for _, pair := range pairs {
+		result = append(result, *pair)
+	}
+
+	// Sort by total descending
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Total > result[j].Total
+	})
+
+	return result, nil
+}
@@ -0,0 +1,134 @@
+package supabase
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hakim/backend/internal/models"
+)
+
+// ============================================
+// LLM USAGE METHODS
+// ============================================
+
+// RecordLLMUsage stores one row per LLM call made for a complaint. The
+// department is the one the complaint was routed to, if any.
+func (c *Client) RecordLLMUsage(complaintID string, departmentID uuid.UUID, usage []LLMUsage) error {
+	if len(usage) == 0 {
+		return nil
+	}
+
+	rows := make([]map[string]interface{}, 0, len(usage))
+	for _, u := range usage {
+		row := map[string]interface{}{
+			"complaint_id":      complaintID,
+			"purpose":           u.Purpose,
+			"provider":          u.Provider,
+			"model":             u.Model,
+			"prompt_tokens":     u.PromptTokens,
+			"completion_tokens": u.CompletionTokens,
+			"cost_usd":          u.CostUSD,
+			"latency_ms":        u.LatencyMs,
+		}
+		if departmentID != uuid.Nil {
+			row["department_id"] = departmentID.String()
+		}
+		if u.ImageDetail != "" {
+			row["image_detail"] = u.ImageDetail
+		}
+		rows = append(rows, row)
+	}
+
+	if _, err := c.doRequest("POST", "/rest/v1/ai_usage", rows, ""); err != nil {
+		return fmt.Errorf("failed to record LLM usage: %w", err)
+	}
+	return nil
+}
+
+// GetMonthlyLLMSpend returns the USD spent on LLM calls since the start of
+// the current month (UTC)
+func (c *Client) GetMonthlyLLMSpend() (float64, error) {
+	now := time.Now().UTC()
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).Format("2006-01-02")
+
+	resp, err := c.doRequest("GET", "/rest/v1/ai_usage_monthly?select=cost_usd&month=eq."+month, nil, "")
+	if err != nil {
+		return 0, fmt.Errorf("failed to get LLM spend: %w", err)
+	}
+
+	var rows []struct {
+		CostUSD float64 `json:"cost_usd"`
+	}
+	if err := json.Unmarshal(resp, &rows); err != nil {
+		return 0, fmt.Errorf("failed to parse LLM spend: %w", err)
+	}
+	if len(rows) == 0 {
+		return 0, nil
+	}
+	return rows[0].CostUSD, nil
+}
+
+// GetAIUsage returns LLM usage per day, department and model, newest first
+func (c *Client) GetAIUsage(token string, since *time.Time, departmentID string) (*models.AIUsageReport, error) {
+	query := "/rest/v1/ai_usage_daily?select=*&order=date.desc,cost_usd.desc"
+	if since != nil {
+		query += "&date=gte." + url.QueryEscape(since.UTC().Format("2006-01-02"))
+	}
+	if departmentID != "" {
+		query += "&department_id=eq." + departmentID
+	}
+
+	resp, err := c.doRequest("GET", query, nil, token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get AI usage: %w", err)
+	}
+
+	var rows []struct {
+		Date             string  `json:"date"`
+		DepartmentID     *string `json:"department_id"`
+		Model            *string `json:"model"`
+		Calls            int     `json:"calls"`
+		PromptTokens     int     `json:"prompt_tokens"`
+		CompletionTokens int     `json:"completion_tokens"`
+		CostUSD          float64 `json:"cost_usd"`
+	}
+	if err := json.Unmarshal(resp, &rows); err != nil {
+		return nil, fmt.Errorf("failed to parse AI usage: %w", err)
+	}
+
+	departmentNames := make(map[string]string)
+	if departments, err := c.GetDepartments(); err == nil {
+		for _, d := range departments {
+			departmentNames[d.ID.String()] = d.NameAr
+		}
+	}
+
+	report := &models.AIUsageReport{Usage: make([]models.AIUsage, 0, len(rows))}
+	for _, row := range rows {
+		usage := models.AIUsage{
+			Date:             row.Date,
+			Calls:            row.Calls,
+			PromptTokens:     row.PromptTokens,
+			CompletionTokens: row.CompletionTokens,
+			CostUSD:          row.CostUSD,
+		}
+		if row.DepartmentID != nil {
+			usage.DepartmentID = *row.DepartmentID
+			usage.DepartmentName = departmentNames[*row.DepartmentID]
+		}
+		if row.Model != nil {
+			usage.Model = *row.Model
+		}
+		report.Usage = append(report.Usage, usage)
+
+		report.Calls += row.Calls
+		report.PromptTokens += row.PromptTokens
+		report.CompletionTokens += row.CompletionTokens
+		report.CostUSD += row.CostUSD
+	}
+
+	return report, nil
+}
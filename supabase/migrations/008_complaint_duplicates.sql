@@ -0,0 +1,30 @@
+-- Migration 008: Duplicate Complaint Detection & Merging
+-- Adds links between near-duplicate complaints so staff can merge them under a master
+
+-- ============================================
+-- DUPLICATE LINK COLUMNS
+-- ============================================
+
+-- Best duplicate match found when the complaint was submitted (pending staff review)
+ALTER TABLE complaints ADD COLUMN IF NOT EXISTS suggested_duplicate_of UUID REFERENCES complaints(id) ON DELETE SET NULL;
+
+-- Master complaint this one was merged into; status updates fan out from the master
+ALTER TABLE complaints ADD COLUMN IF NOT EXISTS merged_into UUID REFERENCES complaints(id) ON DELETE SET NULL;
+
+ALTER TABLE complaints DROP CONSTRAINT IF EXISTS complaints_not_merged_into_self;
+ALTER TABLE complaints ADD CONSTRAINT complaints_not_merged_into_self CHECK (merged_into IS NULL OR merged_into <> id);
+
+-- ============================================
+-- INDEXES
+-- ============================================
+
+-- Lookup of duplicates linked to a master
+CREATE INDEX IF NOT EXISTS idx_complaints_merged_into
+ON complaints(merged_into) WHERE merged_into IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_complaints_suggested_duplicate_of
+ON complaints(suggested_duplicate_of) WHERE suggested_duplicate_of IS NOT NULL;
+
+-- Candidate search by category, time window and coordinates
+CREATE INDEX IF NOT EXISTS idx_complaints_duplicate_search
+ON complaints(category_id, created_at DESC, latitude, longitude) WHERE merged_into IS NULL;
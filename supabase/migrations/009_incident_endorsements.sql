@@ -0,0 +1,71 @@
+-- Migration 009: "Me Too" Endorsements
+-- Lets citizens endorse an existing public incident instead of filing a new complaint
+
+-- ============================================
+-- ENDORSEMENTS TABLE
+-- ============================================
+
+CREATE TABLE IF NOT EXISTS complaint_endorsements (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    complaint_id UUID NOT NULL REFERENCES complaints(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES profiles(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE (complaint_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_complaint_endorsements_complaint_id ON complaint_endorsements(complaint_id);
+CREATE INDEX IF NOT EXISTS idx_complaint_endorsements_user_id ON complaint_endorsements(user_id);
+
+-- ============================================
+-- DENORMALIZED COUNT ON COMPLAINTS
+-- ============================================
+
+ALTER TABLE complaints ADD COLUMN IF NOT EXISTS endorsement_count INTEGER DEFAULT 0 NOT NULL;
+
+-- Keep complaints.endorsement_count in sync (citizens cannot update others' complaints)
+CREATE OR REPLACE FUNCTION update_endorsement_count()
+RETURNS TRIGGER
+LANGUAGE plpgsql
+SECURITY DEFINER
+SET search_path = public
+AS $$
+BEGIN
+    UPDATE complaints
+    SET endorsement_count = (
+        SELECT COUNT(*) FROM complaint_endorsements
+        WHERE complaint_id = COALESCE(NEW.complaint_id, OLD.complaint_id)
+    )
+    WHERE id = COALESCE(NEW.complaint_id, OLD.complaint_id);
+    RETURN NULL;
+END;
+$$;
+
+DROP TRIGGER IF EXISTS complaint_endorsements_count ON complaint_endorsements;
+CREATE TRIGGER complaint_endorsements_count
+    AFTER INSERT OR DELETE ON complaint_endorsements
+    FOR EACH ROW EXECUTE FUNCTION update_endorsement_count();
+
+-- ============================================
+-- RLS POLICIES
+-- ============================================
+
+ALTER TABLE complaint_endorsements ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY complaint_endorsements_select_policy ON complaint_endorsements
+    FOR SELECT
+    USING (
+        user_id = (SELECT auth.uid())
+        OR EXISTS (
+            SELECT 1 FROM profiles
+            WHERE profiles.id = (SELECT auth.uid())
+            AND profiles.role IN ('admin', 'super_admin', 'employee')
+        )
+    );
+
+CREATE POLICY complaint_endorsements_insert_policy ON complaint_endorsements
+    FOR INSERT
+    WITH CHECK (user_id = (SELECT auth.uid()));
+
+CREATE POLICY complaint_endorsements_delete_policy ON complaint_endorsements
+    FOR DELETE
+    USING (user_id = (SELECT auth.uid()));
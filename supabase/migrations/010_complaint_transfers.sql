@@ -0,0 +1,75 @@
+-- Migration 010: Inter-Department Complaint Transfers
+-- Tracks complaints moved between departments, with an optional acceptance step
+
+-- ============================================
+-- TRANSFER STATUS ENUM
+-- ============================================
+
+DO $$ BEGIN
+    CREATE TYPE transfer_status AS ENUM ('pending', 'completed', 'rejected');
+EXCEPTION
+    WHEN duplicate_object THEN NULL;
+END $$;
+
+-- ============================================
+-- TRANSFERS TABLE
+-- ============================================
+
+CREATE TABLE IF NOT EXISTS complaint_transfers (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    complaint_id UUID NOT NULL REFERENCES complaints(id) ON DELETE CASCADE,
+    from_department_id UUID REFERENCES departments(id) ON DELETE SET NULL,
+    to_department_id UUID NOT NULL REFERENCES departments(id) ON DELETE CASCADE,
+    to_category_id UUID REFERENCES categories(id) ON DELETE SET NULL,
+    reason TEXT NOT NULL,
+    status transfer_status DEFAULT 'pending' NOT NULL,
+    requested_by UUID REFERENCES profiles(id) ON DELETE SET NULL,
+    responded_by UUID REFERENCES profiles(id) ON DELETE SET NULL,
+    response_note TEXT,
+    responded_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_complaint_transfers_complaint_id ON complaint_transfers(complaint_id);
+CREATE INDEX IF NOT EXISTS idx_complaint_transfers_to_department_status ON complaint_transfers(to_department_id, status);
+CREATE INDEX IF NOT EXISTS idx_complaint_transfers_pair ON complaint_transfers(from_department_id, to_department_id);
+
+-- At most one pending transfer per complaint
+CREATE UNIQUE INDEX IF NOT EXISTS idx_complaint_transfers_one_pending
+ON complaint_transfers(complaint_id) WHERE status = 'pending';
+
+-- ============================================
+-- RLS POLICIES (staff only)
+-- ============================================
+
+ALTER TABLE complaint_transfers ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY complaint_transfers_select_policy ON complaint_transfers
+    FOR SELECT
+    USING (
+        EXISTS (
+            SELECT 1 FROM profiles
+            WHERE profiles.id = (SELECT auth.uid())
+            AND profiles.role IN ('admin', 'super_admin', 'employee')
+        )
+    );
+
+CREATE POLICY complaint_transfers_insert_policy ON complaint_transfers
+    FOR INSERT
+    WITH CHECK (
+        EXISTS (
+            SELECT 1 FROM profiles
+            WHERE profiles.id = (SELECT auth.uid())
+            AND profiles.role IN ('admin', 'super_admin', 'employee')
+        )
+    );
+
+CREATE POLICY complaint_transfers_update_policy ON complaint_transfers
+    FOR UPDATE
+    USING (
+        EXISTS (
+            SELECT 1 FROM profiles
+            WHERE profiles.id = (SELECT auth.uid())
+            AND profiles.role IN ('admin', 'super_admin', 'employee')
+        )
+    );
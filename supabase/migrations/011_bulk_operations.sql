@@ -0,0 +1,62 @@
+-- Migration 011: Bulk Operations on Complaints
+-- Adds staff tags on complaints and a table tracking large background bulk jobs
+
+-- ============================================
+-- STAFF TAGS
+-- ============================================
+
+ALTER TABLE complaints ADD COLUMN IF NOT EXISTS tags TEXT[] DEFAULT '{}';
+
+CREATE INDEX IF NOT EXISTS idx_complaints_tags ON complaints USING GIN(tags);
+
+-- ============================================
+-- BULK JOBS TABLE
+-- ============================================
+
+DO $$ BEGIN
+    CREATE TYPE bulk_job_status AS ENUM ('queued', 'running', 'completed', 'failed');
+EXCEPTION
+    WHEN duplicate_object THEN NULL;
+END $$;
+
+CREATE TABLE IF NOT EXISTS bulk_jobs (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    action VARCHAR(20) NOT NULL,
+    status bulk_job_status DEFAULT 'queued' NOT NULL,
+    request JSONB NOT NULL,
+    total INTEGER DEFAULT 0 NOT NULL,
+    processed INTEGER DEFAULT 0 NOT NULL,
+    succeeded INTEGER DEFAULT 0 NOT NULL,
+    failed INTEGER DEFAULT 0 NOT NULL,
+    results JSONB DEFAULT '[]'::jsonb,
+    error TEXT,
+    created_by UUID NOT NULL REFERENCES profiles(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    completed_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_bulk_jobs_created_by ON bulk_jobs(created_by, created_at DESC);
+
+-- ============================================
+-- RLS POLICIES (staff only)
+-- ============================================
+
+ALTER TABLE bulk_jobs ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY bulk_jobs_select_policy ON bulk_jobs
+    FOR SELECT
+    USING (
+        EXISTS (
+            SELECT 1 FROM profiles
+            WHERE profiles.id = (SELECT auth.uid())
+            AND profiles.role IN ('admin', 'super_admin', 'employee')
+        )
+    );
+
+CREATE POLICY bulk_jobs_insert_policy ON bulk_jobs
+    FOR INSERT
+    WITH CHECK (created_by = (SELECT auth.uid()));
+
+CREATE POLICY bulk_jobs_update_policy ON bulk_jobs
+    FOR UPDATE
+    USING (created_by = (SELECT auth.uid()));
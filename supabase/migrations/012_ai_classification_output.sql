@@ -0,0 +1,56 @@
+-- Migration 012: Persist Full AI Classification Output
+-- Stores every classification field on the complaint and a per-run log for staff review
+
+-- ============================================
+-- COMPLAINT AI COLUMNS
+-- ============================================
+-- ai_category, ai_category_confidence, ai_priority and ai_tags already exist (001)
+
+ALTER TABLE complaints ADD COLUMN IF NOT EXISTS ai_summary TEXT;
+ALTER TABLE complaints ADD COLUMN IF NOT EXISTS ai_sentiment VARCHAR(20);
+ALTER TABLE complaints ADD COLUMN IF NOT EXISTS ai_source VARCHAR(20); -- 'llm' or 'keywords'
+
+-- ============================================
+-- CLASSIFICATION LOG TABLE
+-- ============================================
+
+CREATE TABLE IF NOT EXISTS ai_classifications (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    complaint_id UUID NOT NULL REFERENCES complaints(id) ON DELETE CASCADE,
+    source VARCHAR(20) NOT NULL,
+    model VARCHAR(100),
+    category_id UUID REFERENCES categories(id) ON DELETE SET NULL,
+    category_name VARCHAR(100),
+    priority VARCHAR(20),
+    confidence DECIMAL(5, 4),
+    summary TEXT,
+    sentiment VARCHAR(20),
+    image_analysis TEXT,
+    tags TEXT[],
+    latency_ms INTEGER DEFAULT 0,
+    raw_response TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_ai_classifications_complaint_id
+ON ai_classifications(complaint_id, created_at DESC);
+
+CREATE INDEX IF NOT EXISTS idx_ai_classifications_source
+ON ai_classifications(source, created_at DESC);
+
+-- ============================================
+-- RLS POLICIES
+-- Raw model output is staff-only; rows are written by the backend service role
+-- ============================================
+
+ALTER TABLE ai_classifications ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY ai_classifications_select_policy ON ai_classifications
+    FOR SELECT
+    USING (
+        EXISTS (
+            SELECT 1 FROM profiles
+            WHERE profiles.id = (SELECT auth.uid())
+            AND profiles.role IN ('admin', 'super_admin', 'employee')
+        )
+    );
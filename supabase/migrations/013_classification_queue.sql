@@ -0,0 +1,97 @@
+-- Migration 013: Asynchronous Classification Queue
+-- Complaints are saved immediately and classified by background workers
+
+-- ============================================
+-- COMPLAINT CLASSIFICATION STATE
+-- ============================================
+
+ALTER TABLE complaints ADD COLUMN IF NOT EXISTS classification_status VARCHAR(20) DEFAULT 'completed';
+-- 'pending', 'processing', 'completed', 'failed'
+
+-- ============================================
+-- CLASSIFICATION JOBS TABLE
+-- ============================================
+
+CREATE TABLE IF NOT EXISTS classification_jobs (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    complaint_id UUID NOT NULL REFERENCES complaints(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'queued', -- 'queued', 'processing', 'done', 'failed'
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    run_after TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    locked_at TIMESTAMP WITH TIME ZONE,
+    locked_by VARCHAR(100),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    completed_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_classification_jobs_queue
+ON classification_jobs(status, run_after)
+WHERE status IN ('queued', 'processing');
+
+CREATE INDEX IF NOT EXISTS idx_classification_jobs_complaint_id
+ON classification_jobs(complaint_id);
+
+-- ============================================
+-- CLAIM FUNCTION
+-- Locks jobs with SKIP LOCKED so several workers or server instances can
+-- share the queue. Jobs locked for over 5 minutes are treated as abandoned.
+-- ============================================
+
+CREATE OR REPLACE FUNCTION claim_classification_jobs(p_worker TEXT, p_limit INTEGER)
+RETURNS SETOF classification_jobs
+LANGUAGE plpgsql
+SECURITY DEFINER
+SET search_path = public
+AS $$
+BEGIN
+    RETURN QUERY
+    UPDATE classification_jobs j
+    SET status = 'processing',
+        locked_at = NOW(),
+        locked_by = p_worker,
+        attempts = j.attempts + 1
+    WHERE j.id IN (
+        SELECT id FROM classification_jobs
+        WHERE (status = 'queued' AND run_after <= NOW())
+           OR (status = 'processing' AND locked_at < NOW() - INTERVAL '5 minutes')
+        ORDER BY created_at
+        LIMIT p_limit
+        FOR UPDATE SKIP LOCKED
+    )
+    RETURNING j.*;
+END;
+$$;
+
+REVOKE EXECUTE ON FUNCTION claim_classification_jobs(TEXT, INTEGER) FROM PUBLIC, anon, authenticated;
+
+-- ============================================
+-- RLS POLICIES
+-- The queue is internal; only the backend service role touches it
+-- ============================================
+
+ALTER TABLE classification_jobs ENABLE ROW LEVEL SECURITY;
+
+-- ============================================
+-- REALTIME
+-- Citizens subscribe to their own complaint and notification rows to see
+-- classification results as soon as they are applied
+-- ============================================
+
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM pg_publication WHERE pubname = 'supabase_realtime') THEN
+        IF NOT EXISTS (
+            SELECT 1 FROM pg_publication_tables
+            WHERE pubname = 'supabase_realtime' AND tablename = 'complaints'
+        ) THEN
+            ALTER PUBLICATION supabase_realtime ADD TABLE complaints;
+        END IF;
+        IF NOT EXISTS (
+            SELECT 1 FROM pg_publication_tables
+            WHERE pubname = 'supabase_realtime' AND tablename = 'notifications'
+        ) THEN
+            ALTER PUBLICATION supabase_realtime ADD TABLE notifications;
+        END IF;
+    END IF;
+END $$;
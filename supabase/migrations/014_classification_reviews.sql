@@ -0,0 +1,105 @@
+-- Migration 014: Classification Review Queue
+-- Low-confidence and fallback classifications wait for staff to confirm or
+-- correct them; every decision is kept as labeled training data
+
+-- ============================================
+-- REVIEW QUEUE TABLE
+-- ============================================
+
+CREATE TABLE IF NOT EXISTS classification_reviews (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    complaint_id UUID NOT NULL REFERENCES complaints(id) ON DELETE CASCADE,
+    reason VARCHAR(30) NOT NULL, -- 'low_confidence', 'fallback'
+    status VARCHAR(20) NOT NULL DEFAULT 'pending', -- 'pending', 'confirmed', 'corrected'
+    predicted_category_id UUID REFERENCES categories(id) ON DELETE SET NULL,
+    predicted_priority VARCHAR(20),
+    predicted_confidence DECIMAL(5, 4),
+    predicted_source VARCHAR(20),
+    final_category_id UUID REFERENCES categories(id) ON DELETE SET NULL,
+    final_priority VARCHAR(20),
+    reviewed_by UUID REFERENCES profiles(id) ON DELETE SET NULL,
+    review_note TEXT,
+    reviewed_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- At most one open review per complaint
+CREATE UNIQUE INDEX IF NOT EXISTS idx_classification_reviews_pending
+ON classification_reviews(complaint_id)
+WHERE status = 'pending';
+
+CREATE INDEX IF NOT EXISTS idx_classification_reviews_status
+ON classification_reviews(status, created_at);
+
+-- ============================================
+-- LABELED TRAINING DATA
+-- ============================================
+
+CREATE TABLE IF NOT EXISTS classification_labels (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    complaint_id UUID NOT NULL REFERENCES complaints(id) ON DELETE CASCADE,
+    review_id UUID REFERENCES classification_reviews(id) ON DELETE SET NULL,
+    title TEXT NOT NULL,
+    description TEXT NOT NULL,
+    category_id UUID NOT NULL REFERENCES categories(id) ON DELETE CASCADE,
+    priority VARCHAR(20) NOT NULL,
+    predicted_category_id UUID REFERENCES categories(id) ON DELETE SET NULL,
+    predicted_priority VARCHAR(20),
+    predicted_source VARCHAR(20),
+    corrected BOOLEAN NOT NULL DEFAULT false,
+    labeled_by UUID REFERENCES profiles(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_classification_labels_category_id
+ON classification_labels(category_id);
+
+CREATE INDEX IF NOT EXISTS idx_classification_labels_created_at
+ON classification_labels(created_at DESC);
+
+-- ============================================
+-- RLS POLICIES (staff only)
+-- ============================================
+
+ALTER TABLE classification_reviews ENABLE ROW LEVEL SECURITY;
+ALTER TABLE classification_labels ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY classification_reviews_select_policy ON classification_reviews
+    FOR SELECT
+    USING (
+        EXISTS (
+            SELECT 1 FROM profiles
+            WHERE profiles.id = (SELECT auth.uid())
+            AND profiles.role IN ('admin', 'super_admin', 'employee')
+        )
+    );
+
+CREATE POLICY classification_reviews_update_policy ON classification_reviews
+    FOR UPDATE
+    USING (
+        EXISTS (
+            SELECT 1 FROM profiles
+            WHERE profiles.id = (SELECT auth.uid())
+            AND profiles.role IN ('admin', 'super_admin', 'employee')
+        )
+    );
+
+CREATE POLICY classification_labels_select_policy ON classification_labels
+    FOR SELECT
+    USING (
+        EXISTS (
+            SELECT 1 FROM profiles
+            WHERE profiles.id = (SELECT auth.uid())
+            AND profiles.role IN ('admin', 'super_admin', 'employee')
+        )
+    );
+
+CREATE POLICY classification_labels_insert_policy ON classification_labels
+    FOR INSERT
+    WITH CHECK (
+        EXISTS (
+            SELECT 1 FROM profiles
+            WHERE profiles.id = (SELECT auth.uid())
+            AND profiles.role IN ('admin', 'super_admin', 'employee')
+        )
+    );
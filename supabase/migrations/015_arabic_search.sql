@@ -0,0 +1,40 @@
+-- Migration 015: Normalized Arabic Search
+-- Complaint text is indexed in normalized form so spelling variants
+-- (أ/إ/ا, ة/ه, ى/ي, diacritics, tatweel, Arabic-Indic digits) match
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- ============================================
+-- NORMALIZATION FUNCTION
+-- Keep in sync with arabic.Normalize in internal/arabic/normalize.go
+-- ============================================
+
+CREATE OR REPLACE FUNCTION normalize_arabic(input TEXT)
+RETURNS TEXT
+LANGUAGE sql
+IMMUTABLE
+PARALLEL SAFE
+SET search_path = public
+AS $$
+    SELECT lower(
+        regexp_replace(
+            translate(
+                -- diacritics, superscript alef and tatweel
+                regexp_replace(coalesce(input, ''), '[\u064B-\u0652\u0670\u0640]', '', 'g'),
+                'أإآٱةىؤئ٠١٢٣٤٥٦٧٨٩۰۱۲۳۴۵۶۷۸۹',
+                'ااااهيوي01234567890123456789'
+            ),
+            '\s+', ' ', 'g'
+        )
+    );
+$$;
+
+-- ============================================
+-- SEARCH COLUMN
+-- ============================================
+
+ALTER TABLE complaints ADD COLUMN IF NOT EXISTS search_text TEXT
+    GENERATED ALWAYS AS (normalize_arabic(title || ' ' || description)) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_complaints_search_text
+ON complaints USING gin (search_text gin_trgm_ops);
@@ -0,0 +1,120 @@
+-- Migration 016: Classification Rules
+-- Keyword, priority, spam and offensive-word rules used by the fallback
+-- classifier move from code into an admin-managed table. The API reloads
+-- active rules periodically, so changes apply without a redeploy.
+
+-- ============================================
+-- RULES TABLE
+-- ============================================
+
+CREATE TABLE IF NOT EXISTS classification_rules (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    rule_set VARCHAR(20) NOT NULL, -- 'category', 'priority', 'spam', 'offensive'
+    category_id UUID REFERENCES categories(id) ON DELETE CASCADE,
+    priority VARCHAR(20), -- 'critical', 'high', 'low'
+    pattern TEXT NOT NULL,
+    is_regex BOOLEAN NOT NULL DEFAULT false, -- regexes run on normalized text
+    is_negative BOOLEAN NOT NULL DEFAULT false, -- a match vetoes the target instead of voting for it
+    weight DECIMAL(6, 3) NOT NULL DEFAULT 1,
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    description TEXT,
+    created_by UUID REFERENCES profiles(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    CONSTRAINT classification_rules_rule_set_check
+        CHECK (rule_set IN ('category', 'priority', 'spam', 'offensive')),
+    CONSTRAINT classification_rules_target_check CHECK (
+        (rule_set = 'category' AND category_id IS NOT NULL AND priority IS NULL) OR
+        (rule_set = 'priority' AND priority IN ('critical', 'high', 'low') AND category_id IS NULL) OR
+        (rule_set IN ('spam', 'offensive') AND category_id IS NULL AND priority IS NULL)
+    )
+);
+
+CREATE INDEX IF NOT EXISTS idx_classification_rules_active
+ON classification_rules(rule_set)
+WHERE is_active = true;
+
+CREATE TRIGGER update_classification_rules_updated_at BEFORE UPDATE ON classification_rules
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at();
+
+-- ============================================
+-- SEED RULES
+-- Same patterns as the built-in defaults in internal/rules/defaults.go
+-- ============================================
+
+INSERT INTO classification_rules (rule_set, priority, pattern)
+SELECT 'priority', v.priority, v.pattern
+FROM (VALUES
+    ('critical', 'urgent'), ('critical', 'emergency'), ('critical', 'critical'),
+    ('critical', 'danger'), ('critical', 'طوارئ'), ('critical', 'عاجل'),
+    ('critical', 'خطر'), ('critical', 'حريق'), ('critical', 'انفجار'),
+    ('high', 'important'), ('high', 'serious'), ('high', 'مهم'),
+    ('high', 'خطير'), ('high', 'انقطاع'),
+    ('low', 'minor'), ('low', 'small'), ('low', 'بسيط'),
+    ('low', 'صغير'), ('low', 'استفسار')
+) AS v(priority, pattern);
+
+INSERT INTO classification_rules (rule_set, pattern)
+SELECT 'spam', pattern
+FROM unnest(ARRAY[
+    'test', 'testing', 'asdf', 'qwerty', 'aaaa', '1234', 'xxxx',
+    'تجربة', 'تست', 'اختبار فقط',
+    'ههههه', 'هاها', 'lol', 'haha'
+]) AS pattern;
+
+INSERT INTO classification_rules (rule_set, pattern)
+SELECT 'offensive', pattern
+FROM unnest(ARRAY['حمار', 'غبي', 'كلب', 'خنزير', 'لعنة']) AS pattern;
+
+-- Category keywords start from the keywords stored on each category
+INSERT INTO classification_rules (rule_set, category_id, pattern)
+SELECT 'category', c.id, k.keyword
+FROM categories c, unnest(c.keywords) AS k(keyword)
+WHERE c.keywords IS NOT NULL AND trim(k.keyword) <> '';
+
+-- ============================================
+-- RLS POLICIES (staff read, admins write)
+-- ============================================
+
+ALTER TABLE classification_rules ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY classification_rules_select_policy ON classification_rules
+    FOR SELECT
+    USING (
+        EXISTS (
+            SELECT 1 FROM profiles
+            WHERE profiles.id = (SELECT auth.uid())
+            AND profiles.role IN ('admin', 'super_admin', 'employee')
+        )
+    );
+
+CREATE POLICY classification_rules_insert_policy ON classification_rules
+    FOR INSERT
+    WITH CHECK (
+        EXISTS (
+            SELECT 1 FROM profiles
+            WHERE profiles.id = (SELECT auth.uid())
+            AND profiles.role IN ('admin', 'super_admin')
+        )
+    );
+
+CREATE POLICY classification_rules_update_policy ON classification_rules
+    FOR UPDATE
+    USING (
+        EXISTS (
+            SELECT 1 FROM profiles
+            WHERE profiles.id = (SELECT auth.uid())
+            AND profiles.role IN ('admin', 'super_admin')
+        )
+    );
+
+CREATE POLICY classification_rules_delete_policy ON classification_rules
+    FOR DELETE
+    USING (
+        EXISTS (
+            SELECT 1 FROM profiles
+            WHERE profiles.id = (SELECT auth.uid())
+            AND profiles.role IN ('admin', 'super_admin')
+        )
+    );
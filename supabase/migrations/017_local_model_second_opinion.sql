@@ -0,0 +1,16 @@
+-- Migration 017: Local Model Second Opinion
+-- The local statistical classifier runs alongside the LLM; its prediction is
+-- logged with each classification and disagreements go to staff review
+
+ALTER TABLE ai_classifications ADD COLUMN IF NOT EXISTS second_opinion_category_id UUID
+    REFERENCES categories(id) ON DELETE SET NULL;
+ALTER TABLE ai_classifications ADD COLUMN IF NOT EXISTS second_opinion_confidence DECIMAL(5, 4);
+ALTER TABLE ai_classifications ADD COLUMN IF NOT EXISTS second_opinion_model VARCHAR(100);
+ALTER TABLE ai_classifications ADD COLUMN IF NOT EXISTS model_disagreement BOOLEAN NOT NULL DEFAULT false;
+
+CREATE INDEX IF NOT EXISTS idx_ai_classifications_disagreement
+ON ai_classifications(created_at DESC)
+WHERE model_disagreement = true;
+
+COMMENT ON COLUMN complaints.ai_source IS 'llm, keywords or local_model';
+COMMENT ON COLUMN classification_reviews.reason IS 'low_confidence, fallback or model_disagreement';
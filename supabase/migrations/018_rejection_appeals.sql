@@ -0,0 +1,127 @@
+-- Migration 018: Rejection Appeals
+-- Automatically rejected complaints are kept with the reason and the rules
+-- that fired; citizens can appeal and moderators can overturn the rejection.
+-- Overturn rates per rule feed back into the screening filter.
+
+-- ============================================
+-- REJECTION DETAILS ON COMPLAINTS
+-- ============================================
+
+ALTER TABLE complaints ADD COLUMN IF NOT EXISTS rejection_reason TEXT;
+ALTER TABLE complaints ADD COLUMN IF NOT EXISTS rejection_source VARCHAR(20); -- 'pre_screen', 'llm'; NULL for staff rejections
+ALTER TABLE complaints ADD COLUMN IF NOT EXISTS rejection_rule_ids UUID[];
+-- Set when a moderator overturns a rejection; classification then skips screening
+ALTER TABLE complaints ADD COLUMN IF NOT EXISTS screening_overridden BOOLEAN NOT NULL DEFAULT false;
+
+CREATE INDEX IF NOT EXISTS idx_complaints_rejection_source
+ON complaints(rejection_source)
+WHERE rejection_source IS NOT NULL;
+
+-- ============================================
+-- APPEALS TABLE
+-- ============================================
+
+CREATE TABLE IF NOT EXISTS rejection_appeals (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    complaint_id UUID NOT NULL UNIQUE REFERENCES complaints(id) ON DELETE CASCADE, -- one appeal per complaint
+    user_id UUID NOT NULL REFERENCES profiles(id) ON DELETE CASCADE,
+    statement TEXT NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending', -- 'pending', 'upheld', 'overturned'
+    -- Snapshot of the rejection being appealed
+    rejection_reason TEXT,
+    rejection_source VARCHAR(20),
+    rejection_rule_ids UUID[],
+    reviewed_by UUID REFERENCES profiles(id) ON DELETE SET NULL,
+    review_note TEXT,
+    reviewed_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_rejection_appeals_status
+ON rejection_appeals(status, created_at);
+
+-- ============================================
+-- OVERRIDE STATISTICS
+-- ============================================
+
+-- Per screening rule: rejections it caused and how their appeals were decided
+CREATE OR REPLACE VIEW rejection_rule_stats WITH (security_invoker = true) AS
+WITH rejections AS (
+    SELECT rule_id, COUNT(*) AS rejections
+    FROM complaints, unnest(rejection_rule_ids) AS rule_id
+    GROUP BY rule_id
+),
+appeals AS (
+    SELECT
+        rule_id,
+        COUNT(*) AS appeals,
+        COUNT(*) FILTER (WHERE status = 'upheld') AS upheld,
+        COUNT(*) FILTER (WHERE status = 'overturned') AS overturned
+    FROM rejection_appeals, unnest(rejection_rule_ids) AS rule_id
+    GROUP BY rule_id
+)
+SELECT
+    r.rule_id,
+    cr.pattern,
+    cr.rule_set,
+    r.rejections,
+    COALESCE(a.appeals, 0) AS appeals,
+    COALESCE(a.upheld, 0) AS upheld,
+    COALESCE(a.overturned, 0) AS overturned
+FROM rejections r
+LEFT JOIN appeals a ON a.rule_id = r.rule_id
+LEFT JOIN classification_rules cr ON cr.id = r.rule_id;
+
+-- Per rejection source: pre-screen filter or LLM
+CREATE OR REPLACE VIEW rejection_source_stats WITH (security_invoker = true) AS
+SELECT
+    c.rejection_source AS source,
+    COUNT(*) AS rejections,
+    COUNT(a.id) AS appeals,
+    COUNT(a.id) FILTER (WHERE a.status = 'upheld') AS upheld,
+    COUNT(a.id) FILTER (WHERE a.status = 'overturned') AS overturned
+FROM complaints c
+LEFT JOIN rejection_appeals a ON a.complaint_id = c.id
+WHERE c.rejection_source IS NOT NULL
+GROUP BY c.rejection_source;
+
+-- ============================================
+-- STRAY ANIMAL EXCEPTIONS
+-- Reports about stray dogs mention "كلب"; these negative rules cancel the
+-- offensive verdict for them. Same as internal/rules/defaults.go.
+-- ============================================
+
+INSERT INTO classification_rules (rule_set, pattern, is_negative, description)
+SELECT 'offensive', pattern, true, 'Stray animal reports'
+FROM unnest(ARRAY['كلب ضال', 'كلاب ضال', 'كلب الضال', 'كلاب الضال']) AS pattern;
+
+-- ============================================
+-- RLS POLICIES
+-- ============================================
+
+ALTER TABLE rejection_appeals ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY rejection_appeals_select_policy ON rejection_appeals
+    FOR SELECT
+    USING (
+        user_id = (SELECT auth.uid())
+        OR EXISTS (
+            SELECT 1 FROM profiles
+            WHERE profiles.id = (SELECT auth.uid())
+            AND profiles.role IN ('admin', 'super_admin', 'employee')
+        )
+    );
+
+CREATE POLICY rejection_appeals_insert_policy ON rejection_appeals
+    FOR INSERT
+    WITH CHECK (user_id = (SELECT auth.uid()));
+
+CREATE POLICY rejection_appeals_update_policy ON rejection_appeals
+    FOR UPDATE
+    USING (
+        EXISTS (
+            SELECT 1 FROM profiles
+            WHERE profiles.id = (SELECT auth.uid())
+            AND profiles.role IN ('admin', 'super_admin', 'employee')
+        )
+    );
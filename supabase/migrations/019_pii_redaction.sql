@@ -0,0 +1,10 @@
+-- Migration 019: PII Redaction Audit
+-- Personal data is replaced with placeholders before complaint text is sent
+-- to the LLM. Each classification records which kinds were redacted and how
+-- many of each were found; the values themselves are never stored.
+
+ALTER TABLE ai_classifications ADD COLUMN IF NOT EXISTS redaction_kinds TEXT[];
+ALTER TABLE ai_classifications ADD COLUMN IF NOT EXISTS redactions JSONB;
+
+COMMENT ON COLUMN ai_classifications.redaction_kinds IS 'Kinds redacted before the LLM call; NULL when redaction was off or no LLM was called';
+COMMENT ON COLUMN ai_classifications.redactions IS 'Count of redacted values per kind, e.g. {"phone": 1}';
@@ -0,0 +1,13 @@
+-- Migration 020: Prompt Injection Signals
+-- Classifications whose complaint text contains instruction-like patterns,
+-- or whose LLM priority contradicts the keyword rules, are flagged and sent
+-- to staff review
+
+ALTER TABLE ai_classifications ADD COLUMN IF NOT EXISTS manipulation_signals TEXT[];
+
+CREATE INDEX IF NOT EXISTS idx_ai_classifications_manipulation
+ON ai_classifications(created_at DESC)
+WHERE manipulation_signals IS NOT NULL;
+
+COMMENT ON COLUMN ai_classifications.manipulation_signals IS 'ignore_instructions, role_override, set_classification, output_format, priority_mismatch';
+COMMENT ON COLUMN classification_reviews.reason IS 'low_confidence, fallback, model_disagreement or suspected_manipulation';
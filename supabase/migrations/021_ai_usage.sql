@@ -0,0 +1,63 @@
+-- Migration 021: AI Usage Accounting
+-- One row per LLM call with token counts and estimated cost, aggregated per
+-- day, department and model for admin analytics and the monthly budget
+
+CREATE TABLE IF NOT EXISTS ai_usage (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    complaint_id UUID REFERENCES complaints(id) ON DELETE SET NULL,
+    department_id UUID REFERENCES departments(id) ON DELETE SET NULL,
+    purpose VARCHAR(30) NOT NULL, -- 'classification', 'repair'
+    provider VARCHAR(50),
+    model VARCHAR(100),
+    prompt_tokens INTEGER NOT NULL DEFAULT 0,
+    completion_tokens INTEGER NOT NULL DEFAULT 0,
+    cost_usd DECIMAL(12, 6) NOT NULL DEFAULT 0,
+    latency_ms INTEGER,
+    image_detail VARCHAR(10), -- 'high', 'low'; NULL without images
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_ai_usage_created_at ON ai_usage(created_at);
+CREATE INDEX IF NOT EXISTS idx_ai_usage_department ON ai_usage(department_id, created_at);
+
+-- Days are UTC, matching the monthly budget
+CREATE OR REPLACE VIEW ai_usage_daily WITH (security_invoker = true) AS
+SELECT
+    (created_at AT TIME ZONE 'UTC')::date AS date,
+    department_id,
+    model,
+    COUNT(*) AS calls,
+    SUM(prompt_tokens) AS prompt_tokens,
+    SUM(completion_tokens) AS completion_tokens,
+    SUM(cost_usd)::float8 AS cost_usd
+FROM ai_usage
+GROUP BY 1, 2, 3;
+
+CREATE OR REPLACE VIEW ai_usage_monthly WITH (security_invoker = true) AS
+SELECT
+    date_trunc('month', created_at AT TIME ZONE 'UTC')::date AS month,
+    COUNT(*) AS calls,
+    SUM(cost_usd)::float8 AS cost_usd
+FROM ai_usage
+GROUP BY 1;
+
+-- ============================================
+-- RLS POLICIES
+-- Rows are written with the service key; admins see everything, employees
+-- their own department
+-- ============================================
+
+ALTER TABLE ai_usage ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY ai_usage_select_policy ON ai_usage
+    FOR SELECT
+    USING (
+        EXISTS (
+            SELECT 1 FROM profiles
+            WHERE profiles.id = (SELECT auth.uid())
+            AND (
+                profiles.role IN ('admin', 'super_admin')
+                OR (profiles.role = 'employee' AND profiles.department_id = ai_usage.department_id)
+            )
+        )
+    );
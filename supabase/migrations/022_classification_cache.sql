@@ -0,0 +1,5 @@
+-- Migration 022: Classification Cache
+-- LLM verdicts are reused for complaints with identical normalized content;
+-- such runs are marked so they can be told apart from fresh LLM calls
+
+ALTER TABLE ai_classifications ADD COLUMN IF NOT EXISTS cached BOOLEAN NOT NULL DEFAULT false;
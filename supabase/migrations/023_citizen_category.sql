@@ -0,0 +1,29 @@
+-- Migration 023: Citizen Category Reconciliation
+-- The category the citizen picked and the one the AI picked are stored
+-- separately from the routing category, with how they were reconciled
+
+ALTER TABLE complaints ADD COLUMN IF NOT EXISTS citizen_category_id UUID REFERENCES categories(id) ON DELETE SET NULL;
+ALTER TABLE complaints ADD COLUMN IF NOT EXISTS ai_category_id UUID REFERENCES categories(id) ON DELETE SET NULL;
+-- 'ai_only', 'agreed', 'citizen_kept', 'disputed'
+ALTER TABLE complaints ADD COLUMN IF NOT EXISTS category_reconciliation VARCHAR(20);
+
+-- Until now the category column held the citizen's choice until
+-- classification replaced it, so it is only known for pending complaints
+UPDATE complaints
+SET citizen_category_id = category_id
+WHERE classification_status = 'pending' AND citizen_category_id IS NULL;
+
+-- Per citizen-chosen category: how often the AI agreed
+CREATE OR REPLACE VIEW category_disagreement_stats WITH (security_invoker = true) AS
+SELECT
+    citizen_category_id AS category_id,
+    COUNT(*) AS complaints,
+    COUNT(*) FILTER (WHERE category_reconciliation = 'agreed') AS agreed,
+    COUNT(*) FILTER (WHERE category_reconciliation = 'citizen_kept') AS citizen_kept,
+    COUNT(*) FILTER (WHERE category_reconciliation = 'disputed') AS disputed
+FROM complaints
+WHERE citizen_category_id IS NOT NULL
+AND category_reconciliation IS NOT NULL
+GROUP BY citizen_category_id;
+
+COMMENT ON COLUMN classification_reviews.reason IS 'low_confidence, fallback, model_disagreement, suspected_manipulation or category_disagreement';
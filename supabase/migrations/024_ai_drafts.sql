@@ -0,0 +1,65 @@
+-- Migration 024: AI Drafts
+-- Draft citizen replies and resolution summaries written by the LLM for
+-- employees, logged with the text that was finally sent for quality review
+
+-- Tone guidelines the department's drafted replies follow
+ALTER TABLE departments ADD COLUMN IF NOT EXISTS reply_guidelines TEXT;
+
+CREATE TABLE IF NOT EXISTS ai_drafts (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    complaint_id UUID NOT NULL REFERENCES complaints(id) ON DELETE CASCADE,
+    kind VARCHAR(30) NOT NULL, -- 'reply', 'resolution_summary'
+    instructions TEXT,
+    draft_text TEXT NOT NULL,
+    summary JSONB, -- structured resolution summary
+    model VARCHAR(100),
+    -- Set when the employee sends or saves the text
+    final_text TEXT,
+    edited BOOLEAN NOT NULL DEFAULT false,
+    created_by UUID NOT NULL REFERENCES profiles(id) ON DELETE CASCADE,
+    finalized_by UUID REFERENCES profiles(id) ON DELETE SET NULL,
+    finalized_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_ai_drafts_complaint ON ai_drafts(complaint_id);
+CREATE INDEX IF NOT EXISTS idx_ai_drafts_kind ON ai_drafts(kind, created_at);
+
+COMMENT ON COLUMN ai_usage.purpose IS 'classification, repair, reply_draft or resolution_summary';
+
+-- ============================================
+-- RLS POLICIES
+-- ============================================
+
+ALTER TABLE ai_drafts ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY ai_drafts_select_policy ON ai_drafts
+    FOR SELECT
+    USING (
+        EXISTS (
+            SELECT 1 FROM profiles
+            WHERE profiles.id = (SELECT auth.uid())
+            AND profiles.role IN ('admin', 'super_admin', 'employee')
+        )
+    );
+
+CREATE POLICY ai_drafts_insert_policy ON ai_drafts
+    FOR INSERT
+    WITH CHECK (
+        created_by = (SELECT auth.uid())
+        AND EXISTS (
+            SELECT 1 FROM profiles
+            WHERE profiles.id = (SELECT auth.uid())
+            AND profiles.role IN ('admin', 'super_admin', 'employee')
+        )
+    );
+
+CREATE POLICY ai_drafts_update_policy ON ai_drafts
+    FOR UPDATE
+    USING (
+        EXISTS (
+            SELECT 1 FROM profiles
+            WHERE profiles.id = (SELECT auth.uid())
+            AND profiles.role IN ('admin', 'super_admin', 'employee')
+        )
+    );
@@ -0,0 +1,43 @@
+-- Migration 025: Clarifying Questions
+-- Vague complaints are no longer rejected: the citizen is asked follow-up
+-- questions, and the complaint is classified once they answer. Answers are
+-- also appended to the complaint description.
+
+CREATE TABLE IF NOT EXISTS complaint_clarifications (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    complaint_id UUID NOT NULL REFERENCES complaints(id) ON DELETE CASCADE,
+    round INTEGER NOT NULL DEFAULT 1,
+    question TEXT NOT NULL,
+    answer TEXT, -- NULL when the citizen skipped the question
+    source VARCHAR(20) NOT NULL, -- 'pre_screen', 'llm'
+    answered_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_complaint_clarifications_complaint
+ON complaint_clarifications(complaint_id, round);
+
+COMMENT ON COLUMN complaints.classification_status IS 'pending, processing, completed, failed or needs_info (waiting for clarifying answers)';
+
+-- ============================================
+-- RLS POLICIES
+-- Questions are written and answered through the service key after the
+-- server checks ownership; citizens and staff can read them.
+-- ============================================
+
+ALTER TABLE complaint_clarifications ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY complaint_clarifications_select_policy ON complaint_clarifications
+    FOR SELECT
+    USING (
+        EXISTS (
+            SELECT 1 FROM complaints
+            WHERE complaints.id = complaint_clarifications.complaint_id
+            AND complaints.user_id = (SELECT auth.uid())
+        )
+        OR EXISTS (
+            SELECT 1 FROM profiles
+            WHERE profiles.id = (SELECT auth.uid())
+            AND profiles.role IN ('admin', 'super_admin', 'employee')
+        )
+    );
@@ -0,0 +1,111 @@
+-- Migration 026: Knowledge Base Deflection
+-- Information requests ("how do I renew...", "where can I...") are no longer
+-- rejected. The citizen is sent matching answers from a managed knowledge
+-- base and can still escalate to a real complaint if the answers don't help.
+
+CREATE TABLE IF NOT EXISTS knowledge_base (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    department_id UUID REFERENCES departments(id) ON DELETE CASCADE, -- NULL applies to every department
+    category_id UUID REFERENCES categories(id) ON DELETE SET NULL,
+    question TEXT NOT NULL,
+    question_ar TEXT NOT NULL,
+    answer TEXT NOT NULL,
+    answer_ar TEXT NOT NULL,
+    url TEXT,
+    keywords TEXT[] DEFAULT '{}',
+    is_active BOOLEAN DEFAULT true,
+    created_by UUID REFERENCES profiles(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_knowledge_base_department ON knowledge_base(department_id) WHERE is_active;
+
+CREATE TRIGGER update_knowledge_base_updated_at BEFORE UPDATE ON knowledge_base
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at();
+
+-- One row per information request answered from the knowledge base
+CREATE TABLE IF NOT EXISTS complaint_deflections (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    complaint_id UUID NOT NULL UNIQUE REFERENCES complaints(id) ON DELETE CASCADE,
+    department_id UUID REFERENCES departments(id) ON DELETE SET NULL,
+    entry_ids UUID[] NOT NULL,
+    escalated BOOLEAN DEFAULT false,
+    escalated_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_complaint_deflections_department ON complaint_deflections(department_id);
+
+-- Set when the citizen escalates a deflected complaint, so it is not
+-- deflected again
+ALTER TABLE complaints ADD COLUMN IF NOT EXISTS deflection_declined BOOLEAN DEFAULT false;
+
+ALTER TABLE ai_classifications ADD COLUMN IF NOT EXISTS information_request BOOLEAN DEFAULT false;
+
+-- Per department: complaints received, how many were answered from the
+-- knowledge base, and how many of those the citizen escalated anyway
+CREATE OR REPLACE VIEW deflection_stats WITH (security_invoker = true) AS
+SELECT
+    c.department_id,
+    COUNT(*) AS complaints,
+    COUNT(d.id) AS deflected,
+    COUNT(d.id) FILTER (WHERE d.escalated) AS escalated
+FROM complaints c
+LEFT JOIN complaint_deflections d ON d.complaint_id = c.id
+WHERE c.department_id IS NOT NULL
+GROUP BY c.department_id;
+
+-- ============================================
+-- RLS POLICIES
+-- Active entries are public. Staff manage entries through the server, which
+-- limits employees to their own department. Deflections are written with the
+-- service key.
+-- ============================================
+
+ALTER TABLE knowledge_base ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY knowledge_base_select_policy ON knowledge_base
+    FOR SELECT
+    USING (
+        is_active
+        OR EXISTS (
+            SELECT 1 FROM profiles
+            WHERE profiles.id = (SELECT auth.uid())
+            AND profiles.role IN ('admin', 'super_admin', 'employee')
+        )
+    );
+
+CREATE POLICY knowledge_base_write_policy ON knowledge_base
+    FOR ALL
+    USING (
+        EXISTS (
+            SELECT 1 FROM profiles
+            WHERE profiles.id = (SELECT auth.uid())
+            AND profiles.role IN ('admin', 'super_admin', 'employee')
+        )
+    )
+    WITH CHECK (
+        EXISTS (
+            SELECT 1 FROM profiles
+            WHERE profiles.id = (SELECT auth.uid())
+            AND profiles.role IN ('admin', 'super_admin', 'employee')
+        )
+    );
+
+ALTER TABLE complaint_deflections ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY complaint_deflections_select_policy ON complaint_deflections
+    FOR SELECT
+    USING (
+        EXISTS (
+            SELECT 1 FROM complaints
+            WHERE complaints.id = complaint_deflections.complaint_id
+            AND complaints.user_id = (SELECT auth.uid())
+        )
+        OR EXISTS (
+            SELECT 1 FROM profiles
+            WHERE profiles.id = (SELECT auth.uid())
+            AND profiles.role IN ('admin', 'super_admin', 'employee')
+        )
+    );
@@ -0,0 +1,18 @@
+-- Migration 027: Voice Transcription
+-- Voice attachments are transcribed by a Whisper-compatible server. The
+-- transcript is kept on the attachment for staff and is classified with the
+-- complaint when the written description is short.
+
+ALTER TABLE attachments ADD COLUMN IF NOT EXISTS transcript TEXT;
+ALTER TABLE attachments ADD COLUMN IF NOT EXISTS transcript_language VARCHAR(20);
+ALTER TABLE attachments ADD COLUMN IF NOT EXISTS transcript_duration_seconds NUMERIC(8, 2);
+ALTER TABLE attachments ADD COLUMN IF NOT EXISTS transcriber VARCHAR(100);
+ALTER TABLE attachments ADD COLUMN IF NOT EXISTS transcription_status VARCHAR(20)
+    CHECK (transcription_status IN ('completed', 'failed'));
+ALTER TABLE attachments ADD COLUMN IF NOT EXISTS transcription_error TEXT;
+ALTER TABLE attachments ADD COLUMN IF NOT EXISTS transcribed_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_attachments_voice ON attachments(complaint_id) WHERE file_type = 'voice';
+
+-- Set when a voice transcript was part of the text the classifier saw
+ALTER TABLE ai_classifications ADD COLUMN IF NOT EXISTS voice_transcript_used BOOLEAN DEFAULT false;
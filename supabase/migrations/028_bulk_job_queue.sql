@@ -0,0 +1,53 @@
+-- Migration 028: Durable Bulk Jobs
+-- Large bulk operations are queued and run by background workers with the
+-- service key, so they survive restarts and do not depend on the caller's
+-- session staying valid
+
+-- ============================================
+-- QUEUE STATE
+-- ============================================
+
+-- The complaints the job applies to, resolved with the caller's permissions
+-- when it was queued
+ALTER TABLE bulk_jobs ADD COLUMN IF NOT EXISTS item_ids UUID[] DEFAULT '{}' NOT NULL;
+ALTER TABLE bulk_jobs ADD COLUMN IF NOT EXISTS locked_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE bulk_jobs ADD COLUMN IF NOT EXISTS locked_by VARCHAR(100);
+
+CREATE INDEX IF NOT EXISTS idx_bulk_jobs_queue
+ON bulk_jobs(status, created_at)
+WHERE status IN ('queued', 'running');
+
+-- ============================================
+-- CLAIM FUNCTION
+-- Locks queued jobs with SKIP LOCKED so several server instances can share
+-- the queue. Running jobs are never reclaimed: their items may be partly
+-- applied, so workers mark abandoned ones failed instead.
+-- ============================================
+
+CREATE OR REPLACE FUNCTION claim_bulk_jobs(p_worker TEXT, p_limit INTEGER)
+RETURNS SETOF bulk_jobs
+LANGUAGE plpgsql
+SECURITY DEFINER
+SET search_path = public
+AS $$
+BEGIN
+    RETURN QUERY
+    UPDATE bulk_jobs j
+    SET status = 'running',
+        locked_at = NOW(),
+        locked_by = p_worker
+    WHERE j.id IN (
+        SELECT id FROM bulk_jobs
+        WHERE status = 'queued'
+        ORDER BY created_at
+        LIMIT p_limit
+        FOR UPDATE SKIP LOCKED
+    )
+    RETURNING j.*;
+END;
+$$;
+
+REVOKE EXECUTE ON FUNCTION claim_bulk_jobs(TEXT, INTEGER) FROM PUBLIC, anon, authenticated;
+
+-- Progress is written by the workers only
+DROP POLICY IF EXISTS bulk_jobs_update_policy ON bulk_jobs;
@@ -0,0 +1,33 @@
+-- Migration 029: Longer Classification Lock Window
+-- One job can download several images, transcribe several voice notes and
+-- make an LLM call with a repair retry. The worker bounds the downloads and
+-- transcriptions at 8 minutes, so a lock is only treated as abandoned after
+-- 20 minutes; a shorter window let a second worker claim a job that was
+-- still running.
+
+CREATE OR REPLACE FUNCTION claim_classification_jobs(p_worker TEXT, p_limit INTEGER)
+RETURNS SETOF classification_jobs
+LANGUAGE plpgsql
+SECURITY DEFINER
+SET search_path = public
+AS $$
+BEGIN
+    RETURN QUERY
+    UPDATE classification_jobs j
+    SET status = 'processing',
+        locked_at = NOW(),
+        locked_by = p_worker,
+        attempts = j.attempts + 1
+    WHERE j.id IN (
+        SELECT id FROM classification_jobs
+        WHERE (status = 'queued' AND run_after <= NOW())
+           OR (status = 'processing' AND locked_at < NOW() - INTERVAL '20 minutes')
+        ORDER BY created_at
+        LIMIT p_limit
+        FOR UPDATE SKIP LOCKED
+    )
+    RETURNING j.*;
+END;
+$$;
+
+REVOKE EXECUTE ON FUNCTION claim_classification_jobs(TEXT, INTEGER) FROM PUBLIC, anon, authenticated;